@@ -10,24 +10,26 @@ import (
 	algos "github.com/bwiggs/spacetraders-go/algos/routing"
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bt"
+	"github.com/bwiggs/spacetraders-go/client"
+	"github.com/bwiggs/spacetraders-go/events"
+	"github.com/bwiggs/spacetraders-go/repo"
 	"github.com/bwiggs/spacetraders-go/tasks"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/go-faster/errors"
-	"github.com/spf13/viper"
 )
 
-func NavigationAction() *bt.Selector {
-	return bt.NewSelector(
-		bt.NewSequence(
+func NavigationAction() *bt.Selector[*Blackboard] {
+	return bt.NewSelector[*Blackboard](
+		bt.NewSequence[*Blackboard](
 			ConditionIsAtNavDest{},
 		),
-		bt.NewSelector(
-			bt.NewSequence(
+		bt.NewSelector[*Blackboard](
+			bt.NewSequence[*Blackboard](
 				ConditionWaypointHasFuel{},
-				bt.Invert(ConditionShipFuelFull{}),
-				bt.AlwaysFail(RefuelAction{}),
+				bt.Invert[*Blackboard](ConditionShipFuelFull{}),
+				bt.AlwaysFail[*Blackboard](RefuelAction{}),
 			),
-			bt.NewSequence(
+			bt.NewSequence[*Blackboard](
 				ActionUpdateMarkets(),
 				OrbitAction{},
 				NavAction{},
@@ -36,17 +38,17 @@ func NavigationAction() *bt.Selector {
 	)
 }
 
-func ActionUpdateMarkets() *bt.Sequence {
-	return bt.NewSequence(
-		bt.AlwaysSucceed(
-			bt.NewSequence(
+func ActionUpdateMarkets() *bt.Sequence[*Blackboard] {
+	return bt.NewSequence[*Blackboard](
+		bt.AlwaysSucceed[*Blackboard](
+			bt.NewSequence[*Blackboard](
 				NewConditionAtWaypointWithTrait("MARKETPLACE"),
 				ActionDock{},
 				ActionScanMarket{},
 			),
 		),
-		bt.AlwaysSucceed(
-			bt.NewSequence(
+		bt.AlwaysSucceed[*Blackboard](
+			bt.NewSequence[*Blackboard](
 				NewConditionAtWaypointWithTrait("SHIPYARD"),
 				ActionDock{},
 				ActionScanShipyard{},
@@ -57,23 +59,12 @@ func ActionUpdateMarkets() *bt.Sequence {
 
 type ConditionInTransitToDest struct{}
 
-func (a ConditionInTransitToDest) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionInTransitToDest: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.ship == nil {
-		slog.Error("ConditionInTransitToDest: blackboard: ship was nil")
-		return bt.Running
-	}
-
-	if bb.ship.state.Nav.Status != api.ShipNavStatusINTRANSIT {
+func (a ConditionInTransitToDest) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if bb.ship.state().Nav.Status != api.ShipNavStatusINTRANSIT {
 		return bt.Failure
 	}
 
-	if bb.destination == string(bb.ship.state.Nav.Route.Destination.Symbol) {
+	if bb.destination == string(bb.ship.state().Nav.Route.Destination.Symbol) {
 		return bt.Running
 	}
 
@@ -82,18 +73,7 @@ func (a ConditionInTransitToDest) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionShipFuelFull struct{}
 
-func (a ConditionShipFuelFull) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionShipFuelFull: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.ship == nil {
-		slog.Error("ConditionShipFuelFull: blackboard: ship was nil")
-		return bt.Running
-	}
-
+func (a ConditionShipFuelFull) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.ship.IsFuelFull() {
 		return bt.Success
 	}
@@ -103,29 +83,12 @@ func (a ConditionShipFuelFull) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionWaypointHasFuel struct{}
 
-func (a ConditionWaypointHasFuel) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionWaypointHasFuel: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.ship == nil {
-		slog.Error("ConditionWaypointHasFuel: blackboard: ship was nil")
-		return bt.Running
-	}
-
+func (a ConditionWaypointHasFuel) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	wp := bb.ship.CurrWaypoint()
 
 	bb.log.Debug("ConditionWaypointHasFuel: checking waypoint for fuel", "waypoint", wp)
 
-	hasFuel, err := bb.repo.WaypointHasGood(wp, "FUEL")
-	if err != nil {
-		slog.Error(errors.Wrap(err, "ConditionWaypointHasFuel: failed to check waypoint for fuel").Error())
-		return bt.Running
-	}
-
-	if hasFuel {
+	if bb.world.MarketHasGood(wp, "FUEL") {
 		return bt.Success
 	}
 
@@ -134,13 +97,7 @@ func (a ConditionWaypointHasFuel) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionContractIsActive struct{}
 
-func (a ConditionContractIsActive) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionContractIsActive: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionContractIsActive) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionContractIsActive: blackboard: contract was nil")
 		return bt.Running
@@ -150,7 +107,7 @@ func (a ConditionContractIsActive) Tick(data bt.Blackboard) bt.BehaviorStatus {
 		return bt.Failure
 	}
 
-	if time.Until(bb.contract.Terms.Deadline) > 0 {
+	if bb.Clock().Until(bb.contract.Terms.Deadline) > 0 {
 		return bt.Success
 	}
 
@@ -159,13 +116,7 @@ func (a ConditionContractIsActive) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionIsProfitableTradeRouteForContract struct{}
 
-func (a ConditionIsProfitableTradeRouteForContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionIsProfitableTradeRouteForContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionIsProfitableTradeRouteForContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		bb.Logger().Error("ConditionIsProfitableTradeRouteForContract: blackboard: contract was nil")
 		return bt.Running
@@ -181,29 +132,18 @@ func (a ConditionIsProfitableTradeRouteForContract) Tick(data bt.Blackboard) bt.
 
 type ConditionShipHasRemainingContractUnits struct{}
 
-func (a ConditionShipHasRemainingContractUnits) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionShipHasRemainingContractUnits: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionShipHasRemainingContractUnits) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionShipHasRemainingContractUnits: blackboard: contract was nil")
 		return bt.Running
 	}
 
-	if bb.ship == nil {
-		slog.Error("ConditionShipHasRemainingContractUnits: blackboard: ship was nil")
-		return bt.Running
-	}
-
 	for _, g := range bb.contract.Terms.Deliver {
 		remaining := g.UnitsRequired - g.UnitsFulfilled
 		if remaining == 0 {
 			continue
 		}
-		for _, inv := range bb.ship.state.Cargo.Inventory {
+		for _, inv := range bb.ship.state().Cargo.Inventory {
 			if string(inv.Symbol) != g.TradeSymbol {
 				continue
 			}
@@ -218,13 +158,7 @@ func (a ConditionShipHasRemainingContractUnits) Tick(data bt.Blackboard) bt.Beha
 
 type ConditionHasActiveContract struct{}
 
-func (a ConditionHasActiveContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionHasActiveContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionHasActiveContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract != nil && bb.contract.GetAccepted() {
 		slog.Debug("ConditionHasActiveContract: true", "contract", bb.contract.ID)
 		return bt.Success
@@ -237,13 +171,7 @@ func (a ConditionHasActiveContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionNilContract struct{}
 
-func (a ConditionNilContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionNilContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionNilContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		return bt.Success
 	}
@@ -253,14 +181,8 @@ func (a ConditionNilContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionContractExpired struct{}
 
-func (a ConditionContractExpired) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionContractExpired: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.contract != nil && bb.contract.IsExpired() {
+func (a ConditionContractExpired) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if bb.contract != nil && bb.contract.IsExpired(bb.Clock().Now()) {
 		return bt.Success
 	}
 
@@ -269,13 +191,7 @@ func (a ConditionContractExpired) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionClearContract struct{}
 
-func (a ActionClearContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ActionClearContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionClearContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	bb.contract = nil
 
 	return bt.Failure
@@ -283,16 +199,10 @@ func (a ActionClearContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionContractClosed struct{}
 
-func (a ConditionContractClosed) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionContractClosed: expected a blackboard")
-		return bt.Running
-	}
-
-	bb.log.Debug("ConditionContractClosed: status", "fulfilled", bb.contract.GetFulfilled(), "expired", bb.contract.IsExpired())
+func (a ConditionContractClosed) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	bb.log.Debug("ConditionContractClosed: status", "fulfilled", bb.contract.GetFulfilled(), "expired", bb.contract.IsExpired(bb.Clock().Now()))
 
-	if bb.contract.GetFulfilled() || bb.contract.IsExpired() {
+	if bb.contract.GetFulfilled() || bb.contract.IsExpired(bb.Clock().Now()) {
 		return bt.Success
 	}
 
@@ -301,13 +211,7 @@ func (a ConditionContractClosed) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionContractFulfilled struct{}
 
-func (a ConditionContractFulfilled) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionContractFulfilled: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionContractFulfilled) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract.GetFulfilled() {
 		return bt.Success
 	}
@@ -317,14 +221,8 @@ func (a ConditionContractFulfilled) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionContractInProgress struct{}
 
-func (a ConditionContractInProgress) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionContractInProgress: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.contract != nil && !bb.contract.IsExpired() && !bb.contract.GetFulfilled() && bb.contract.GetAccepted() {
+func (a ConditionContractInProgress) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if bb.contract != nil && !bb.contract.IsExpired(bb.Clock().Now()) && !bb.contract.GetFulfilled() && bb.contract.GetAccepted() {
 		return bt.Success
 	}
 
@@ -333,14 +231,8 @@ func (a ConditionContractInProgress) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 type ActionSetLatestContract struct{}
 
-func (a ActionSetLatestContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ActionSetLatestContract: expected a blackboard")
-		return bt.Running
-	}
-
-	contract, err := tasks.GetLatestContract(bb.ship.client)
+func (a ActionSetLatestContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	contract, err := tasks.GetLatestContract(ctx, bb.ship.client)
 	if err != nil {
 		bb.Logger().Error(errors.Wrap(err, "ActionSetLatestContract: failed to get latest contract").Error())
 		return bt.Running
@@ -356,13 +248,7 @@ func (a ActionSetLatestContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionHasPendingContract struct{}
 
-func (a ConditionHasPendingContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionHasPendingContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionHasPendingContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract != nil && !bb.contract.GetAccepted() {
 		slog.Debug("ConditionHasPendingContract: true", "contract", bb.contract.ID)
 		return bt.Success
@@ -375,14 +261,8 @@ func (a ConditionHasPendingContract) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 type NegotiateNewContract struct{}
 
-func (a NegotiateNewContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("NegotiateNewContract: expected a blackboard")
-		return bt.Running
-	}
-
-	res, err := bb.ship.client.NegotiateContract(context.TODO(), api.NegotiateContractParams{ShipSymbol: bb.ship.symbol})
+func (a NegotiateNewContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	res, err := bb.ship.client.NegotiateContract(ctx, api.NegotiateContractParams{ShipSymbol: bb.ship.symbol})
 	if err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "NegotiateNewContract: Failed to negotiate contract").Error())
 		return bt.Running
@@ -395,33 +275,23 @@ func (a NegotiateNewContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type AcceptContract struct{}
 
-func (a AcceptContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("NegotiateNewContract: expected a blackboard")
-		return bt.Running
-	}
-
-	res, err := bb.ship.client.AcceptContract(context.TODO(), api.AcceptContractParams{ContractId: bb.contract.ID})
+func (a AcceptContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	res, err := bb.ship.client.AcceptContract(ctx, api.AcceptContractParams{ContractId: bb.contract.ID})
 	if err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "NegotiateNewContract: failed to accept contract").Error())
 		return bt.Running
 	}
 
 	bb.contract = NewContract(&res.Data.Contract)
+	events.Publish(bb.ship.bus, events.ContractAccepted{Contract: res.Data.Contract})
+	events.Publish(bb.ship.bus, events.CreditsChanged{Agent: res.Data.Agent.Symbol, Credits: res.Data.Agent.Credits})
 
 	return bt.Success
 }
 
 type ConditionContractIsFulfilled struct{}
 
-func (a ConditionContractIsFulfilled) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionContractIsFulfilled: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionContractIsFulfilled) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionContractIsFulfilled: blackboard: contract was nil")
 		return bt.Running
@@ -438,20 +308,9 @@ func (a ConditionContractIsFulfilled) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 type ConditionAtMarketplace struct{}
 
-func (a ConditionAtMarketplace) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionAtMarketplace: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionAtMarketplace) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	loc := bb.ship.CurrWaypoint()
-	isMarketplace, err := bb.repo.WaypointHasTrait(loc, "MARKETPLACE")
-	if err != nil {
-		return bt.Running
-	}
-
-	if isMarketplace {
+	if bb.world.HasTrait(loc, "MARKETPLACE") {
 		return bt.Success
 	}
 
@@ -462,20 +321,9 @@ type ConditionAtWaypointWithTrait struct {
 	trait string
 }
 
-func (a ConditionAtWaypointWithTrait) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionAtWaypointWithTrait: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionAtWaypointWithTrait) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	loc := bb.ship.CurrWaypoint()
-	hasTrait, err := bb.repo.WaypointHasTrait(loc, a.trait)
-	if err != nil {
-		return bt.Running
-	}
-
-	if hasTrait {
+	if bb.world.HasTrait(loc, a.trait) {
 		return bt.Success
 	}
 
@@ -488,20 +336,14 @@ func NewConditionAtWaypointWithTrait(trait string) *ConditionAtWaypointWithTrait
 
 type ConditionIsAtExtractionWaypoint struct{}
 
-func (a ConditionIsAtExtractionWaypoint) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionIsAtExtractionWaypoint: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionIsAtExtractionWaypoint) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.extractionWaypoint == "" {
 		slog.Error("ConditionIsAtExtractionWaypoint: blackboard: contract was blank")
 		return bt.Running
 	}
 
 	if bb.ship.CurrWaypoint() == bb.extractionWaypoint {
-		if time.Until(bb.ship.state.Nav.Route.Arrival) > 0 {
+		if bb.ship.clock.Until(bb.ship.state().Nav.Route.Arrival) > 0 {
 			return bt.Running
 		}
 		return bt.Success
@@ -511,13 +353,7 @@ func (a ConditionIsAtExtractionWaypoint) Tick(data bt.Blackboard) bt.BehaviorSta
 
 type SetDestinationToExtractionWaypoint struct{}
 
-func (a SetDestinationToExtractionWaypoint) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("SetDestinationToExtractionWaypoint: expected a blackboard")
-		return bt.Running
-	}
-
+func (a SetDestinationToExtractionWaypoint) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.extractionWaypoint == "" {
 		slog.Error("SetDestinationToExtractionWaypoint: blackboard: contract was blank")
 		return bt.Running
@@ -529,27 +365,12 @@ func (a SetDestinationToExtractionWaypoint) Tick(data bt.Blackboard) bt.Behavior
 
 type SetDestinationToBestTradeMarketSale struct{}
 
-func (a SetDestinationToBestTradeMarketSale) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("SetDestinationToBestTradeMarketSale: expected a blackboard")
-		return bt.Running
-	}
-
+func (a SetDestinationToBestTradeMarketSale) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	l := bb.Logger().With("behavior", "SetDestinationToBestTradeMarketSale")
 
-	if bb.ship == nil {
-		l.Error("SetDestinationToBestTradeMarketSale: blackboard: ship was nil")
-		return bt.Running
-	}
+	bb.ship.Update(ctx)
 
-	bb.ship.Update()
-
-	trades, err := bb.repo.FindMarketTrades()
-	if err != nil {
-		l.Error(errors.Wrap(err, "SetDestinationToBestTradeMarketSale: FindMarketTrades failed:").Error())
-		return bt.Running
-	}
+	trades := bb.world.MarketTrades()
 
 	if len(trades) == 0 {
 		l.Warn("no markets for trades")
@@ -567,27 +388,12 @@ func (a SetDestinationToBestTradeMarketSale) Tick(data bt.Blackboard) bt.Behavio
 
 type SetDestinationToBestTradeMarket struct{}
 
-func (a SetDestinationToBestTradeMarket) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("SetDestinationToBestTradeMarket: expected a blackboard")
-		return bt.Running
-	}
-
+func (a SetDestinationToBestTradeMarket) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	l := bb.Logger().With("behavior", "SetDestinationToBestTradeMarket")
 
-	if bb.ship == nil {
-		l.Error("SetDestinationToBestTradeMarket: blackboard: ship was nil")
-		return bt.Running
-	}
+	bb.ship.Update(ctx)
 
-	bb.ship.Update()
-
-	trades, err := bb.repo.FindMarketTrades()
-	if err != nil {
-		l.Error(err.Error())
-		return bt.Running
-	}
+	trades := bb.world.MarketTrades()
 
 	if len(trades) == 0 {
 		l.Warn("no markets for trades")
@@ -605,19 +411,8 @@ func (a SetDestinationToBestTradeMarket) Tick(data bt.Blackboard) bt.BehaviorSta
 
 type SetDestinationToBestMarketToSellCargo struct{}
 
-func (a SetDestinationToBestMarketToSellCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("SetDestinationToBestMarketToSellCargo: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.ship == nil {
-		bb.Logger().Error("SetDestinationToBestMarketToSellCargo: blackboard: ship was nil")
-		return bt.Running
-	}
-
-	bb.ship.Update()
+func (a SetDestinationToBestMarketToSellCargo) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	bb.ship.Update(ctx)
 
 	markets, err := bb.repo.FindMarketsForGoods(bb.ship.InventorySymbols())
 	if err != nil {
@@ -637,13 +432,7 @@ func (a SetDestinationToBestMarketToSellCargo) Tick(data bt.Blackboard) bt.Behav
 
 type SetPurchaseFromContract struct{}
 
-func (a SetPurchaseFromContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("SetPurchaseFromContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a SetPurchaseFromContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("SetPurchaseFromContract: blackboard: contract was nil")
 		return bt.Running
@@ -683,18 +472,15 @@ func (a SetPurchaseFromContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 		// figure out best path to the waypoint
 		origin := bb.ship.CurrWaypoint()
 		slog.Debug("SetPurchaseFromContract: finding best path/dest for good", "origin", origin)
-		waypoints, err := bb.repo.GetWaypoints(bb.ship.System())
-		if err != nil {
-			slog.Error(errors.Wrap(err, "SetPurchaseFromContract: GetWaypoints failed:").Error())
-			return bt.Running
-		}
+		waypoints := bb.world.Waypoints(bb.ship.System())
 
+		ship := bb.ship.state()
 		market := markets[0]
-		cost, path := algos.FindPath(bb.ship.state, market, waypoints)
+		cost, path := algos.FindPath(&ship, market, waypoints)
 		bb.log.Debug("SetPurchaseFromContract: found path to market", "origin", origin, "dest", market, "path", path, "cost", cost)
 		if len(markets) > 1 {
 			for i := 1; i < len(markets); i++ {
-				c, p := algos.FindPath(bb.ship.state, markets[1], waypoints)
+				c, p := algos.FindPath(&ship, markets[1], waypoints)
 				bb.log.Debug("SetPurchaseFromContract: found path to market", "origin", origin, "dest", markets[1], "path", p, "cost", c)
 				if c < cost {
 					cost = c
@@ -721,19 +507,13 @@ func (a SetPurchaseFromContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionIsAtNavDest struct{}
 
-func (a ConditionIsAtNavDest) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionIsAtNavDest: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionIsAtNavDest) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.destination == "" {
 		slog.Error("ConditionIsAtNavDest: blackboard: destination was empty")
 		return bt.Running
 	}
 
-	if bb.destination == bb.ship.state.Nav.Route.Destination.Symbol {
+	if bb.destination == bb.ship.state().Nav.Route.Destination.Symbol {
 		return bt.Success
 	}
 
@@ -742,13 +522,7 @@ func (a ConditionIsAtNavDest) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionIsAtContractDestination struct{}
 
-func (a ConditionIsAtContractDestination) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionIsAtContractDestination: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionIsAtContractDestination) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionIsAtContractDestination: blackboard: contract was nil")
 		return bt.Running
@@ -768,13 +542,7 @@ func (a ConditionIsAtContractDestination) Tick(data bt.Blackboard) bt.BehaviorSt
 
 type SetDeliveryDestFromContract struct{}
 
-func (a SetDeliveryDestFromContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("SetDeliveryDestFromContract: expected a blackboard")
-		return bt.Running
-	}
-
+func (a SetDeliveryDestFromContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("SetDeliveryDestFromContract: blackboard: contract was nil")
 		return bt.Running
@@ -794,24 +562,13 @@ func (a SetDeliveryDestFromContract) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 type NavAction struct{}
 
-func (a NavAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("NavAction: expected a blackboard")
-		return bt.Running
-	}
-
-	if bb.ship == nil {
-		slog.Error("NavAction: blackboard: ship was nil")
-		return bt.Running
-	}
-
+func (a NavAction) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.destination == "" {
 		slog.Error("NavAction: blackboard: destination was empty")
 		return bt.Running
 	}
 
-	if time.Until(bb.ship.state.Nav.Route.Arrival) > 0 {
+	if bb.ship.clock.Until(bb.ship.state().Nav.Route.Arrival) > 0 {
 		return bt.Running
 	}
 
@@ -820,13 +577,10 @@ func (a NavAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 	}
 
 	// figure out best path to the waypoint
-	waypoints, err := bb.repo.GetWaypoints(viper.GetString("SYSTEM"))
-	if err != nil {
-		slog.Error(errors.Wrap(err, "NavAction: GetWaypoints failed:").Error())
-		return bt.Failure
-	}
+	waypoints := bb.world.Waypoints(bb.ship.System())
 
-	cost, path := algos.FindPath(bb.ship.state, bb.destination, waypoints)
+	ship := bb.ship.state()
+	cost, path := algos.FindPath(&ship, bb.destination, waypoints)
 	if len(path) == 0 {
 		slog.Error("NavAction: no path found")
 		return bt.Failure
@@ -836,7 +590,17 @@ func (a NavAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 	bb.destination = path[1]
 
-	if err := bb.ship.Transit(bb.destination); err != nil {
+	if err := bb.ship.Transit(ctx, bb.destination); err != nil {
+		var transitErr *client.ShipInTransitError
+		var fuelErr *client.InsufficientFuelError
+		switch {
+		case errors.As(err, &transitErr):
+			// still underway, the ship waits out the arrival before the next tick
+			return bt.Running
+		case errors.As(err, &fuelErr):
+			bb.ship.log.Warn("NavAction: insufficient fuel", "dest", bb.destination, "fuel.required", fuelErr.FuelRequired, "fuel.available", fuelErr.FuelAvailable)
+			return bt.Failure
+		}
 		bb.ship.log.Error(errors.Wrap(err, "Failed to transit ship").Error())
 		return bt.Failure
 	}
@@ -852,7 +616,7 @@ func NewPurchaseAction(dest string) *PurchaseAction {
 	return &PurchaseAction{dest}
 }
 
-func (a *PurchaseAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
+func (a *PurchaseAction) Tick(ctx context.Context, data *Blackboard) bt.BehaviorStatus {
 	slog.Debug("PurchaseAction")
 	return bt.Running
 }
@@ -860,24 +624,13 @@ func (a *PurchaseAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 type PurchaseContractGoodsAction struct {
 }
 
-func (a *PurchaseContractGoodsAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("PurchaseContractGoodsAction: expected a blackboard")
-		return bt.Failure
-	}
-
+func (a *PurchaseContractGoodsAction) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	contract := bb.contract
 	if contract == nil {
 		slog.Error("PurchaseContractGoodsAction: blackboard: contract was nil")
 		return bt.Running
 	}
 
-	if bb.ship == nil {
-		slog.Error("PurchaseContractGoodsAction: blackboard: ship was nil")
-		return bt.Running
-	}
-
 	for _, d := range contract.Terms.Deliver {
 		remainingUnits := d.UnitsRequired - d.UnitsFulfilled
 		bb.ship.log.Debug(fmt.Sprintf("Contract: %s %d remaining", d.TradeSymbol, remainingUnits), "action", "PurchaseContractGoodsAction")
@@ -891,7 +644,7 @@ func (a *PurchaseContractGoodsAction) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 		if remainingUnits > 0 {
 			bb.ship.log.Debug("go buy remaining units", "action", "PurchaseContractGoodsAction")
-			// return NewPurchaseGoodSequence(ship, d.TradeSymbol, remainingUnits, d.DestinationSymbol).Tick(data)
+			// return NewPurchaseGoodSequence(ship, d.TradeSymbol, remainingUnits, d.DestinationSymbol).Tick(ctx, data)
 		}
 	}
 
@@ -900,14 +653,13 @@ func (a *PurchaseContractGoodsAction) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 type RefuelAction struct{}
 
-func (a RefuelAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("RefuelAction: expected a blackboard")
-		return bt.Running
-	}
-
-	if err := bb.ship.Refuel(); err != nil {
+func (a RefuelAction) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if err := bb.ship.Refuel(ctx); err != nil {
+		var creditsErr *client.InsufficientCreditsError
+		if errors.As(err, &creditsErr) {
+			bb.ship.log.Warn("RefuelAction: insufficient credits", "credits", creditsErr.CreditsAvailable, "price", creditsErr.TotalPrice)
+			return bt.Failure
+		}
 		bb.ship.log.Error(errors.Wrap(err, "Failed to refuel ship").Error())
 		return bt.Running
 	}
@@ -917,14 +669,8 @@ func (a RefuelAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type OrbitAction struct{}
 
-func (a OrbitAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("OrbitAction: expected a blackboard")
-		return bt.Running
-	}
-
-	if err := bb.ship.Orbit(); err != nil {
+func (a OrbitAction) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if err := bb.ship.Orbit(ctx); err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "Failed to orbit ship").Error())
 		return bt.Running
 	}
@@ -934,15 +680,9 @@ func (a OrbitAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionDock struct{}
 
-func (a ActionDock) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("DockAction: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionDock) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if !bb.ship.IsDocked() {
-		if err := bb.ship.Dock(); err != nil {
+		if err := bb.ship.Dock(ctx); err != nil {
 			bb.ship.log.Error(errors.Wrap(err, "Failed to dock ship").Error())
 			return bt.Running
 		}
@@ -953,16 +693,10 @@ func (a ActionDock) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionScanMarket struct{}
 
-func (a ActionScanMarket) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ActionScanMarket: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionScanMarket) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	cwp := bb.ship.CurrWaypoint()
 	bb.ship.log.Info("ActionScanMarket: "+cwp, "waypoint", cwp)
-	res, err := bb.ship.client.GetMarket(context.TODO(), api.GetMarketParams{SystemSymbol: cwp[:7], WaypointSymbol: cwp})
+	res, err := bb.ship.client.GetMarket(ctx, api.GetMarketParams{SystemSymbol: cwp[:7], WaypointSymbol: cwp})
 	if err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "ActionScanMarket: Failed to scan market").Error())
 		return bt.Running
@@ -972,22 +706,17 @@ func (a ActionScanMarket) Tick(data bt.Blackboard) bt.BehaviorStatus {
 		bb.ship.log.Error(errors.Wrap(err, "ActionScanMarket: Failed to upsert market").Error())
 		return bt.Running
 	}
+	events.Publish(bb.ship.bus, events.MarketScanned{Market: res.Data})
 
 	return bt.Success
 }
 
 type ActionScanShipyard struct{}
 
-func (a ActionScanShipyard) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ActionScanShipyard: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionScanShipyard) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	cwp := bb.ship.CurrWaypoint()
 	bb.ship.log.Info("ActionScanShipyard: "+cwp, "waypoint", cwp)
-	res, err := bb.ship.client.GetShipyard(context.TODO(), api.GetShipyardParams{SystemSymbol: cwp[:7], WaypointSymbol: cwp})
+	res, err := bb.ship.client.GetShipyard(ctx, api.GetShipyardParams{SystemSymbol: cwp[:7], WaypointSymbol: cwp})
 	if err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "ActionScanShipyard: Failed to scan market").Error())
 		return bt.Running
@@ -1003,14 +732,7 @@ func (a ActionScanShipyard) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type TransferCargoToNearbyTransport struct{}
 
-func (a TransferCargoToNearbyTransport) Tick(data bt.Blackboard) bt.BehaviorStatus {
-
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("TransferCargoToNearbyTransport: expected a blackboard")
-		return bt.Running
-	}
-
+func (a TransferCargoToNearbyTransport) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	l := bb.Logger().With("behavior", "TransferCargoToNearbyTransport")
 
 	transporters := bb.mission.GetShipsByRole(MissionShipRoleHauler)
@@ -1028,11 +750,11 @@ func (a TransferCargoToNearbyTransport) Tick(data bt.Blackboard) bt.BehaviorStat
 		return bt.Failure
 	}
 
-	l = l.With("transport.ship", transport.symbol, "transport.cargo.cap", transport.state.Cargo.Capacity, "transport.cargo.free", transport.AvailableCargoUnits())
+	l = l.With("transport.ship", transport.symbol, "transport.cargo.cap", transport.state().Cargo.Capacity, "transport.cargo.free", transport.AvailableCargoUnits())
 
-	good := bb.ship.state.Cargo.Inventory[0]
+	good := bb.ship.state().Cargo.Inventory[0]
 
-	_, err := transport.ReceiveTransfer(bb.ship, good.Symbol, -1)
+	_, err := transport.ReceiveTransfer(ctx, bb.ship, good.Symbol, -1)
 	if err != nil {
 		l.Error(err.Error())
 		return bt.Running
@@ -1043,14 +765,8 @@ func (a TransferCargoToNearbyTransport) Tick(data bt.Blackboard) bt.BehaviorStat
 
 type JettisonNonSellableCargo struct{}
 
-func (a JettisonNonSellableCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("JettisonNonSellableCargo: expected a blackboard")
-		return bt.Running
-	}
-
-	for _, inv := range bb.ship.state.Cargo.Inventory {
+func (a JettisonNonSellableCargo) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	for _, inv := range bb.ship.state().Cargo.Inventory {
 		markets, err := bb.repo.FindMarketsForGoods([]string{string(inv.Symbol)})
 		if err != nil {
 			bb.Logger().Error(errors.Wrap(err, "JettisonNonSellableCargo failed:").Error())
@@ -1058,7 +774,7 @@ func (a JettisonNonSellableCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
 		}
 
 		if len(markets) == 0 || inv.Symbol == "QUARTZ_SAND" {
-			bb.ship.Jettison(string(inv.Symbol))
+			bb.ship.Jettison(ctx, string(inv.Symbol))
 		}
 	}
 
@@ -1067,14 +783,17 @@ func (a JettisonNonSellableCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ExtractAction struct{}
 
-func (a ExtractAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ExtractAction: expected a blackboard")
-		return bt.Running
-	}
-
-	if err := bb.ship.Extract(api.Survey{}); err != nil {
+func (a ExtractAction) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if err := bb.ship.Extract(ctx, api.Survey{}); err != nil {
+		var cooldownErr *client.CooldownError
+		var cargoErr *client.CargoError
+		switch {
+		case errors.As(err, &cooldownErr):
+			// cooldown was synced onto the ship, it sleeps it off before the next tick
+			return bt.Running
+		case errors.As(err, &cargoErr):
+			return bt.Failure
+		}
 		bb.Logger().Error(errors.Wrap(err, "Failed to extract").Error())
 		return bt.Running
 	}
@@ -1084,14 +803,8 @@ func (a ExtractAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionSellCargo struct{}
 
-func (a ActionSellCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ActionSellCargo: expected a blackboard")
-		return bt.Running
-	}
-
-	if err := bb.ship.SellCargo(); err != nil {
+func (a ActionSellCargo) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if err := bb.ship.SellCargo(ctx); err != nil {
 		bb.Logger().Error(errors.Wrap(err, "ActionSellCargo: Failed to sell cargo").Error())
 		return bt.Running
 	}
@@ -1100,14 +813,13 @@ func (a ActionSellCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionBuy struct{}
 
-func (a ActionBuy) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("BuyAction: expected a blackboard")
-		return bt.Running
-	}
-
-	if err := bb.ship.Buy(bb.purchaseTargetGood, bb.purchaseMaxUnits, bb.destination); err != nil {
+func (a ActionBuy) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	if err := bb.ship.Buy(ctx, bb.purchaseTargetGood, bb.purchaseMaxUnits, bb.destination); err != nil {
+		var creditsErr *client.InsufficientCreditsError
+		if errors.As(err, &creditsErr) {
+			bb.ship.log.Warn("BuyAction: insufficient credits", "credits", creditsErr.CreditsAvailable, "price", creditsErr.TotalPrice)
+			return bt.Failure
+		}
 		bb.ship.log.Error(errors.Wrap(err, "Failed to buy goods").Error())
 		return bt.Failure
 	}
@@ -1117,28 +829,24 @@ func (a ActionBuy) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionDeliverContractGoods struct{}
 
-func (a ActionDeliverContractGoods) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ActionDeliverContractGoods: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionDeliverContractGoods) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ActionDeliverContractGoods: blackboard: contract was nil")
 		return bt.Running
 	}
-	if bb.ship == nil {
-		slog.Error("ActionDeliverContractGoods: blackboard: ship was nil")
-		return bt.Running
-	}
-
 	if !bb.ship.HasGood(bb.contract.Terms.Deliver[0].TradeSymbol) {
 		return bt.Success
 	}
 
-	contract, err := bb.ship.DeliverContract(bb.contract.ID, bb.contract.Terms.Deliver[0].TradeSymbol)
+	contract, err := bb.ship.DeliverContract(ctx, bb.contract.ID, bb.contract.Terms.Deliver[0].TradeSymbol)
 	if err != nil {
+		var contractErr *client.ContractError
+		if errors.As(err, &contractErr) {
+			// expired or already fulfilled, drop it so a new one is negotiated
+			bb.ship.log.Warn("ActionDeliverContractGoods: contract closed", "contract", bb.contract.ID, "err", contractErr)
+			bb.contract = nil
+			return bt.Failure
+		}
 		bb.ship.log.Error(errors.Wrap(err, "Failed to deliver contract goods").Error())
 		bb.ship.log.Debug("ActionDeliverContractGoods: fail")
 		return bt.Failure
@@ -1157,28 +865,14 @@ func NewTodoBehavior(name string) TodoBehavior {
 	return TodoBehavior{name}
 }
 
-func (a TodoBehavior) Tick(data any) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("TodoBehavior: expected a blackboard")
-		return bt.Running
-	}
+func (a TodoBehavior) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	bb.Logger().Warn("TODO:"+a.name, "behavior", "TodoBehavior")
 	return bt.Running
 }
 
 type ConditionHasNonContractGoods struct{}
 
-func (a ConditionHasNonContractGoods) Tick(data any) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionHasNonContractGoods: expected a blackboard")
-		return bt.Running
-	}
-	if bb.ship == nil {
-		bb.Logger().Error("ConditionHasNonContractGoods: blackboard: ship was nil")
-		return bt.Running
-	}
+func (a ConditionHasNonContractGoods) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		bb.Logger().Error("ConditionHasNonContractGoods: blackboard: contract was nil")
 		return bt.Running
@@ -1189,7 +883,7 @@ func (a ConditionHasNonContractGoods) Tick(data any) bt.BehaviorStatus {
 		contractGoods = append(contractGoods, d.TradeSymbol)
 	}
 
-	for _, inv := range bb.ship.state.Cargo.Inventory {
+	for _, inv := range bb.ship.state().Cargo.Inventory {
 		if !slices.Contains(contractGoods, string(inv.Symbol)) {
 			return bt.Success
 		}
@@ -1200,17 +894,7 @@ func (a ConditionHasNonContractGoods) Tick(data any) bt.BehaviorStatus {
 
 type ConditionHasCargo struct{}
 
-func (a ConditionHasCargo) Tick(data any) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		bb.Logger().Error("ConditionHasCargo: expected a blackboard")
-		return bt.Running
-	}
-	if bb.ship == nil {
-		bb.Logger().Error("ConditionHasCargo: blackboard: ship was nil")
-		return bt.Running
-	}
-
+func (a ConditionHasCargo) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.ship.IsCargoEmpty() {
 		return bt.Failure
 	}
@@ -1220,17 +904,7 @@ func (a ConditionHasCargo) Tick(data any) bt.BehaviorStatus {
 
 type WaitForCargoFull struct{}
 
-func (a WaitForCargoFull) Tick(data any) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("WaitForCargoFull: expected a blackboard")
-		return bt.Running
-	}
-	if bb.ship == nil {
-		slog.Error("WaitForCargoFull: blackboard: ship was nil")
-		return bt.Running
-	}
-
+func (a WaitForCargoFull) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	l := bb.ship.log.With("cargo.avail", bb.ship.AvailableCargoUnits())
 	if bb.ship.IsCargoFull() {
 		l.Info("WaitForCargoFull: full!")
@@ -1244,17 +918,7 @@ func (a WaitForCargoFull) Tick(data any) bt.BehaviorStatus {
 
 type ConditionCargoIsFull struct{}
 
-func (a ConditionCargoIsFull) Tick(data any) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionCargoIsFull: expected a blackboard")
-		return bt.Running
-	}
-	if bb.ship == nil {
-		slog.Error("ConditionCargoIsFull: blackboard: ship was nil")
-		return bt.Running
-	}
-
+func (a ConditionCargoIsFull) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.ship.IsCargoFull() {
 		return bt.Success
 	}
@@ -1263,13 +927,7 @@ func (a ConditionCargoIsFull) Tick(data any) bt.BehaviorStatus {
 
 type ConditionContractAccepted struct{}
 
-func (a ConditionContractAccepted) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionContractAccepted: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionContractAccepted) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionContractAccepted: blackboard: contract was nil")
 		return bt.Running
@@ -1284,23 +942,12 @@ func (a ConditionContractAccepted) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type AcceptContractAction struct{}
 
-func (a AcceptContractAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("AcceptContractAction: expected a blackboard")
-		return bt.Running
-	}
-
+func (a AcceptContractAction) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("AcceptContractAction: blackboard: contract was nil")
 		return bt.Running
 	}
-	if bb.ship == nil {
-		slog.Error("AcceptContractAction: blackboard: ship was nil")
-		return bt.Running
-	}
-
-	res, err := bb.ship.client.AcceptContract(context.TODO(), api.AcceptContractParams{ContractId: bb.contract.ID})
+	res, err := bb.ship.client.AcceptContract(ctx, api.AcceptContractParams{ContractId: bb.contract.ID})
 	if err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "Failed to accept contract").Error())
 		slog.Debug("AcceptContractAction: fail")
@@ -1308,6 +955,8 @@ func (a AcceptContractAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
 	}
 
 	bb.contract.Contract = &res.Data.Contract
+	events.Publish(bb.ship.bus, events.ContractAccepted{Contract: res.Data.Contract})
+	events.Publish(bb.ship.bus, events.CreditsChanged{Agent: res.Data.Agent.Symbol, Credits: res.Data.Agent.Credits})
 
 	slog.Debug("AcceptContractAction: success")
 	return bt.Success
@@ -1321,7 +970,7 @@ func NewConditionCanFulfillContract(contract *Contract) *ConditionCanFulfillCont
 	return &ConditionCanFulfillContract{contract}
 }
 
-func (a *ConditionCanFulfillContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
+func (a *ConditionCanFulfillContract) Tick(ctx context.Context, data *Blackboard) bt.BehaviorStatus {
 	if a.contract.Fulfilled {
 		return bt.Success
 	}
@@ -1330,13 +979,7 @@ func (a *ConditionCanFulfillContract) Tick(data bt.Blackboard) bt.BehaviorStatus
 
 type ConditionContractTermsMet struct{}
 
-func (a ConditionContractTermsMet) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionContractTermsMet: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionContractTermsMet) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionContractTermsMet: blackboard: contract was nil")
 		return bt.Running
@@ -1354,13 +997,7 @@ func (a ConditionContractTermsMet) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionHasContractGoods struct{}
 
-func (a ConditionHasContractGoods) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionHasContractGoods: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionHasContractGoods) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("ConditionHasContractGoods: blackboard: contract was nil")
 		return bt.Running
@@ -1371,7 +1008,7 @@ func (a ConditionHasContractGoods) Tick(data bt.Blackboard) bt.BehaviorStatus {
 		contractGoods = append(contractGoods, d.TradeSymbol)
 	}
 
-	for _, inv := range bb.ship.state.Cargo.Inventory {
+	for _, inv := range bb.ship.state().Cargo.Inventory {
 		if slices.Contains(contractGoods, string(inv.Symbol)) {
 			return bt.Success
 		}
@@ -1382,25 +1019,21 @@ func (a ConditionHasContractGoods) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ActionFulfillContract struct{}
 
-func (a ActionFulfillContract) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("FulfillContractAction: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionFulfillContract) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.contract == nil {
 		slog.Error("FulfillContractAction: blackboard: contract was nil")
 		return bt.Running
 	}
 
-	res, err := bb.ship.client.FulfillContract(context.TODO(), api.FulfillContractParams{ContractId: bb.contract.ID})
+	res, err := bb.ship.client.FulfillContract(ctx, api.FulfillContractParams{ContractId: bb.contract.ID})
 	if err != nil {
 		bb.ship.log.Error(errors.Wrap(err, "FulfillContractAction: failed to fulfill contract").Error())
 		return bt.Running
 	}
 
 	slog.Debug("FulfillContractAction: success", "contract", res.Data.Contract.ID)
+	events.Publish(bb.ship.bus, events.ContractFulfilled{Contract: res.Data.Contract})
+	events.Publish(bb.ship.bus, events.CreditsChanged{Agent: res.Data.Agent.Symbol, Credits: res.Data.Agent.Credits})
 
 	bb.contract = nil
 
@@ -1416,8 +1049,8 @@ func NewIsAtWaypointAction(ship *Ship, waypoint string) *IsAtWaypointAction {
 	return &IsAtWaypointAction{ship, waypoint}
 }
 
-func (a *IsAtWaypointAction) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	if string(a.ship.state.Nav.WaypointSymbol) != a.waypoint {
+func (a *IsAtWaypointAction) Tick(ctx context.Context, data *Blackboard) bt.BehaviorStatus {
+	if string(a.ship.state().Nav.WaypointSymbol) != a.waypoint {
 		slog.Debug("NewIsAtWaypointAction: fail", "waypoint", a.waypoint)
 		return bt.Failure
 	}
@@ -1429,9 +1062,15 @@ type ActionSleepNode struct {
 	dur time.Duration
 }
 
-func (a ActionSleepNode) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	time.Sleep(a.dur)
-	return bt.Success
+// Tick waits out the duration on the ship's clock, and fails if the tick is
+// cancelled first.
+func (a ActionSleepNode) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	select {
+	case <-bb.Clock().After(a.dur):
+		return bt.Success
+	case <-ctx.Done():
+		return bt.Failure
+	}
 }
 
 func ActionSleep(duration time.Duration) ActionSleepNode {
@@ -1440,13 +1079,7 @@ func ActionSleep(duration time.Duration) ActionSleepNode {
 
 type ConditionIsAtTradeSource struct{}
 
-func (a ConditionIsAtTradeSource) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionIsAtTradeSource: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionIsAtTradeSource) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.ship.CurrWaypoint() == bb.tradeSource {
 		return bt.Success
 	}
@@ -1456,13 +1089,7 @@ func (a ConditionIsAtTradeSource) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionIsAtTradeBuyer struct{}
 
-func (a ConditionIsAtTradeBuyer) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionIsAtTradeBuyer: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionIsAtTradeBuyer) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.ship.CurrWaypoint() == bb.tradeBuyer {
 		return bt.Success
 	}
@@ -1472,13 +1099,7 @@ func (a ConditionIsAtTradeBuyer) Tick(data bt.Blackboard) bt.BehaviorStatus {
 
 type ConditionHasActiveTrade struct{}
 
-func (a ConditionHasActiveTrade) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionHasActiveTrade: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ConditionHasActiveTrade) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	if bb.tradeBuyer != "" {
 		return bt.Success
 	}
@@ -1486,45 +1107,41 @@ func (a ConditionHasActiveTrade) Tick(data bt.Blackboard) bt.BehaviorStatus {
 	return bt.Failure
 }
 
-type ActionAssignBestTrade struct{}
+// TradeMaxUnits caps each purchase ActionBuy makes for an assigned trade.
+const TradeMaxUnits = 20
 
-func (a ActionAssignBestTrade) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionHasActiveTrade: expected a blackboard")
-		return bt.Running
+// BestTrade picks the trade ActionAssignBestTrade assigns from trades ranked
+// best first, as world.MarketTrades returns them. The backtester runs it over
+// historical rankings.
+func BestTrade(trades []repo.MarketTrade) (repo.MarketTrade, bool) {
+	if len(trades) == 0 {
+		return repo.MarketTrade{}, false
 	}
+	return trades[0], true
+}
 
-	trades, err := bb.repo.FindMarketTrades()
-	if err != nil {
-		bb.Logger().Error(errors.Wrap(err, "ActionAssignBestTrade: FindMarketTrades failed:").Error())
-		return bt.Running
-	}
+type ActionAssignBestTrade struct{}
 
-	if len(trades) == 0 {
-		bb.Logger().Info(errors.Wrap(err, "ActionAssignBestTrade: no trades found").Error())
+func (a ActionAssignBestTrade) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	trade, ok := BestTrade(bb.world.MarketTrades())
+	if !ok {
+		bb.Logger().Info("ActionAssignBestTrade: no trades found")
 		return bt.Running
 	}
 
-	bb.tradeSource = trades[0].Origin
-	bb.tradeBuyer = trades[0].Dest
-	bb.tradeGood = trades[0].Good
+	bb.tradeSource = trade.Origin
+	bb.tradeBuyer = trade.Dest
+	bb.tradeGood = trade.Good
 
 	// TODO: why limit this?
-	bb.purchaseMaxUnits = 20
+	bb.purchaseMaxUnits = TradeMaxUnits
 
 	return bt.Success
 }
 
 type ActionSetDestinationTradeBuyer struct{}
 
-func (a ActionSetDestinationTradeBuyer) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ActionSetDestinationTradeBuyer: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionSetDestinationTradeBuyer) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	bb.destination = bb.tradeBuyer
 
 	return bt.Success
@@ -1532,13 +1149,7 @@ func (a ActionSetDestinationTradeBuyer) Tick(data bt.Blackboard) bt.BehaviorStat
 
 type ActionSetDestinationTradeSource struct{}
 
-func (a ActionSetDestinationTradeSource) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ActionSetDestinationTradeSource: expected a blackboard")
-		return bt.Running
-	}
-
+func (a ActionSetDestinationTradeSource) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
 	bb.destination = bb.tradeSource
 
 	return bt.Success
@@ -1546,14 +1157,8 @@ func (a ActionSetDestinationTradeSource) Tick(data bt.Blackboard) bt.BehaviorSta
 
 type ConditionHasTradeCargo struct{}
 
-func (a ConditionHasTradeCargo) Tick(data bt.Blackboard) bt.BehaviorStatus {
-	bb, ok := data.(*Blackboard)
-	if !ok {
-		slog.Error("ConditionHasTradeCargo: expected a blackboard")
-		return bt.Running
-	}
-
-	spew.Dump(bb.ship.state.Cargo.Inventory)
+func (a ConditionHasTradeCargo) Tick(ctx context.Context, bb *Blackboard) bt.BehaviorStatus {
+	spew.Dump(bb.ship.state().Cargo.Inventory)
 	bb.Logger().Debug("ConditionHasTradeCargo: checking for trade cargo", "good", bb.tradeGood)
 
 	if bb.ship.HasGood(bb.tradeGood) {
@@ -1,13 +1,22 @@
 package actors
 
 import (
+	"fmt"
 	"log/slog"
+	"strings"
+
+	"github.com/bwiggs/spacetraders-go/clock"
 
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 )
 
+// Blackboard is a ship's mission state, handed to every behavior tree node
+// its mission ticks. It's made by the ship it belongs to, so ship is never
+// nil.
 type Blackboard struct {
 	repo               *repo.Repo
+	world              *world.World
 	contract           *Contract
 	mission            Mission
 	ship               *Ship
@@ -26,9 +35,72 @@ type Blackboard struct {
 	log *slog.Logger
 }
 
+// Clock is the ships clock, or the real one when no ship is assigned.
+func (bb *Blackboard) Clock() clock.Clock {
+	if bb.ship != nil {
+		return bb.ship.clock
+	}
+	return clock.Real()
+}
+
 func (bb *Blackboard) Logger() *slog.Logger {
 	if bb.log != nil {
 		return bb.log
 	}
 	return &slog.Logger{}
 }
+
+// String lists the blackboard's mission state, for crash reports.
+func (bb *Blackboard) String() string {
+	fields := []string{}
+	for _, f := range bb.fields() {
+		if f.value != "" && f.value != "0" && f.value != "false" {
+			fields = append(fields, fmt.Sprintf("%s=%s", f.name, f.value))
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+type blackboardField struct {
+	name  string
+	value string
+}
+
+// fields returns the blackboard's mission state, by field.
+func (bb *Blackboard) fields() []blackboardField {
+	fields := []blackboardField{}
+	add := func(name string, value any) {
+		fields = append(fields, blackboardField{name: name, value: fmt.Sprint(value)})
+	}
+
+	mission, contract := "", ""
+	if bb.mission != nil {
+		mission = bb.mission.String()
+	}
+	if bb.contract != nil && bb.contract.Contract != nil {
+		contract = bb.contract.ID
+	}
+	add("mission", mission)
+	add("contract", contract)
+	add("destination", bb.destination)
+	add("purchaseTargetGood", bb.purchaseTargetGood)
+	add("purchaseMaxUnits", bb.purchaseMaxUnits)
+	add("tradeGood", bb.tradeGood)
+	add("tradeSource", bb.tradeSource)
+	add("tradeBuyer", bb.tradeBuyer)
+	add("extractionWaypoint", bb.extractionWaypoint)
+	add("complete", bb.complete)
+
+	return fields
+}
+
+// changes lists the fields whose values differ from before.
+func (bb *Blackboard) changes(before []blackboardField) []repo.FieldChange {
+	changes := []repo.FieldChange{}
+	for i, f := range bb.fields() {
+		if f.value != before[i].value {
+			changes = append(changes, repo.FieldChange{Field: f.name, From: before[i].value, To: f.value})
+		}
+	}
+	return changes
+}
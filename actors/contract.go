@@ -20,8 +20,7 @@ func (c *Contract) Revenue() int {
 	return c.Terms.Payment.OnAccepted + c.Terms.Payment.OnAccepted
 }
 
-func (c *Contract) IsExpired() bool {
-	now := time.Now()
+func (c *Contract) IsExpired(now time.Time) bool {
 	if c.GetAccepted() {
 		return c.Terms.Deadline.Before(now)
 	}
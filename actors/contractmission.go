@@ -1,97 +1,105 @@
 package actors
 
 import (
+	"context"
+
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bt"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 )
 
 type ContractMission struct {
 	*BaseMission
-	bt bt.BehaviorNode
+	bt bt.BehaviorNode[*Blackboard]
+}
+
+func (m *ContractMission) Trees() map[string]bt.BehaviorNode[*Blackboard] {
+	return map[string]bt.BehaviorNode[*Blackboard]{"": m.bt}
 }
 
 func (m *ContractMission) String() string {
 	return "ContractMission"
 }
 
-func (m *ContractMission) Execute(data *Blackboard) {
-	data.repo = m.repo
-	m.bt.Tick(data)
+func (m *ContractMission) Execute(ctx context.Context, data *Blackboard) {
+	data.repo = m.repo.WithContext(ctx)
+	data.world = m.world
+	bt.Tick(ctx, m.bt, data)
 	if data.complete {
 		// TODO: unassigned the ship so it can be used for something else
 	}
 }
 
-func NewContractMission(client api.Invoker, repo *repo.Repo) *ContractMission {
-	base := NewBaseMission(client, repo)
+func NewContractMission(client api.Invoker, repo *repo.Repo, world *world.World) *ContractMission {
+	base := NewBaseMission(client, repo, world)
 	base.name = "ContractMission"
 	return &ContractMission{
 		BaseMission: base,
-		bt: bt.NewSelector(
+		bt: bt.NewSelector[*Blackboard](
 			// success if the contract can by filfilled
 
-			bt.NewSequence(
+			bt.NewSequence[*Blackboard](
 				ConditionContractInProgress{},
 
-				bt.NewSelector(
-					bt.NewSequence(
+				bt.NewSelector[*Blackboard](
+					bt.Named[*Blackboard]("deliver", bt.NewSequence[*Blackboard](
 						ConditionIsAtContractDestination{},
-						bt.NewSelector(
-							bt.NewSequence(
+						bt.NewSelector[*Blackboard](
+							bt.NewSequence[*Blackboard](
 								ConditionContractTermsMet{},
 								ActionFulfillContract{},
 							),
-							bt.NewSequence(
+							bt.NewSequence[*Blackboard](
 								ConditionHasContractGoods{},
 								ActionDock{},
 								ActionDeliverContractGoods{},
 							),
 
-							bt.NewSequence(
+							bt.NewSequence[*Blackboard](
 								ConditionHasNonContractGoods{},
-								bt.AlwaysFail(ActionSellCargo{}),
+								bt.AlwaysFail[*Blackboard](ActionSellCargo{}),
 							),
 						),
-					),
+					)),
 
-					bt.NewSequence(
+					bt.Named[*Blackboard]("goto delivery", bt.NewSequence[*Blackboard](
 						ConditionCargoIsFull{},
-						bt.NewSequence(
+						bt.NewSequence[*Blackboard](
 							SetDeliveryDestFromContract{},
 							NavigationAction(),
 						),
-					),
+					)),
 
-					bt.NewSequence(
+					bt.Named[*Blackboard]("purchase", bt.NewSequence[*Blackboard](
 						SetPurchaseFromContract{},
 						NavigationAction(),
 						ActionDock{},
 						ActionBuy{},
-					),
+					)),
 				),
 			),
 
-			bt.NewSequence(
+			bt.NewSequence[*Blackboard](
 				ConditionNilContract{},
 				ActionSetLatestContract{},
 			),
 
-			bt.NewSequence(
-				bt.AlwaysFail(ActionSellCargo{}),
+			bt.NewSequence[*Blackboard](
+				bt.AlwaysFail[*Blackboard](ActionSellCargo{}),
 			),
 
-			bt.NewSequence(
+			bt.Named[*Blackboard]("negotiate", bt.NewSequence[*Blackboard](
 				ConditionContractClosed{},
 				ActionDock{},
 				NegotiateNewContract{},
-			),
+			)),
 
-			bt.NewSequence(
+			bt.Named[*Blackboard]("accept", bt.NewSequence[*Blackboard](
 				ConditionHasPendingContract{},
 				// IsCurrentContractProfitable{},
 				AcceptContract{},
-			),
+			)),
 		),
 	}
 }
@@ -1,9 +1,12 @@
 package actors
 
 import (
+	"context"
+
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bt"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 )
 
 type ExtractionMission struct {
@@ -16,71 +19,72 @@ func (m *ExtractionMission) AssignShip(role MissionShipRole, ship *Ship) {
 	m.BaseMission.AssignShip(role, ship)
 }
 
-func (m *ExtractionMission) Execute(data *Blackboard) {
+func (m *ExtractionMission) Execute(ctx context.Context, data *Blackboard) {
 	shipRole, _ := m.GetShipRole(data.ship.symbol)
 	data.log = data.log.With("mission", "ExtractionMission", "role", shipRole)
 	data.extractionWaypoint = m.extractionWaypoint
-	data.repo = m.repo
+	data.repo = m.repo.WithContext(ctx)
+	data.world = m.world
 	data.mission = m
 
-	m.GetShipBehavior(data.ship.symbol).Tick(&data)
+	bt.Tick(ctx, m.GetShipBehavior(data.ship.symbol), data)
 }
 
-func NewExtractionMission(client api.Invoker, repo *repo.Repo, extractionWaypoint string) *ExtractionMission {
-	base := NewBaseMission(client, repo)
+func NewExtractionMission(client api.Invoker, repo *repo.Repo, world *world.World, extractionWaypoint string) *ExtractionMission {
+	base := NewBaseMission(client, repo, world)
 	base.name = "ExtractionMission"
 
-	gotoExtractionPoint := bt.NewSequence(
-		bt.Invert(ConditionIsAtExtractionWaypoint{}),
+	gotoExtractionPoint := bt.NewSequence[*Blackboard](
+		bt.Invert[*Blackboard](ConditionIsAtExtractionWaypoint{}),
 		SetDestinationToExtractionWaypoint{},
 		NavigationAction(),
 	)
 
-	extract := bt.NewSequence(
+	extract := bt.NewSequence[*Blackboard](
 		ConditionIsAtExtractionWaypoint{},
 		JettisonNonSellableCargo{},
-		bt.Invert(ConditionCargoIsFull{}),
+		bt.Invert[*Blackboard](ConditionCargoIsFull{}),
 		ExtractAction{},
 	)
 
-	sell := bt.NewSequence(
+	sell := bt.NewSequence[*Blackboard](
 		JettisonNonSellableCargo{},
 		ConditionHasCargo{},
 		SetDestinationToBestMarketToSellCargo{},
-		bt.NewSequence(
+		bt.NewSequence[*Blackboard](
 			NavigationAction(),
 			ActionDock{},
 			ActionSellCargo{},
 		),
 	)
 
-	transfer := bt.NewSequence(
+	transfer := bt.NewSequence[*Blackboard](
 		JettisonNonSellableCargo{},
 		ConditionHasCargo{},
 		TransferCargoToNearbyTransport{},
 	)
 
-	// surveyorBehavior := bt.NewSelector(
+	// surveyorBehavior := bt.NewSelector[*Blackboard](
 	// 	ConditionIsAtExtractionWaypoint{},
 	// 	NewTodoBehavior("survey point"),
 	// )
 
-	excavateBehavior := bt.NewSelector(
+	excavateBehavior := bt.NewSelector[*Blackboard](
 		gotoExtractionPoint,
 		transfer,
 		extract,
 	)
 
-	haulerBehavior := bt.NewSelector(
-		bt.NewSequence(
+	haulerBehavior := bt.NewSelector[*Blackboard](
+		bt.NewSequence[*Blackboard](
 			ConditionIsAtExtractionWaypoint{},
-			bt.Invert(ConditionCargoIsFull{}),
+			bt.Invert[*Blackboard](ConditionCargoIsFull{}),
 		),
 		sell,
 		gotoExtractionPoint,
 	)
 
-	excavateAndhaulerBehavior := bt.NewSelector(
+	excavateAndhaulerBehavior := bt.NewSelector[*Blackboard](
 		extract,
 		sell,
 		gotoExtractionPoint,
@@ -1,39 +1,44 @@
 package actors
 
 import (
+	"context"
 	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bt"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 )
 
 type MarketReconMission struct {
 	*BaseMission
-	bt bt.BehaviorNode
+	bt bt.BehaviorNode[*Blackboard]
+}
+
+func (m *MarketReconMission) Trees() map[string]bt.BehaviorNode[*Blackboard] {
+	return map[string]bt.BehaviorNode[*Blackboard]{"": m.bt}
 }
 
 func (m *MarketReconMission) String() string {
 	return "MarketReconMission"
 }
 
-func (m *MarketReconMission) Execute(data *Blackboard) {
-	data.repo = m.repo
-	m.bt.Tick(data)
+func (m *MarketReconMission) Execute(ctx context.Context, data *Blackboard) {
+	data.repo = m.repo.WithContext(ctx)
+	data.world = m.world
+	bt.Tick(ctx, m.bt, data)
 	if data.complete {
 		// TODO: unassigned the ship so it can be used for something else
 	}
 }
 
-func NewMarketReconMission(client api.Invoker, repo *repo.Repo) *MarketReconMission {
-	base := NewBaseMission(client, repo)
+func NewMarketReconMission(client api.Invoker, repo *repo.Repo, world *world.World) *MarketReconMission {
+	base := NewBaseMission(client, repo, world)
 	base.name = "MarketReconMission"
 	return &MarketReconMission{
 		BaseMission: base,
-		bt: bt.NewSequence(
-			ActionUpdateMarkets(),
-			ActionSleep(1*time.Minute),
-		),
+		// scan at most once a minute, without holding a worker while waiting
+		bt: bt.Cooldown[*Blackboard](time.Minute, ActionUpdateMarkets()),
 	}
 }
 
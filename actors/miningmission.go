@@ -1,8 +1,8 @@
 package actors
 
 import (
+	"context"
 	"fmt"
-	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/repo"
@@ -32,19 +32,19 @@ func (m *MiningMission) HasValidSurveys() bool {
 	return m.surveys != nil && len(m.surveys) > 0
 }
 
-func (m *MiningMission) Execute(ship *Ship) {
+func (m *MiningMission) Execute(ctx context.Context, ship *Ship) {
 	ship.log.Debug("MiningMission Executing", "state", m.state)
 
-	cooldown := time.Until(ship.state.Cooldown.Expiration.Value)
+	cooldown := ship.clock.Until(ship.state().Cooldown.Expiration.Value)
 	if cooldown > 0 {
 		ship.log.Debug(fmt.Sprintf("cooldown: %s second", cooldown))
-		time.Sleep(cooldown)
+		ship.clock.Sleep(cooldown)
 	}
 
-	arrival := time.Until(ship.state.Nav.Route.Arrival)
+	arrival := ship.clock.Until(ship.state().Nav.Route.Arrival)
 	if arrival > 0 {
 		ship.log.Debug(fmt.Sprintf("transiting: %s second", arrival))
-		time.Sleep(arrival)
+		ship.clock.Sleep(arrival)
 	}
 
 	if m.state == IdleState {
@@ -53,11 +53,11 @@ func (m *MiningMission) Execute(ship *Ship) {
 
 	switch m.state {
 	case TransitOriginState:
-		if err := ship.Refuel(); err != nil {
+		if err := ship.Refuel(ctx); err != nil {
 			ship.log.Error(err.Error())
 			return
 		}
-		err := ship.Transit(m.origin)
+		err := ship.Transit(ctx, m.origin)
 		if err != nil {
 			ship.log.Error(err.Error())
 			return
@@ -66,13 +66,13 @@ func (m *MiningMission) Execute(ship *Ship) {
 		m.state = ExtractState
 	case ExtractState:
 
-		if err := ship.Refuel(); err != nil {
+		if err := ship.Refuel(ctx); err != nil {
 			ship.log.Error(err.Error())
 			return
 		}
 
 		if ship.HasSurveyor() && !m.HasValidSurveys() {
-			if surveys, err := ship.Survey(); err == nil {
+			if surveys, err := ship.Survey(ctx); err == nil {
 				m.surveys = surveys
 				return // to enable cooldown
 			} else {
@@ -83,7 +83,7 @@ func (m *MiningMission) Execute(ship *Ship) {
 		shitGoods := []string{"ICE_WATER"}
 		for _, g := range shitGoods {
 			if ship.HasGood("ICE_WATER") {
-				if err := ship.Jettison(g); err != nil {
+				if err := ship.Jettison(ctx, g); err != nil {
 					ship.log.Error(errors.Wrap(err, "Failed to jettison worthloss good: "+g).Error())
 				}
 			}
@@ -94,16 +94,16 @@ func (m *MiningMission) Execute(ship *Ship) {
 			return
 		}
 
-		if err := ship.Extract(api.Survey{}); err != nil {
+		if err := ship.Extract(ctx, api.Survey{}); err != nil {
 			ship.log.Error(err.Error())
 		}
 
 		// if m.HasValidSurveys() {
-		// 	if err := ship.Extract(m.surveys[0]); err != nil {
+		// 	if err := ship.Extract(ctx, m.surveys[0]); err != nil {
 		// 		ship.log.Error(err.Error())
 		// 	}
 		// } else {
-		// 	if err := ship.Extract(api.Survey{}); err != nil {
+		// 	if err := ship.Extract(ctx, api.Survey{}); err != nil {
 		// 		ship.log.Error(err.Error())
 		// 	}
 		// }
@@ -124,7 +124,7 @@ func (m *MiningMission) Execute(ship *Ship) {
 		}
 
 		for _, wp := range wps {
-			err := ship.Transit(wp)
+			err := ship.Transit(ctx, wp)
 			if err != nil {
 				ship.log.Warn(fmt.Sprintf("couldnt navigate to %s, (maybe low fuel) using next best", wp))
 				continue
@@ -137,12 +137,12 @@ func (m *MiningMission) Execute(ship *Ship) {
 		return
 
 	case SellState:
-		if err := ship.Refuel(); err != nil {
+		if err := ship.Refuel(ctx); err != nil {
 			ship.log.Error(err.Error())
 			return
 		}
-		for _, tg := range ship.state.Cargo.Inventory {
-			if err := ship.Sell(string(tg.Symbol)); err != nil {
+		for _, tg := range ship.state().Cargo.Inventory {
+			if err := ship.Sell(ctx, string(tg.Symbol)); err != nil {
 				ship.log.Error(errors.Wrap(err, "SellState failed to SellAll").Error())
 			}
 		}
@@ -1,11 +1,13 @@
 package actors
 
 import (
+	"context"
 	"log/slog"
 
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bt"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 )
 
 type MissionShipRole int
@@ -20,25 +22,49 @@ const (
 	MissionShipRoleSatellite
 )
 
+func (r MissionShipRole) String() string {
+	switch r {
+	case MissionShipRoleExcavator:
+		return "excavator"
+	case MissionShipRoleTransporter:
+		return "transporter"
+	case MissionShipRoleSurveyor:
+		return "surveyor"
+	case MissionShipRoleExcavatorTransporter:
+		return "excavator-transporter"
+	case MissionShipRoleTrader:
+		return "trader"
+	case MissionShipRoleHauler:
+		return "hauler"
+	case MissionShipRoleSatellite:
+		return "satellite"
+	}
+	return "unknown"
+}
+
 type Mission interface {
-	Execute(*Blackboard)
+	Execute(context.Context, *Blackboard)
 
 	AssignShip(MissionShipRole, *Ship)
 	GetShipRole(string) (MissionShipRole, bool)
 	GetShipsByRole(MissionShipRole) []*Ship
-	GetShipBehavior(string) bt.BehaviorNode
+	GetShipBehavior(string) bt.BehaviorNode[*Blackboard]
+	// Trees returns the mission's behavior trees by role name, or under ""
+	// when one tree runs every ship, e.g. to export them.
+	Trees() map[string]bt.BehaviorNode[*Blackboard]
 
 	String() string
 }
 
 type ShipRoleMap map[string]MissionShipRole
 type ShipsByRole map[MissionShipRole][]*Ship
-type RoleBehaviors map[MissionShipRole]bt.BehaviorNode
+type RoleBehaviors map[MissionShipRole]bt.BehaviorNode[*Blackboard]
 
-func NewBaseMission(client api.Invoker, repo *repo.Repo) *BaseMission {
+func NewBaseMission(client api.Invoker, repo *repo.Repo, world *world.World) *BaseMission {
 	return &BaseMission{
 		client:        client,
 		repo:          repo,
+		world:         world,
 		shipRole:      make(ShipRoleMap),
 		shipsByRole:   make(ShipsByRole),
 		roleBehaviors: make(RoleBehaviors),
@@ -49,6 +75,7 @@ type BaseMission struct {
 	name          string
 	client        api.Invoker
 	repo          *repo.Repo
+	world         *world.World
 	roleBehaviors RoleBehaviors
 	shipRole      ShipRoleMap
 	shipsByRole   ShipsByRole
@@ -71,7 +98,7 @@ func (m *BaseMission) GetShipsByRole(role MissionShipRole) []*Ship {
 	return []*Ship{}
 }
 
-func (m *BaseMission) GetShipBehavior(shipSymbol string) bt.BehaviorNode {
+func (m *BaseMission) GetShipBehavior(shipSymbol string) bt.BehaviorNode[*Blackboard] {
 	role, ok := m.GetShipRole(shipSymbol)
 	if !ok {
 		return NewTodoBehavior("no behavior for role")
@@ -85,6 +112,14 @@ func (m *BaseMission) GetShipBehavior(shipSymbol string) bt.BehaviorNode {
 	return behavior
 }
 
+func (m *BaseMission) Trees() map[string]bt.BehaviorNode[*Blackboard] {
+	trees := map[string]bt.BehaviorNode[*Blackboard]{}
+	for role, tree := range m.roleBehaviors {
+		trees[role.String()] = tree
+	}
+	return trees
+}
+
 func (m *BaseMission) AssignShip(role MissionShipRole, ship *Ship) {
 	m.shipRole[ship.symbol] = role
 
@@ -96,22 +131,21 @@ func (m *BaseMission) AssignShip(role MissionShipRole, ship *Ship) {
 	m.shipsByRole[role] = ships
 }
 
-func (m *BaseMission) Execute(data *Blackboard) {
+func (m *BaseMission) Execute(ctx context.Context, data *Blackboard) {
 	slog.Info("BaseMission: execute")
 }
 
+// NewIdleMission parks a ship, e.g. one quarantined by the supervisor.
 func NewIdleMission() *IdleMission {
-	return &IdleMission{
-		name:        "IdleMission",
-		BaseMission: NewBaseMission(nil, nil),
-	}
+	base := NewBaseMission(nil, nil, nil)
+	base.name = "IdleMission"
+	return &IdleMission{BaseMission: base}
 }
 
 type IdleMission struct {
 	*BaseMission
-	name string
 }
 
-func (m *IdleMission) Execute(ship *Ship) {
-	ship.log.Info("sleeping")
+func (m *IdleMission) Execute(ctx context.Context, data *Blackboard) {
+	data.Logger().Info("sleeping")
 }
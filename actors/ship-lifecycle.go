@@ -0,0 +1,175 @@
+package actors
+
+import (
+	"context"
+
+	"github.com/bwiggs/spacetraders-go/bt"
+	"github.com/pkg/errors"
+)
+
+// ShipStatus is where a ship is in its lifecycle.
+type ShipStatus int
+
+const (
+	// ShipNew is a ship that hasn't been started yet.
+	ShipNew ShipStatus = iota
+	ShipRunning
+	ShipPaused
+	ShipStopped
+)
+
+func (s ShipStatus) String() string {
+	switch s {
+	case ShipNew:
+		return "new"
+	case ShipRunning:
+		return "running"
+	case ShipPaused:
+		return "paused"
+	case ShipStopped:
+		return "stopped"
+	}
+	return "unknown"
+}
+
+// ErrShipStopped is returned by ship actions attempted after Stop.
+var ErrShipStopped = errors.New("ship stopped")
+
+// Start schedules the ship's ticks until ctx is done or Stop is called.
+func (s *Ship) Start(ctx context.Context) {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	if s.status != ShipNew {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancelCause(ctx)
+	s.status = ShipRunning
+	s.sched.Add(s)
+
+	go func() {
+		<-s.ctx.Done()
+		s.Stop()
+	}()
+}
+
+// Pause stops scheduling the ship's ticks, a tick in progress finishes.
+func (s *Ship) Pause() {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	if s.status != ShipRunning {
+		return
+	}
+
+	s.status = ShipPaused
+	s.sched.Remove(s.symbol)
+	s.log.Info("paused")
+}
+
+// Resume schedules a paused ship's ticks again.
+func (s *Ship) Resume() {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	if s.status != ShipPaused {
+		return
+	}
+
+	s.status = ShipRunning
+	s.sched.Add(s)
+	s.log.Info("resumed")
+}
+
+// Stop ends the ship's ticks for good. An api call already sent is allowed
+// to finish and its response applied, later actions fail with ErrShipStopped,
+// so Stop returns once the ship's state matches the server's.
+func (s *Ship) Stop() {
+	s.lifeMu.Lock()
+	if s.status == ShipStopped {
+		s.lifeMu.Unlock()
+		return
+	}
+	s.status = ShipStopped
+	s.sched.Remove(s.symbol)
+	if s.cancel != nil {
+		s.cancel(ErrShipStopped)
+	}
+	s.lifeMu.Unlock()
+
+	// wait out a tick in progress
+	s.ticking.Lock()
+	forget(s.Mission(), s.Blackboard())
+	s.ticking.Unlock()
+
+	s.log.Info("stopped")
+}
+
+// Restart clears the ship's blackboard, so its mission starts over from a
+// clean slate, e.g. after the supervisor recovered a panicking tick.
+func (s *Ship) Restart() {
+	s.restart(nil)
+}
+
+// Quarantine restarts the ship on mission instead of its own, e.g. an idle
+// mission for a ship that keeps crashing. The swap waits out a tick in
+// progress, so the old mission isn't ticked again once Quarantine returns.
+func (s *Ship) Quarantine(mission Mission) {
+	s.log.Info("Quarantined: " + mission.String())
+	s.restart(mission)
+}
+
+// restart swaps in a fresh blackboard, and mission unless it's nil, between
+// ticks.
+func (s *Ship) restart(mission Mission) {
+	s.ticking.Lock()
+	defer s.ticking.Unlock()
+
+	s.lifeMu.Lock()
+	old, data := s.mission, s.data
+	if mission != nil {
+		s.mission = mission
+	}
+	s.data = &Blackboard{ship: s, log: s.log}
+	s.lifeMu.Unlock()
+
+	forget(old, data)
+}
+
+// forget drops the state mission's trees keep for data, once they're no
+// longer ticked with it.
+func forget(mission Mission, data *Blackboard) {
+	if mission == nil {
+		return
+	}
+	for _, tree := range mission.Trees() {
+		bt.Reset(tree, data)
+	}
+}
+
+// Blackboard returns the ship's mission state.
+func (s *Ship) Blackboard() *Blackboard {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	return s.data
+}
+
+// Mission returns the ship's mission, if it has one.
+func (s *Ship) Mission() Mission {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	return s.mission
+}
+
+// Status returns where the ship is in its lifecycle.
+func (s *Ship) Status() ShipStatus {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	return s.status
+}
+
+// stopped reports whether Stop has been called, or the ship's context is
+// done.
+func (s *Ship) stopped() bool {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	return s.status == ShipStopped || (s.ctx != nil && s.ctx.Err() != nil)
+}
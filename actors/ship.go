@@ -5,78 +5,296 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/bt"
+	"github.com/bwiggs/spacetraders-go/client"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/bwiggs/spacetraders-go/events"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/scheduler"
+	"github.com/bwiggs/spacetraders-go/telemetry"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// pollInterval is how often a ship with nothing to wait for is ticked.
+	pollInterval = 2 * time.Second
+	// maxTickDuration is the deadline for one pass of a ship's behavior tree.
+	maxTickDuration = 5 * time.Minute
+)
+
+// ShipStore holds the ships as the api last reported them, e.g. kernel.State.
+// A ship's client keeps it current from every response, so ships read their
+// nav, fuel, cargo and cooldown from it rather than keeping their own copy.
+type ShipStore interface {
+	Ship(symbol string) (api.Ship, bool)
+	UpdateShip(ship *api.Ship)
+	EditShip(symbol string, fn func(ship *api.Ship))
+}
+
 type Ship struct {
-	state        *api.Ship
+	store        ShipStore
 	symbol       string
-	mission      Mission
 	log          *slog.Logger
 	client       api.Invoker
+	clock        clock.Clock
+	bus          *events.Bus
 	transferLock sync.Mutex
-}
 
-func NewShip(ship *api.Ship, client api.Invoker) *Ship {
+	// mission and its blackboard data, kept between ticks, and waiting, what
+	// the ship's next tick was scheduled for, are guarded by lifeMu. Next
+	// runs when the ship is added to the scheduler, even mid tick.
+	mission Mission
+	data    *Blackboard
+	waiting scheduler.Priority
+
+	// lifecycle, see ship-lifecycle.go
+	sched   *scheduler.Scheduler
+	lifeMu  sync.Mutex
+	status  ShipStatus
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	ticking sync.Mutex
+	// cancelTick cancels the tick in progress, if any
+	cancelTick context.CancelFunc
+	// ticked is the mission of the tick in progress, if any, and retired
+	// the missions swapped out while it runs
+	ticked   Mission
+	retired  []Mission
+	lastTick atomic.Pointer[bt.Recorder]
+}
+
+// NewShip returns a ship ticked by sched once started, whenever it arrives,
+// cools down or every pollInterval while it has nothing to wait for. client
+// must keep store current, like kernel.StateInvoker; ship is stored in it if
+// store hasn't seen it yet.
+func NewShip(ship *api.Ship, store ShipStore, client api.Invoker, sched *scheduler.Scheduler, bus *events.Bus) *Ship {
 
 	logger := slog.With("ship", ship.Symbol)
 
+	if _, ok := store.Ship(ship.Symbol); !ok {
+		store.UpdateShip(ship)
+	}
+
 	s := &Ship{
 		symbol:       ship.Symbol,
-		state:        ship,
+		store:        store,
 		client:       client,
+		clock:        sched.Clock(),
+		bus:          bus,
 		log:          logger,
 		transferLock: sync.Mutex{},
+		sched:        sched,
 	}
-
-	go func(ship *Ship) {
-		data := Blackboard{ship: ship, log: logger}
-		for {
-			ship.Wait()
-			if ship.mission != nil {
-				ship.mission.Execute(&data)
-			} else {
-				ship.log.Info("idling - no current mission")
-			}
-		}
-	}(s)
+	s.data = &Blackboard{ship: s, log: logger}
 
 	return s
 }
 
-func (s *Ship) Wait() {
-	var dur time.Duration
+// state returns the ship as the store last saw it.
+func (s *Ship) state() api.Ship {
+	ship, _ := s.store.Ship(s.symbol)
+	return ship
+}
+
+// Name implements scheduler.Task.
+func (s *Ship) Name() string {
+	return s.symbol
+}
+
+// Next implements scheduler.Task, the ship wakes when its transit or cooldown
+// ends, or polls.
+func (s *Ship) Next() (time.Time, scheduler.Priority) {
+	now := s.clock.Now()
+	ship := s.state()
+
+	if arrival := ship.Nav.Route.Arrival; arrival.After(now) {
+		dest := ship.Nav.Route.Destination.Symbol
+		s.log.Info(fmt.Sprintf("%s: transit: %s %s", s.symbol, dest, arrival.Sub(now)), "route.dest", dest)
+		return arrival, s.wait(scheduler.PriorityArrival)
+	}
 
-	arrivalTime := time.Until(s.state.Nav.Route.Arrival)
-	cooldownTime := time.Until(s.state.Cooldown.Expiration.Value)
+	if expiration := ship.Cooldown.Expiration.Value; expiration.After(now) {
+		s.log.Info(fmt.Sprintf("%s: cooldown: %s", s.symbol, expiration.Sub(now)))
+		return expiration, s.wait(scheduler.PriorityCooldown)
+	}
 
-	if arrivalTime > 0 {
-		dest := s.state.Nav.Route.Destination.Symbol
-		dur = arrivalTime
-		s.log.Info(fmt.Sprintf("%s: transit: %s %s", s.symbol, dest, dur), "route.dest", dest)
-	} else if cooldownTime > 0 {
-		dur = cooldownTime
-		s.log.Info(fmt.Sprintf("%s: cooldown: %s", s.symbol, dur))
+	return now.Add(pollInterval), s.wait(scheduler.PriorityPoll)
+}
+
+// wait records what the ship's next tick is for, and returns it.
+func (s *Ship) wait(prio scheduler.Priority) scheduler.Priority {
+	s.lifeMu.Lock()
+	defer s.lifeMu.Unlock()
+	s.waiting = prio
+	return prio
+}
+
+// Tick implements scheduler.Task, publishing what the ship was waiting for
+// and running its mission.
+func (s *Ship) Tick() {
+	s.ticking.Lock()
+	defer s.ticking.Unlock()
+	if s.stopped() {
+		return
+	}
+
+	s.lifeMu.Lock()
+	mission, data, waiting := s.mission, s.data, s.waiting
+	s.ticked = mission
+	s.lifeMu.Unlock()
+	defer s.endTick(data)
+
+	switch waiting {
+	case scheduler.PriorityArrival:
+		events.Publish(s.bus, events.ShipArrived{Ship: s.symbol, Waypoint: s.state().Nav.Route.Destination.Symbol})
+	case scheduler.PriorityCooldown:
+		events.Publish(s.bus, events.CooldownExpired{Ship: s.symbol})
+	}
+
+	if mission != nil {
+		s.tick(mission, data)
 	} else {
-		dur = 2 * time.Second
+		s.log.Info("idling - no current mission")
+	}
+}
+
+// endTick forgets the missions swapped out while the tick was running, now
+// their trees are done with data.
+func (s *Ship) endTick(data *Blackboard) {
+	s.lifeMu.Lock()
+	retired := s.retired
+	s.ticked, s.retired = nil, nil
+	s.lifeMu.Unlock()
+
+	for _, mission := range retired {
+		forget(mission, data)
+	}
+}
+
+// tick runs one pass of the mission's behavior tree under a span, with the
+// api calls and repo queries it makes as children. The tick's context is
+// cancelled when the ship's mission is swapped or the tick runs past
+// maxTickDuration. Stopping the ship doesn't cancel it, that would abort a
+// purchase already sent; actionContext keeps later actions from being sent.
+func (s *Ship) tick(mission Mission, data *Blackboard) {
+	// sit out api outages rather than failing every tick
+	if err := client.APIBreaker().Wait(s.ctx); err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(s.ctx), maxTickDuration)
+	defer cancel()
+
+	s.lifeMu.Lock()
+	s.cancelTick = cancel
+	s.lifeMu.Unlock()
+	defer func() {
+		s.lifeMu.Lock()
+		s.cancelTick = nil
+		s.lifeMu.Unlock()
+	}()
+
+	ctx, span := telemetry.Tracer().Start(ctx, "tick", trace.WithAttributes(
+		attribute.String("ship", s.symbol),
+		attribute.String("mission", mission.String()),
+	))
+	defer span.End()
+
+	rec := bt.NewRecorder()
+	before := data.fields()
+	started := s.clock.Now()
+	mission.Execute(bt.WithRecorder(ctx, rec), data)
+	s.lastTick.Store(rec)
+
+	trace := tickTrace(s.symbol, mission.String(), started, s.clock.Since(started), rec, data.changes(before))
+	events.Publish(s.bus, events.ShipTicked{Trace: trace})
+}
+
+// tickTrace is the trace of a tick recorded by rec, its status is the root's.
+func tickTrace(ship, mission string, started time.Time, d time.Duration, rec *bt.Recorder, changes []repo.FieldChange) repo.TickTrace {
+	t := repo.TickTrace{
+		Ship:      ship,
+		Mission:   mission,
+		StartedAt: started,
+		Duration:  d,
+		Nodes:     []repo.TickNode{},
+		Changes:   changes,
+	}
+	for _, v := range rec.Visits() {
+		t.Nodes = append(t.Nodes, repo.TickNode{Path: v.Path, Name: v.Name, Status: v.Status.String(), Duration: v.Duration})
+	}
+	if len(t.Nodes) > 0 {
+		t.Status = t.Nodes[0].Status
+	}
+	return t
+}
+
+// LastTick returns the statuses the ship's behavior tree nodes returned in
+// its last complete tick, for exporting the tree with bt.DOT or bt.Mermaid.
+func (s *Ship) LastTick() *bt.Recorder {
+	return s.lastTick.Load()
+}
+
+// actionContext tags ship commands so they are sent ahead of mission queries
+// and background crawls. Once the ship is stopped it's already cancelled, so
+// no new command is sent.
+func (s *Ship) actionContext(ctx context.Context) context.Context {
+	if s.stopped() {
+		stopped, cancel := context.WithCancelCause(ctx)
+		cancel(ErrShipStopped)
+		ctx = stopped
 	}
+	return client.WithPriority(ctx, client.PriorityShipAction)
+}
 
-	time.Sleep(dur)
+// cargoChanged publishes the ship's hold from a response, which the client
+// has already applied to the store.
+func (s *Ship) cargoChanged(cargo api.ShipCargo) {
+	events.Publish(s.bus, events.CargoChanged{Ship: s.symbol, Cargo: cargo})
 }
 
+// traded publishes a market transaction and the balance it left the agent
+// with.
+func (s *Ship) traded(t api.MarketTransaction, agent api.Agent) {
+	events.Publish(s.bus, events.TradeExecuted{Ship: s.symbol, Transaction: t})
+	events.Publish(s.bus, events.CreditsChanged{Agent: agent.Symbol, Credits: agent.Credits})
+}
+
+// SetMission swaps the ship's mission, cancelling the old mission's tick in
+// progress. The old mission's trees forget the ship once they're no longer
+// ticked with its blackboard.
 func (s *Ship) SetMission(mission Mission) {
 	s.log.Info("Mission: " + mission.String())
+
+	s.lifeMu.Lock()
+	if s.cancelTick != nil {
+		s.cancelTick()
+	}
+	old, data := s.mission, s.data
 	s.mission = mission
+	retire := old != nil && old != mission
+	if retire && s.ticked == old {
+		// still running, the tick forgets it when it ends
+		s.retired = append(s.retired, old)
+		retire = false
+	}
+	s.lifeMu.Unlock()
+
+	if retire {
+		forget(old, data)
+	}
 }
 
-func (s *Ship) DeliverContract(contractID, good string) (*api.Contract, error) {
+func (s *Ship) DeliverContract(ctx context.Context, contractID, good string) (*api.Contract, error) {
 	s.log.Info("DeliverContract: " + good)
 	if !s.IsDocked() {
-		if err := s.Dock(); err != nil {
+		if err := s.Dock(ctx); err != nil {
 			return nil, errors.Wrap(err, "Undock failed")
 		}
 	}
@@ -90,22 +308,24 @@ func (s *Ship) DeliverContract(contractID, good string) (*api.Contract, error) {
 
 	dcr := api.DeliverContractReq{ShipSymbol: s.symbol, TradeSymbol: good, Units: ownedUnits}
 	sres, err := s.client.DeliverContract(
-		context.TODO(),
+		s.actionContext(ctx),
 		api.NewOptDeliverContractReq(dcr),
 		api.DeliverContractParams{ContractId: contractID},
 	)
 	if err != nil {
+		s.syncFromError(ctx, err)
 		return nil, errors.Wrap(err, "Contract Deliver failed")
 	}
 
-	s.state.Cargo = sres.Data.Cargo
+	s.cargoChanged(sres.Data.Cargo)
+	events.Publish(s.bus, events.ContractDelivered{Contract: sres.Data.Contract, Ship: s.symbol, Good: good, Units: ownedUnits})
 
 	return &sres.Data.Contract, nil
 }
 
-func (s *Ship) SellCargo() error {
+func (s *Ship) SellCargo(ctx context.Context) error {
 	res, err := s.client.GetMarket(
-		context.TODO(),
+		s.actionContext(ctx),
 		api.GetMarketParams{SystemSymbol: s.CurrWaypoint()[:7], WaypointSymbol: s.CurrWaypoint()},
 	)
 	if err != nil {
@@ -117,6 +337,7 @@ func (s *Ship) SellCargo() error {
 			s.log.Warn(errors.Wrap(err, "failed to update market repo data").Error())
 		}
 	}
+	events.Publish(s.bus, events.MarketScanned{Market: res.Data})
 
 	marketItems := make(map[string]int)
 	for _, i := range res.Data.TradeGoods {
@@ -126,7 +347,7 @@ func (s *Ship) SellCargo() error {
 		marketItems[string(i.Symbol)] = i.TradeVolume
 	}
 
-	for _, inv := range s.state.Cargo.Inventory {
+	for _, inv := range s.state().Cargo.Inventory {
 		good := string(inv.Symbol)
 		vol, hasItem := marketItems[good]
 		if !hasItem {
@@ -137,7 +358,7 @@ func (s *Ship) SellCargo() error {
 		s.log.Info(fmt.Sprintf("Selling %d %s", units, good))
 
 		sres, err := s.client.SellCargo(
-			context.TODO(),
+			s.actionContext(ctx),
 			api.NewOptSellCargoReq(api.SellCargoReq{
 				Symbol: api.TradeSymbol(good),
 				Units:  units,
@@ -145,26 +366,34 @@ func (s *Ship) SellCargo() error {
 			api.SellCargoParams{ShipSymbol: s.symbol},
 		)
 		if err != nil {
+			var notSold *client.MarketTradeError
+			if errors.As(err, &notSold) {
+				s.log.Warn("Selling: market not buying "+good, "err", err)
+				continue
+			}
+			s.syncFromError(ctx, err)
 			return errors.Wrap(err, "Sell failed")
 		}
 
 		t := sres.Data.Transaction
 
 		s.log.Info(fmt.Sprintf("Transaction: Sell: $(%+d) %s (%d x $%d)", t.TotalPrice, t.TradeSymbol, t.Units, t.PricePerUnit))
-		s.state.Cargo = sres.Data.Cargo
+		s.cargoChanged(sres.Data.Cargo)
+		s.traded(t, sres.Data.Agent)
 	}
 
 	return nil
 }
 
-func (s *Ship) Sell(good string) error {
+func (s *Ship) Sell(ctx context.Context, good string) error {
 	s.log.Info("Selling " + good)
 	if !s.IsDocked() {
-		if err := s.Dock(); err != nil {
+		if err := s.Dock(ctx); err != nil {
 			return errors.Wrap(err, "Undock failed")
 		}
 	}
 
+	volumeCap := 0
 	for {
 		ownedUnits := s.CountInventoryBySymbol(good)
 		if ownedUnits == 0 {
@@ -172,7 +401,7 @@ func (s *Ship) Sell(good string) error {
 		}
 
 		res, err := s.client.GetMarket(
-			context.TODO(),
+			s.actionContext(ctx),
 			api.GetMarketParams{SystemSymbol: s.CurrWaypoint()[:7], WaypointSymbol: s.CurrWaypoint()},
 		)
 		if err != nil {
@@ -193,10 +422,13 @@ func (s *Ship) Sell(good string) error {
 		}
 
 		units := min(ownedUnits, maxTradeVol)
+		if volumeCap > 0 {
+			units = min(units, volumeCap)
+		}
 		s.log.Info(fmt.Sprintf("Selling %d units", units))
 
 		sres, err := s.client.SellCargo(
-			context.TODO(),
+			s.actionContext(ctx),
 			api.NewOptSellCargoReq(api.SellCargoReq{
 				Symbol: api.TradeSymbol(good),
 				Units:  units,
@@ -204,21 +436,38 @@ func (s *Ship) Sell(good string) error {
 			api.SellCargoParams{ShipSymbol: s.symbol},
 		)
 		if err != nil {
+			// the market info was stale, go around again with the fresh volume
+			var volErr *client.MarketVolumeExceededError
+			if errors.As(err, &volErr) && volErr.TradeVolume > 0 && volErr.TradeVolume < units {
+				s.log.Warn("Selling: trade volume exceeded", "units", units, "tradeVolume", volErr.TradeVolume)
+				volumeCap = volErr.TradeVolume
+				continue
+			}
+			s.syncFromError(ctx, err)
 			return errors.Wrap(err, "Sell failed")
 		}
 
-		s.state.Cargo = sres.Data.Cargo
+		s.cargoChanged(sres.Data.Cargo)
+		s.traded(sres.Data.Transaction, sres.Data.Agent)
 	}
 
 	return nil
 }
 
-func (s *Ship) Buy(good string, maxUnits int, wp string) error {
+// PurchaseUnits is how many units Buy purchases in one transaction: as many
+// as fit in the hold, up to the market's trade volume and maxUnits. Buy keeps
+// purchasing until the hold is full.
+func PurchaseUnits(cargoSpace, tradeVolume, maxUnits int) int {
+	return max(0, min(cargoSpace, tradeVolume, maxUnits))
+}
+
+func (s *Ship) Buy(ctx context.Context, good string, maxUnits int, wp string) error {
 	s.log.Info("Buying " + good)
 
+	volumeCap := 0
 	for {
 
-		cargoSpace := s.state.Cargo.Capacity - s.state.Cargo.Units
+		cargoSpace := s.AvailableCargoUnits()
 
 		if cargoSpace == 0 {
 			s.log.Debug("Cargo full, cant buy any more goods")
@@ -226,7 +475,7 @@ func (s *Ship) Buy(good string, maxUnits int, wp string) error {
 		}
 
 		res, err := s.client.GetMarket(
-			context.TODO(),
+			s.actionContext(ctx),
 			api.GetMarketParams{SystemSymbol: wp[:7], WaypointSymbol: wp},
 		)
 		if err != nil {
@@ -246,11 +495,14 @@ func (s *Ship) Buy(good string, maxUnits int, wp string) error {
 			break
 		}
 
-		units := min(cargoSpace, availableUnits, maxUnits)
+		units := PurchaseUnits(cargoSpace, availableUnits, maxUnits)
+		if volumeCap > 0 {
+			units = min(units, volumeCap)
+		}
 		s.log.Info(fmt.Sprintf("Buying %d units", units))
 
 		pres, err := s.client.PurchaseCargo(
-			context.TODO(),
+			s.actionContext(ctx),
 			api.NewOptPurchaseCargoReq(api.PurchaseCargoReq{
 				Symbol: api.TradeSymbol(good),
 				Units:  units,
@@ -258,21 +510,30 @@ func (s *Ship) Buy(good string, maxUnits int, wp string) error {
 			api.PurchaseCargoParams{ShipSymbol: s.symbol},
 		)
 		if err != nil {
+			// the market info was stale, go around again with the fresh volume
+			var volErr *client.MarketVolumeExceededError
+			if errors.As(err, &volErr) && volErr.TradeVolume > 0 && volErr.TradeVolume < units {
+				s.log.Warn("Buying: trade volume exceeded", "units", units, "tradeVolume", volErr.TradeVolume)
+				volumeCap = volErr.TradeVolume
+				continue
+			}
+			s.syncFromError(ctx, err)
 			return errors.Wrap(err, "Buy failed")
 		}
 
-		if err := s.Update(); err != nil {
+		if err := s.Update(ctx); err != nil {
 			return errors.Wrap(err, "failed to update ship state")
 		}
 
-		s.state.Cargo = pres.Data.Cargo
+		s.cargoChanged(pres.Data.Cargo)
+		s.traded(pres.Data.Transaction, pres.Data.Agent)
 	}
 
 	return nil
 }
 
 func (s *Ship) GetCargoItemBySymbol(good api.TradeSymbol) (api.ShipCargoItem, bool) {
-	for _, inv := range s.state.Cargo.Inventory {
+	for _, inv := range s.state().Cargo.Inventory {
 		if inv.Symbol == good {
 			return inv, true
 		}
@@ -280,7 +541,7 @@ func (s *Ship) GetCargoItemBySymbol(good api.TradeSymbol) (api.ShipCargoItem, bo
 	return api.ShipCargoItem{}, false
 }
 
-func (s *Ship) ReceiveTransfer(from *Ship, tradeGoodSymbol api.TradeSymbol, maxUnits int) (bool, error) {
+func (s *Ship) ReceiveTransfer(ctx context.Context, from *Ship, tradeGoodSymbol api.TradeSymbol, maxUnits int) (bool, error) {
 	if s.IsCargoFull() {
 		s.log.Info("cargo full")
 		return false, nil
@@ -300,7 +561,7 @@ func (s *Ship) ReceiveTransfer(from *Ship, tradeGoodSymbol api.TradeSymbol, maxU
 	}
 
 	res, err := s.client.TransferCargo(
-		context.TODO(),
+		s.actionContext(ctx),
 		api.NewOptTransferCargoReq(api.TransferCargoReq{
 			TradeSymbol: item.Symbol,
 			Units:       units,
@@ -309,18 +570,23 @@ func (s *Ship) ReceiveTransfer(from *Ship, tradeGoodSymbol api.TradeSymbol, maxU
 		api.TransferCargoParams{ShipSymbol: from.symbol},
 	)
 	if err != nil {
+		s.syncFromError(ctx, err)
 		return false, err
 	}
 
-	from.state.Cargo = res.Data.Cargo
-	s.state.Cargo.Units += units
+	from.cargoChanged(res.Data.Cargo)
+
+	// the response only has the sender's hold, the client added the units to
+	// ours in the store
+	cargo := s.state().Cargo
+	s.cargoChanged(cargo)
 
-	s.log.Info("transfer received", "cargo.units", s.state.Cargo.Units, "transfer.from", from.symbol, "transfer.units", units, "transfer.good", item.Symbol)
+	s.log.Info("transfer received", "cargo.units", cargo.Units, "transfer.from", from.symbol, "transfer.units", units, "transfer.good", item.Symbol)
 
 	return true, nil
 }
 
-func (s *Ship) Transit(dest string) error {
+func (s *Ship) Transit(ctx context.Context, dest string) error {
 	origin := s.CurrWaypoint()
 
 	if origin == dest {
@@ -328,110 +594,107 @@ func (s *Ship) Transit(dest string) error {
 	}
 
 	if s.IsDocked() {
-		if err := s.Orbit(); err != nil {
+		if err := s.Orbit(ctx); err != nil {
 			return errors.Wrap(err, "Undock failed")
 		}
 	}
 
-	if s.state.Nav.FlightMode != api.ShipNavFlightModeCRUISE {
+	if s.state().Nav.FlightMode != api.ShipNavFlightModeCRUISE {
 		if _, err := s.client.PatchShipNav(
-			context.TODO(),
+			s.actionContext(ctx),
 			api.NewOptPatchShipNavReq(api.PatchShipNavReq{FlightMode: api.NewOptShipNavFlightMode(api.ShipNavFlightModeCRUISE)}),
 			api.PatchShipNavParams{ShipSymbol: s.symbol}); err != nil {
+			s.syncFromError(ctx, err)
 			return errors.Wrap(err, "Transit: failed to set flight mode")
 		}
 	}
 
 	res, err := s.client.NavigateShip(
-		context.TODO(),
+		s.actionContext(ctx),
 		api.NewOptNavigateShipReq(api.NavigateShipReq{WaypointSymbol: dest}),
 		api.NavigateShipParams{ShipSymbol: s.symbol},
 	)
 	if err != nil {
-		return errors.Wrap(err, "Transit: NavigateShip failed: maybe not enough fuel?")
+		s.syncFromError(ctx, err)
+		return errors.Wrap(err, "Transit: NavigateShip failed")
 	}
 
-	// update state
-	s.state.Fuel = res.Data.Fuel
-	s.state.Nav = res.Data.Nav
-
-	s.log.Info("transiting", "origin", origin, "dest", dest, "arrival", time.Until(s.state.Nav.Route.Arrival))
+	s.log.Info("transiting", "origin", origin, "dest", dest, "arrival", s.clock.Until(res.Data.Nav.Route.Arrival))
 	return nil
 }
 
-func (s *Ship) Survey() ([]api.Survey, error) {
+func (s *Ship) Survey(ctx context.Context) ([]api.Survey, error) {
 	if !s.HasSurveyor() {
 		return nil, nil
 	}
 
 	if s.IsDocked() {
-		if err := s.Orbit(); err != nil {
+		if err := s.Orbit(ctx); err != nil {
 			return nil, errors.Wrap(err, "Undock failed")
 		}
 	}
 
 	s.log.Info("Surveying")
 
-	res, err := s.client.CreateSurvey(context.TODO(), api.CreateSurveyParams{ShipSymbol: s.symbol})
+	res, err := s.client.CreateSurvey(s.actionContext(ctx), api.CreateSurveyParams{ShipSymbol: s.symbol})
 	if err != nil {
+		s.syncFromError(ctx, err)
 		return nil, errors.Wrap(err, "failed creating survey")
 	}
 
-	s.state.Cooldown = res.Data.Cooldown
 	return res.Data.Surveys, nil
 }
 
-func (s *Ship) Dock() error {
+func (s *Ship) Dock(ctx context.Context) error {
 	s.log.Info("Docking")
-	res, err := s.client.DockShip(context.TODO(), api.DockShipParams{ShipSymbol: s.symbol})
-	if err != nil {
+	if _, err := s.client.DockShip(s.actionContext(ctx), api.DockShipParams{ShipSymbol: s.symbol}); err != nil {
+		s.syncFromError(ctx, err)
 		return errors.Wrap(err, "Dock failed")
 	}
 
-	s.state.Nav = res.Data.Nav
-
 	return nil
 }
 
-func (s *Ship) Orbit() error {
+func (s *Ship) Orbit(ctx context.Context) error {
 	s.log.Info("Orbiting")
-	res, err := s.client.OrbitShip(context.TODO(), api.OrbitShipParams{ShipSymbol: s.symbol})
-	if err != nil {
+	if _, err := s.client.OrbitShip(s.actionContext(ctx), api.OrbitShipParams{ShipSymbol: s.symbol}); err != nil {
+		s.syncFromError(ctx, err)
 		return errors.Wrap(err, "Orbit failed")
 	}
 
-	s.state.Nav = res.Data.Nav
-
 	return nil
 }
 
-func (s *Ship) Refuel() error {
-	units := s.state.Fuel.Capacity - s.state.Fuel.Current
+func (s *Ship) Refuel(ctx context.Context) error {
+	fuel := s.state().Fuel
+	units := fuel.Capacity - fuel.Current
 
 	if units <= 0 {
 		return nil
 	}
 
 	if !s.IsDocked() {
-		if err := s.Dock(); err != nil {
+		if err := s.Dock(ctx); err != nil {
 			return errors.Wrap(err, "Refuel failed to dock")
 		}
 	}
 
 	req := api.RefuelShipReq{Units: api.NewOptInt(units)}
-	res, err := s.client.RefuelShip(context.TODO(), api.NewOptRefuelShipReq(req), api.RefuelShipParams{ShipSymbol: s.symbol})
+	res, err := s.client.RefuelShip(s.actionContext(ctx), api.NewOptRefuelShipReq(req), api.RefuelShipParams{ShipSymbol: s.symbol})
 	if err != nil {
+		s.syncFromError(ctx, err)
 		return errors.Wrap(err, "Refuel failed")
 	}
 
-	s.state.Fuel = res.Data.Fuel
+	s.traded(res.Data.Transaction, res.Data.Agent)
 	s.log.Info(fmt.Sprintf("Refueled: $%d (%d x $%d)", res.Data.Transaction.TotalPrice, units, res.Data.Transaction.PricePerUnit))
 
 	return nil
 }
 
 func (s *Ship) AvailableCargoUnits() int {
-	return s.state.Cargo.Capacity - s.state.Cargo.Units
+	cargo := s.state().Cargo
+	return cargo.Capacity - cargo.Units
 }
 
 func (s *Ship) HasFreeCargoSpace() bool {
@@ -439,12 +702,12 @@ func (s *Ship) HasFreeCargoSpace() bool {
 }
 
 func (s *Ship) At(wp string) bool {
-	return s.state.Nav.WaypointSymbol == api.WaypointSymbol(wp)
+	return s.state().Nav.WaypointSymbol == api.WaypointSymbol(wp)
 }
 
-func (s *Ship) Jettison(good string) error {
+func (s *Ship) Jettison(ctx context.Context, good string) error {
 	if s.IsDocked() {
-		if err := s.Orbit(); err != nil {
+		if err := s.Orbit(ctx); err != nil {
 			return errors.Wrap(err, "Undock failed")
 		}
 	}
@@ -458,17 +721,18 @@ func (s *Ship) Jettison(good string) error {
 	l := s.log.With("good", good, "units", units)
 
 	res, err := s.client.Jettison(
-		context.TODO(),
+		s.actionContext(ctx),
 		api.NewOptJettisonReq(api.JettisonReq{Symbol: api.TradeSymbol(good), Units: units}),
 		api.JettisonParams{ShipSymbol: s.symbol},
 	)
 	if err != nil {
+		s.syncFromError(ctx, err)
 		return errors.Wrap(err, "jettison failed:")
 	}
 
 	l.Info("jettisoned")
 
-	s.state.Cargo = res.Data.Cargo
+	s.cargoChanged(res.Data.Cargo)
 
 	return nil
 }
@@ -478,8 +742,8 @@ func (s *Ship) HasGood(good string) bool {
 }
 
 func (s *Ship) HasSurveyor() bool {
-	for _, m := range s.state.Mounts {
-		if m.Symbol == api.ShipMountSymbolMOUNTSURVEYORI || m.Symbol == api.ShipMountSymbolMOUNTSURVEYORII || m.Symbol == api.ShipMountSymbolMOUNTSURVEYORI {
+	for _, m := range s.state().Mounts {
+		if m.Symbol == api.ShipMountSymbolMOUNTSURVEYORI || m.Symbol == api.ShipMountSymbolMOUNTSURVEYORII || m.Symbol == api.ShipMountSymbolMOUNTSURVEYORIII {
 			return true
 		}
 	}
@@ -487,7 +751,7 @@ func (s *Ship) HasSurveyor() bool {
 }
 
 func (s *Ship) CurrWaypoint() string {
-	return string(s.state.Nav.WaypointSymbol)
+	return string(s.state().Nav.WaypointSymbol)
 }
 
 func (s *Ship) System() string {
@@ -495,15 +759,16 @@ func (s *Ship) System() string {
 }
 
 func (s *Ship) IsCargoFull() bool {
-	return s.state.Cargo.Units >= s.state.Cargo.Capacity
+	cargo := s.state().Cargo
+	return cargo.Units >= cargo.Capacity
 }
 
 func (s *Ship) IsCargoEmpty() bool {
-	return s.state.Cargo.Units == 0
+	return s.state().Cargo.Units == 0
 }
 
 func (s *Ship) IsDocked() bool {
-	return s.state.Nav.Status == api.ShipNavStatusDOCKED
+	return s.state().Nav.Status == api.ShipNavStatusDOCKED
 }
 
 func (s *Ship) IsIdle() bool {
@@ -511,66 +776,78 @@ func (s *Ship) IsIdle() bool {
 }
 
 func (s *Ship) InTransit() bool {
-	return s.state.Nav.Route.Arrival.After(time.Now())
+	return s.state().Nav.Route.Arrival.After(s.clock.Now())
 }
 
 func (s *Ship) Cooldown() {
-	t := time.Duration(s.state.Cooldown.RemainingSeconds)
+	t := time.Duration(s.state().Cooldown.RemainingSeconds)
 	if t > 0 {
 		s.log.Info(fmt.Sprintf("Cooldown: sleeping: %s", t))
-		time.Sleep(t * time.Second)
+		s.clock.Sleep(t * time.Second)
 	}
 }
 
-func (s *Ship) Extract(survey api.Survey) error {
+func (s *Ship) Extract(ctx context.Context, survey api.Survey) error {
 	if s.IsCargoFull() {
 		return nil
 	}
 
 	if s.IsDocked() {
-		if err := s.Orbit(); err != nil {
+		if err := s.Orbit(ctx); err != nil {
 			return errors.Wrap(err, "Undock failed")
 		}
 	}
 
 	var yield api.ExtractionYield
 
-	if time.Until(survey.Expiration) > 0 {
+	surveyed := false
+	if s.clock.Until(survey.Expiration) > 0 {
 		s.log.Info("leveraging survey")
 
 		os := api.NewOptSurvey(survey)
-		res, err := s.client.ExtractResourcesWithSurvey(context.TODO(), os, api.ExtractResourcesWithSurveyParams{ShipSymbol: s.symbol})
+		res, err := s.client.ExtractResourcesWithSurvey(s.actionContext(ctx), os, api.ExtractResourcesWithSurveyParams{ShipSymbol: s.symbol})
 		if err != nil {
-			return errors.Wrap(err, "ExtractResources failed")
+			// a spent survey shouldn't cost us the extraction, fall back to a plain one
+			var surveyErr *client.SurveyError
+			if !errors.As(err, &surveyErr) {
+				s.syncFromError(ctx, err)
+				return errors.Wrap(err, "ExtractResources failed")
+			}
+			s.log.Warn("survey rejected, extracting without it", "err", surveyErr)
+		} else {
+			s.cargoChanged(res.Data.Cargo)
+			yield = res.Data.Extraction.Yield
+			surveyed = true
 		}
-		s.state.Cooldown = res.Data.Cooldown
-		s.state.Cargo = res.Data.Cargo
-		yield = res.Data.Extraction.Yield
-	} else {
-		res, err := s.client.ExtractResources(context.TODO(), api.OptExtractResourcesReq{}, api.ExtractResourcesParams{ShipSymbol: s.symbol})
+	}
+
+	if !surveyed {
+		res, err := s.client.ExtractResources(s.actionContext(ctx), api.OptExtractResourcesReq{}, api.ExtractResourcesParams{ShipSymbol: s.symbol})
 		if err != nil {
+			s.syncFromError(ctx, err)
 			return errors.Wrap(err, "ExtractResources failed")
 		}
-		s.state.Cooldown = res.Data.Cooldown
-		s.state.Cargo = res.Data.Cargo
+		s.cargoChanged(res.Data.Cargo)
 		yield = res.Data.Extraction.Yield
 	}
 
-	s.log.Info(fmt.Sprintf("%s: Extracted %d %s", s.state.Symbol, yield.Units, yield.Symbol), "cargo.units", s.state.Cargo.Units, "cargo.cap", s.state.Cargo.Capacity)
+	cargo := s.state().Cargo
+	s.log.Info(fmt.Sprintf("%s: Extracted %d %s", s.symbol, yield.Units, yield.Symbol), "cargo.units", cargo.Units, "cargo.cap", cargo.Capacity)
 
 	return nil
 }
 
 func (s *Ship) InCooldown() bool {
-	return s.state.Cooldown.RemainingSeconds > 0
+	return s.state().Cooldown.RemainingSeconds > 0
 }
 
 func (s *Ship) IsFuelFull() bool {
-	return s.state.Fuel.Current == s.state.Fuel.Capacity
+	fuel := s.state().Fuel
+	return fuel.Current == fuel.Capacity
 }
 
 func (s *Ship) CountInventoryBySymbol(tradeSymbol string) int {
-	for _, inv := range s.state.Cargo.Inventory {
+	for _, inv := range s.state().Cargo.Inventory {
 		if inv.Symbol == api.TradeSymbol(tradeSymbol) {
 			return inv.Units
 		}
@@ -579,18 +856,41 @@ func (s *Ship) CountInventoryBySymbol(tradeSymbol string) int {
 }
 
 func (s *Ship) InventorySymbols() []string {
-	invs := make([]string, len(s.state.Cargo.Inventory))
-	for i, inv := range s.state.Cargo.Inventory {
+	inventory := s.state().Cargo.Inventory
+	invs := make([]string, len(inventory))
+	for i, inv := range inventory {
 		invs[i] = string(inv.Symbol)
 	}
 	return invs
 }
 
-func (s *Ship) Update() error {
-	res, err := s.client.GetMyShip(context.TODO(), api.GetMyShipParams{ShipSymbol: s.symbol})
-	if err != nil {
-		return err
+// Update fetches the whole ship, refreshing the store.
+func (s *Ship) Update(ctx context.Context) error {
+	_, err := s.client.GetMyShip(s.actionContext(ctx), api.GetMyShipParams{ShipSymbol: s.symbol})
+	return err
+}
+
+// syncFromError applies what a typed API error tells us about the ship to the
+// store, so the next tick waits on the real arrival or cooldown instead
+// of retrying blind.
+func (s *Ship) syncFromError(ctx context.Context, err error) {
+	var cooldownErr *client.CooldownError
+	var transitErr *client.ShipInTransitError
+	var navErr *client.ShipNavStatusError
+
+	switch {
+	case errors.As(err, &cooldownErr):
+		s.store.EditShip(s.symbol, func(ship *api.Ship) {
+			ship.Cooldown = cooldownErr.Cooldown
+		})
+	case errors.As(err, &transitErr):
+		s.store.EditShip(s.symbol, func(ship *api.Ship) {
+			ship.Nav.Status = api.ShipNavStatusINTRANSIT
+			ship.Nav.Route.Arrival = transitErr.Arrival
+		})
+	case errors.As(err, &navErr):
+		if err := s.Update(ctx); err != nil {
+			s.log.Warn(errors.Wrap(err, "failed to update ship state").Error())
+		}
 	}
-	s.state = &res.Data
-	return nil
 }
@@ -1,9 +1,12 @@
 package actors
 
 import (
+	"context"
+
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bt"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 )
 
 type TradeMission struct {
@@ -15,32 +18,33 @@ func (m *TradeMission) AssignShip(role MissionShipRole, ship *Ship) {
 	m.BaseMission.AssignShip(role, ship)
 }
 
-func (m *TradeMission) Execute(data *Blackboard) {
+func (m *TradeMission) Execute(ctx context.Context, data *Blackboard) {
 	shipRole, _ := m.GetShipRole(data.ship.symbol)
 	data.log = data.log.With("mission", "TradeMission", "role", shipRole)
-	data.repo = m.repo
+	data.repo = m.repo.WithContext(ctx)
+	data.world = m.world
 	data.mission = m
 
-	m.GetShipBehavior(data.ship.symbol).Tick(data)
+	bt.Tick(ctx, m.GetShipBehavior(data.ship.symbol), data)
 }
 
-func NewTradeMission(client api.Invoker, repo *repo.Repo) *TradeMission {
-	base := NewBaseMission(client, repo)
+func NewTradeMission(client api.Invoker, repo *repo.Repo, world *world.World) *TradeMission {
+	base := NewBaseMission(client, repo, world)
 	base.name = "TradeMission"
 
-	base.roleBehaviors[MissionShipRoleTrader] = bt.NewSelector(
-		bt.NewSequence(
+	base.roleBehaviors[MissionShipRoleTrader] = bt.NewSelector[*Blackboard](
+		bt.NewSequence[*Blackboard](
 			ConditionHasActiveTrade{},
-			bt.NewSelector(
+			bt.NewSelector[*Blackboard](
 				// offload any goods that aren't no the next trade
-				bt.NewSequence(
+				bt.NewSequence[*Blackboard](
 					ConditionIsAtTradeSource{},
-					bt.Invert(ConditionCargoIsFull{}),
-					bt.Invert(ConditionHasTradeCargo{}),
+					bt.Invert[*Blackboard](ConditionCargoIsFull{}),
+					bt.Invert[*Blackboard](ConditionHasTradeCargo{}),
 					ActionDock{},
 					ActionBuy{},
 				),
-				bt.NewSequence(
+				bt.NewSequence[*Blackboard](
 					ConditionIsAtTradeBuyer{},
 					ConditionHasTradeCargo{},
 					ActionDock{},
@@ -49,13 +53,13 @@ func NewTradeMission(client api.Invoker, repo *repo.Repo) *TradeMission {
 					// if anything prior fails, this wont run
 					ActionAssignBestTrade{},
 				),
-				bt.NewSelector(
-					bt.NewSequence(
+				bt.NewSelector[*Blackboard](
+					bt.NewSequence[*Blackboard](
 						ConditionHasTradeCargo{},
 						ActionSetDestinationTradeBuyer{},
 						NavigationAction(),
 					),
-					bt.NewSequence(
+					bt.NewSequence[*Blackboard](
 						ActionSetDestinationTradeSource{},
 						NavigationAction(),
 					),
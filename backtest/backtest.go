@@ -0,0 +1,295 @@
+// Package backtest replays recorded market snapshots through the trade
+// mission's decision loop, so a change to the trade heuristics can be scored
+// against history before it is deployed.
+//
+// Each simulated ship runs the loop TradeMission's behavior tree runs: pick
+// the best ranked trade, fly to the exporter, buy, fly to the importer, sell
+// and pick again. Trades are ranked with world.RankTrades and picked with
+// actors.BestTrade, and purchases are sized by actors.PurchaseUnits, the same
+// functions the mission calls. Prices come from the latest snapshot at the
+// simulated time, moved by our own simulated trades.
+package backtest
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/actors"
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/utils"
+	"github.com/bwiggs/spacetraders-go/world"
+)
+
+// DefaultMaxUnits matches the purchase limit ActionAssignBestTrade sets.
+const DefaultMaxUnits = actors.TradeMaxUnits
+
+// ShipConfig describes a simulated trader.
+type ShipConfig struct {
+	Symbol   string
+	Waypoint string
+	Speed    int
+	Cargo    int
+	Fuel     int
+}
+
+// Config controls a backtest run.
+type Config struct {
+	Ships   []ShipConfig
+	Credits int
+
+	// Start and End bound the simulated time, zero values use the first and
+	// last snapshot. Trips started before End run to completion.
+	Start time.Time
+	End   time.Time
+
+	// Strategy picks each ship's next trade, nil uses BestTrade.
+	Strategy Strategy
+
+	// MaxUnits caps a single purchase transaction, zero uses
+	// DefaultMaxUnits. Ships keep buying until their hold is full.
+	MaxUnits int
+
+	// Slippage is how far the price moves, as a fraction, for every trade
+	// volume's worth of our own buying or selling. Our impact decays back to
+	// the recorded price with a half life of Recovery.
+	Slippage float64
+	Recovery time.Duration
+
+	// FuelPrice is paid per 100 fuel at waypoints with no recorded FUEL price.
+	FuelPrice int
+}
+
+// DefaultConfig is a reasonable starting point for a single hauler.
+var DefaultConfig = Config{
+	Credits:   100_000,
+	MaxUnits:  DefaultMaxUnits,
+	Slippage:  0.05,
+	Recovery:  10 * time.Minute,
+	FuelPrice: 72,
+}
+
+// ShipReport is one ship's results.
+type ShipReport struct {
+	Symbol string
+	// Credits is sales less purchases and fuel.
+	Credits  int
+	Trips    int
+	Idle     time.Duration
+	Fuel     int
+	FuelCost int
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Start   time.Time
+	End     time.Time
+	Credits int
+	Ships   []ShipReport
+}
+
+// Earned is the credits earned by the whole fleet.
+func (r Report) Earned() int {
+	earned := 0
+	for _, s := range r.Ships {
+		earned += s.Credits
+	}
+	return earned
+}
+
+// Strategy chooses a ship's next trade from the ranked trades.
+type Strategy interface {
+	Choose(ship ShipState, trades []repo.MarketTrade) (repo.MarketTrade, bool)
+}
+
+// StrategyFunc adapts a function to a Strategy.
+type StrategyFunc func(ship ShipState, trades []repo.MarketTrade) (repo.MarketTrade, bool)
+
+func (f StrategyFunc) Choose(ship ShipState, trades []repo.MarketTrade) (repo.MarketTrade, bool) {
+	return f(ship, trades)
+}
+
+// BestTrade is ActionAssignBestTrade's choice, actors.BestTrade.
+var BestTrade = StrategyFunc(func(ship ShipState, trades []repo.MarketTrade) (repo.MarketTrade, bool) {
+	return actors.BestTrade(trades)
+})
+
+// ShipState is what a strategy knows about the ship it's choosing for.
+type ShipState struct {
+	Config   ShipConfig
+	Waypoint string
+	Credits  int
+
+	coords map[string]repo.Coords
+}
+
+// FuelTo returns the fuel needed to cruise from the ship's waypoint to wp.
+func (s ShipState) FuelTo(wp string) int {
+	if wp == s.Waypoint {
+		return 0
+	}
+	return fuelCost(distance(s.coords, s.Waypoint, wp))
+}
+
+// Run replays snapshots through the trade loop for every configured ship.
+func Run(cfg Config, snapshots []repo.MarketSnapshot, coords map[string]repo.Coords) (*Report, error) {
+	if len(snapshots) == 0 {
+		return nil, errors.New("backtest: no market snapshots")
+	}
+	if len(cfg.Ships) == 0 {
+		return nil, errors.New("backtest: no ships")
+	}
+	if cfg.Strategy == nil {
+		cfg.Strategy = BestTrade
+	}
+	if cfg.MaxUnits == 0 {
+		cfg.MaxUnits = DefaultMaxUnits
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].RecordedAt.Before(snapshots[j].RecordedAt)
+	})
+	if cfg.Start.IsZero() {
+		cfg.Start = snapshots[0].RecordedAt
+	}
+	if cfg.End.IsZero() {
+		cfg.End = snapshots[len(snapshots)-1].RecordedAt
+	}
+	if !cfg.End.After(cfg.Start) {
+		return nil, errors.New("backtest: end must be after start")
+	}
+
+	sim := &simulation{
+		cfg:     cfg,
+		coords:  coords,
+		market:  newMarket(snapshots, cfg.Slippage, cfg.Recovery),
+		credits: cfg.Credits,
+	}
+	for _, sc := range cfg.Ships {
+		sim.ships = append(sim.ships, &ship{
+			cfg:      sc,
+			waypoint: sc.Waypoint,
+			at:       cfg.Start,
+			report:   ShipReport{Symbol: sc.Symbol},
+		})
+	}
+	sim.run()
+
+	report := &Report{Start: cfg.Start, End: cfg.End, Credits: sim.credits}
+	for _, s := range sim.ships {
+		report.Ships = append(report.Ships, s.report)
+	}
+	return report, nil
+}
+
+type ship struct {
+	cfg      ShipConfig
+	waypoint string
+	at       time.Time
+	report   ShipReport
+}
+
+type simulation struct {
+	cfg     Config
+	coords  map[string]repo.Coords
+	market  *market
+	credits int
+	ships   []*ship
+}
+
+// run steps whichever ship is free earliest until all of them pass End.
+func (sim *simulation) run() {
+	for {
+		var next *ship
+		for _, s := range sim.ships {
+			if s.at.Before(sim.cfg.End) && (next == nil || s.at.Before(next.at)) {
+				next = s
+			}
+		}
+		if next == nil {
+			return
+		}
+		sim.step(next)
+	}
+}
+
+// step runs one pass of the trade loop for s, advancing its clock.
+func (sim *simulation) step(s *ship) {
+	trades := world.RankTrades(sim.market.listings(s.at), sim.coords)
+	state := ShipState{Config: s.cfg, Waypoint: s.waypoint, Credits: sim.credits, coords: sim.coords}
+	trade, ok := sim.cfg.Strategy.Choose(state, trades)
+	if !ok {
+		sim.idle(s)
+		return
+	}
+
+	sim.fly(s, trade.Origin)
+
+	spent, bought := sim.market.buy(s.at, trade.Origin, trade.Good, s.cfg.Cargo, sim.cfg.MaxUnits, sim.credits)
+	if bought == 0 {
+		// ActionBuy fails on insufficient credits, the tree reassigns next tick
+		sim.idle(s)
+		return
+	}
+	sim.credits -= spent
+	s.report.Credits -= spent
+
+	sim.fly(s, trade.Dest)
+
+	earned := sim.market.sell(s.at, trade.Dest, trade.Good, bought)
+	sim.credits += earned
+	s.report.Credits += earned
+	s.report.Trips++
+}
+
+// idle parks s until the market next changes, either with a new snapshot or
+// as our impact wears off.
+func (sim *simulation) idle(s *ship) {
+	until := sim.market.next(s.at)
+	if until.IsZero() || until.After(sim.cfg.End) {
+		until = sim.cfg.End
+	}
+	if sim.cfg.Recovery > 0 && s.at.Add(sim.cfg.Recovery).Before(until) {
+		until = s.at.Add(sim.cfg.Recovery)
+	}
+	s.report.Idle += until.Sub(s.at)
+	s.at = until
+}
+
+// fly cruises s to wp, refuelling for the leg at the departure waypoint.
+func (sim *simulation) fly(s *ship, wp string) {
+	if s.waypoint == wp {
+		return
+	}
+
+	d := distance(sim.coords, s.waypoint, wp)
+	fuel := fuelCost(d)
+	price, ok := sim.market.fuelPrice(s.at, s.waypoint)
+	if !ok {
+		price = sim.cfg.FuelPrice
+	}
+	cost := int(math.Ceil(float64(fuel*price) / 100))
+
+	sim.credits -= cost
+	s.report.Credits -= cost
+	s.report.Fuel += fuel
+	s.report.FuelCost += cost
+	s.at = s.at.Add(travelTime(d, s.cfg.Speed))
+	s.waypoint = wp
+}
+
+func distance(coords map[string]repo.Coords, from, to string) int {
+	a, b := coords[from], coords[to]
+	return utils.Distance2dInt(a.X, a.Y, b.X, b.Y)
+}
+
+// https://github.com/SpaceTradersAPI/api-docs/wiki/Travel-Fuel-and-Time
+func fuelCost(distance int) int {
+	return max(1, distance)
+}
+
+func travelTime(distance, speed int) time.Duration {
+	secs := math.Round(math.Round(math.Max(1, float64(distance)))*25/float64(max(1, speed)) + 15)
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,92 @@
+package backtest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/repo"
+)
+
+var (
+	start  = time.Date(2025, 5, 11, 0, 0, 0, 0, time.UTC)
+	coords = map[string]repo.Coords{
+		"X1-AA1-A1": {X: 0, Y: 0},
+		"X1-AA1-B2": {X: 30, Y: 40},
+	}
+)
+
+func snapshot(at time.Duration, wp, typ string, bid, ask int) repo.MarketSnapshot {
+	return repo.MarketSnapshot{
+		MarketListing: repo.MarketListing{Waypoint: wp, Good: "IRON", Type: typ, Volume: 10, Bid: bid, Ask: ask},
+		RecordedAt:    start.Add(at),
+	}
+}
+
+// A1 exports iron that B2 buys for 200 more, until B2's price drops at ten
+// minutes. Every leg is 50 units, 57 seconds and 36 credits of fuel.
+var snapshots = []repo.MarketSnapshot{
+	snapshot(0, "X1-AA1-A1", "EXPORT", 100, 90),
+	snapshot(0, "X1-AA1-B2", "IMPORT", 320, 300),
+	snapshot(10*time.Minute, "X1-AA1-B2", "IMPORT", 170, 150),
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		credits int
+		want    []ShipReport
+	}{
+		{
+			// the sixth trip sells after the price drop, the seventh would
+			// start after the last snapshot
+			name:    "fills the hold every trip",
+			credits: 10_000,
+			want: []ShipReport{{
+				Symbol:   "HAULER-1",
+				Credits:  (9_000 - 3_000 - 36) + 4*(9_000-3_000-72) + (4_500 - 3_000 - 72),
+				Trips:    6,
+				Fuel:     550,
+				FuelCost: 396,
+			}},
+		},
+		{
+			// the first trip's budget covers two 10 unit purchases, its
+			// sale pays for full holds after that
+			name:    "stops buying when credits run short",
+			credits: 2_500,
+			want: []ShipReport{{
+				Symbol:   "HAULER-1",
+				Credits:  (6_000 - 2_000 - 36) + 4*(9_000-3_000-72) + (4_500 - 3_000 - 72),
+				Trips:    6,
+				Fuel:     550,
+				FuelCost: 396,
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig
+			cfg.Credits = tt.credits
+			cfg.Slippage = 0
+			cfg.Recovery = 0
+			cfg.Ships = []ShipConfig{{Symbol: "HAULER-1", Waypoint: "X1-AA1-A1", Speed: 30, Cargo: 30, Fuel: 400}}
+
+			report, err := Run(cfg, snapshots, coords)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := &Report{
+				Start:   start,
+				End:     start.Add(10 * time.Minute),
+				Credits: tt.credits + tt.want[0].Credits,
+				Ships:   tt.want,
+			}
+			if !reflect.DeepEqual(report, want) {
+				t.Errorf("got %+v, want %+v", report, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package backtest
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/actors"
+	"github.com/bwiggs/spacetraders-go/repo"
+)
+
+type listingKey struct {
+	waypoint, good, typ string
+}
+
+type goodKey struct {
+	waypoint, good string
+}
+
+// impact is how far our own trades have pushed a good's price, as a fraction
+// of the recorded price, as of at.
+type impact struct {
+	value float64
+	at    time.Time
+}
+
+// market answers price questions at any simulated time: the latest recorded
+// listing, moved by whatever impact our trades still have on it.
+type market struct {
+	history  map[listingKey][]repo.MarketSnapshot
+	times    []time.Time
+	slippage float64
+	recovery time.Duration
+	impacts  map[goodKey]impact
+}
+
+func newMarket(snapshots []repo.MarketSnapshot, slippage float64, recovery time.Duration) *market {
+	m := &market{
+		history:  map[listingKey][]repo.MarketSnapshot{},
+		slippage: slippage,
+		recovery: recovery,
+		impacts:  map[goodKey]impact{},
+	}
+	// snapshots arrive sorted, so every history is too
+	for _, s := range snapshots {
+		k := listingKey{s.Waypoint, s.Good, s.Type}
+		m.history[k] = append(m.history[k], s)
+		if n := len(m.times); n == 0 || !m.times[n-1].Equal(s.RecordedAt) {
+			m.times = append(m.times, s.RecordedAt)
+		}
+	}
+	return m
+}
+
+// latest returns the listing as last recorded at or before t.
+func (m *market) latest(k listingKey, t time.Time) (repo.MarketListing, bool) {
+	h := m.history[k]
+	i := sort.Search(len(h), func(i int) bool { return h[i].RecordedAt.After(t) })
+	if i == 0 {
+		return repo.MarketListing{}, false
+	}
+	return h[i-1].MarketListing, true
+}
+
+// listings returns every known listing at t, priced with our impact.
+func (m *market) listings(t time.Time) []repo.MarketListing {
+	listings := []repo.MarketListing{}
+	for k := range m.history {
+		l, ok := m.latest(k, t)
+		if !ok {
+			continue
+		}
+		f := 1 + m.impact(goodKey{l.Waypoint, l.Good}, t)
+		l.Bid = price(l.Bid, f)
+		l.Ask = price(l.Ask, f)
+		listings = append(listings, l)
+	}
+	return listings
+}
+
+// next returns the first time after t that any listing changes, or zero.
+func (m *market) next(t time.Time) time.Time {
+	i := sort.Search(len(m.times), func(i int) bool { return m.times[i].After(t) })
+	if i == len(m.times) {
+		return time.Time{}
+	}
+	return m.times[i]
+}
+
+// impact returns our impact on a good at t. With no recovery set the market
+// shrugs us off between trades and slippage only applies within one.
+func (m *market) impact(k goodKey, t time.Time) float64 {
+	imp, ok := m.impacts[k]
+	if !ok || m.recovery <= 0 {
+		return 0
+	}
+	if !t.After(imp.at) {
+		return imp.value
+	}
+	return imp.value * math.Pow(0.5, float64(t.Sub(imp.at))/float64(m.recovery))
+}
+
+func (m *market) push(k goodKey, t time.Time, units, volume int, sign float64) {
+	m.impacts[k] = impact{
+		value: m.impact(k, t) + sign*m.slippage*float64(units)/float64(max(1, volume)),
+		at:    t,
+	}
+}
+
+// find returns the first of types listing good at wp.
+func (m *market) find(t time.Time, wp, good string, types ...string) (repo.MarketListing, bool) {
+	for _, typ := range types {
+		if l, ok := m.latest(listingKey{wp, good, typ}, t); ok {
+			return l, true
+		}
+	}
+	return repo.MarketListing{}, false
+}
+
+// buy fills a hold with space for units of good at wp, in transactions sized
+// as Ship.Buy sizes them, each priced after the last one moved the market. It
+// stops at the first transaction credits can't cover, as the server rejects
+// it.
+func (m *market) buy(t time.Time, wp, good string, units, maxUnits, credits int) (spent, bought int) {
+	l, ok := m.find(t, wp, good, "EXPORT", "EXCHANGE")
+	if !ok {
+		return 0, 0
+	}
+
+	k := goodKey{wp, good}
+	var scratch float64
+	for {
+		batch := actors.PurchaseUnits(units-bought, l.Volume, maxUnits)
+		p := price(l.Bid, 1+m.impact(k, t)+scratch)
+		if batch == 0 || p*batch > credits-spent {
+			break
+		}
+		spent += p * batch
+		bought += batch
+		if m.recovery > 0 {
+			m.push(k, t, batch, l.Volume, 1)
+		} else {
+			scratch += m.slippage * float64(batch) / float64(max(1, l.Volume))
+		}
+	}
+	return spent, bought
+}
+
+// sell sells units of good at wp in trade volume sized transactions, each
+// priced after the last one moved the market.
+func (m *market) sell(t time.Time, wp, good string, units int) (earned int) {
+	l, ok := m.find(t, wp, good, "IMPORT", "EXCHANGE")
+	if !ok {
+		return 0
+	}
+
+	k := goodKey{wp, good}
+	var scratch float64
+	for sold := 0; sold < units; {
+		batch := units - sold
+		if l.Volume > 0 {
+			batch = min(batch, l.Volume)
+		}
+		earned += price(l.Ask, 1+m.impact(k, t)+scratch) * batch
+		sold += batch
+		if m.recovery > 0 {
+			m.push(k, t, batch, l.Volume, -1)
+		} else {
+			scratch -= m.slippage * float64(batch) / float64(max(1, l.Volume))
+		}
+	}
+	return earned
+}
+
+// fuelPrice returns what 100 fuel costs at wp, if it sells fuel.
+func (m *market) fuelPrice(t time.Time, wp string) (int, bool) {
+	l, ok := m.find(t, wp, "FUEL", "EXCHANGE", "EXPORT", "IMPORT")
+	if !ok {
+		return 0, false
+	}
+	return price(l.Bid, 1+m.impact(goodKey{wp, "FUEL"}, t)), true
+}
+
+func price(p int, factor float64) int {
+	return max(1, int(math.Round(float64(p)*max(0, factor))))
+}
@@ -7,32 +7,21 @@ import (
 
 	"github.com/bwiggs/spacetraders-go/actors"
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client/paginate"
+	"github.com/bwiggs/spacetraders-go/events"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/scheduler"
+	"github.com/bwiggs/spacetraders-go/world"
 	"github.com/go-faster/errors"
 )
 
-func Start(client api.Invoker, r *repo.Repo) {
-	ships := []api.Ship{}
-	page := 1
-	processed := 0
-	for {
-		slog.Info(fmt.Sprintf("loading page %d", page))
-		res, err := client.GetMyShips(context.TODO(), api.GetMyShipsParams{Limit: api.NewOptInt(20), Page: api.NewOptInt(page)})
-		if err != nil {
-			slog.Error(errors.Wrap(err, "bot failed to load ships").Error())
-			return
-		}
-		ships = append(ships, res.Data...)
-		processed += len(res.Data)
-		if len(res.Data) == 0 {
-			break
-		}
-
-		if processed == res.Meta.Total {
-			break
-		}
-		page++
+func Start(ctx context.Context, client api.Invoker, store actors.ShipStore, r *repo.Repo, w *world.World, bus *events.Bus, sched *scheduler.Scheduler) {
+	ships, err := paginate.Collect(ctx, paginate.MyShips(client))
+	if err != nil {
+		slog.Error(errors.Wrap(err, "bot failed to load ships").Error())
+		return
 	}
+	slog.Info(fmt.Sprintf("loaded %d ships", len(ships)))
 
 	fleet := make(map[string]*api.Ship)
 	fleetByType := make(map[string][]*api.Ship)
@@ -47,62 +36,73 @@ func Start(client api.Invoker, r *repo.Repo) {
 		fleetByType[role] = append(fleetByType[role], &s)
 	}
 
-	go contractMission(client, r, fleet)
-	// go marketReconMission(client, r, fleetByType)
-	// go tradeMission(client, r, fleet)
-	// miningMission(client, r, fleet)
-	// extractionMission(client, r, fleet)
+	go contractMission(ctx, client, store, r, w, bus, fleetByType, sched)
+	// go marketReconMission(ctx, client, store, r, w, bus, fleetByType, sched)
+	// go tradeMission(ctx, client, store, r, w, bus, fleet, sched)
+	// miningMission(ctx, client, store, r, w, bus, fleet, sched)
+	// extractionMission(ctx, client, store, r, w, bus, fleet, sched)
 }
 
-func marketReconMission(client api.Invoker, r *repo.Repo, fleetByType map[string][]*api.Ship) {
-	mission := actors.NewMarketReconMission(client, r)
+func marketReconMission(ctx context.Context, client api.Invoker, store actors.ShipStore, r *repo.Repo, w *world.World, bus *events.Bus, fleetByType map[string][]*api.Ship, sched *scheduler.Scheduler) {
+	mission := actors.NewMarketReconMission(client, r, w)
 	for _, p := range fleetByType[string(api.ShipRoleSATELLITE)] {
-		mission.AssignShip(actors.MissionShipRoleTrader, actors.NewShip(p, client))
+		ship := actors.NewShip(p, store, client, sched, bus)
+		mission.AssignShip(actors.MissionShipRoleTrader, ship)
+		ship.Start(ctx)
 	}
 }
-func tradeMission(client api.Invoker, r *repo.Repo, fleet map[string]*api.Ship) {
-	commandShip := actors.NewShip(fleet["BWIGGS-B"], client)
+func tradeMission(ctx context.Context, client api.Invoker, store actors.ShipStore, r *repo.Repo, w *world.World, bus *events.Bus, fleet map[string]*api.Ship, sched *scheduler.Scheduler) {
+	commandShip := actors.NewShip(fleet["BWIGGS-B"], store, client, sched, bus)
 
-	tradeMission := actors.NewTradeMission(client, r)
+	tradeMission := actors.NewTradeMission(client, r, w)
 	tradeMission.AssignShip(actors.MissionShipRoleTrader, commandShip)
+	commandShip.Start(ctx)
 
 	// for _, s := range fleetByType[string(api.ShipRoleTRANSPORT)] {
-	// 	ship := actors.NewShip(s, client)
+	// 	ship := actors.NewShip(s, store, client, sched, bus)
 	// 	tradeMission.AssignShip(actors.MissionShipRoleTrader, ship)
 	// }
 }
-func miningMission(client api.Invoker, r *repo.Repo, fleet map[string]*api.Ship) {
-	// excavator := actors.NewShip(fleet["BWIGGS-5"], client)
+func miningMission(ctx context.Context, client api.Invoker, store actors.ShipStore, r *repo.Repo, w *world.World, bus *events.Bus, fleet map[string]*api.Ship, sched *scheduler.Scheduler) {
+	// excavator := actors.NewShip(fleet["BWIGGS-5"], store, client, sched, bus)
 	// excavator.SetMission(actors.NewMiningMission(r, "X1-HK42-AC5C"))
 
 	// for _, s := range harvestors {
-	// 	harvester := actors.NewShip(s, client)
+	// 	harvester := actors.NewShip(s, store, client, sched, bus)
 	// 	harvester.SetMission(actors.NewMiningMission(r, "X1-HK42-AC5C"))
 	// }
 }
 
-func extractionMission(client api.Invoker, r *repo.Repo, fleet map[string]*api.Ship) {
+func extractionMission(ctx context.Context, client api.Invoker, store actors.ShipStore, r *repo.Repo, w *world.World, bus *events.Bus, fleet map[string]*api.Ship, sched *scheduler.Scheduler) {
 	// // extract mission
 	// {
-	// 	extractMission := actors.NewExtractionMission(client, r, "X1-QY42-CZ5F")
+	// 	extractMission := actors.NewExtractionMission(client, r, w, "X1-QY42-CZ5F")
 	// 	// extractMission.AssignShip(actors.MissionShipRoleTransporter, command)
 	// 	for _, s := range fleetByType[string(api.ShipRoleEXCAVATOR)] {
-	// 		ship := actors.NewShip(s, client)
+	// 		ship := actors.NewShip(s, store, client, sched, bus)
 	// 		extractMission.AssignShip(actors.MissionShipRoleExcavator, ship)
 	// 	}
 	// 	for _, s := range fleetByType[string(api.ShipRoleHAULER)] {
-	// 		ship := actors.NewShip(s, client)
+	// 		ship := actors.NewShip(s, store, client, sched, bus)
 	// 		extractMission.AssignShip(actors.MissionShipRoleHauler, ship)
 	// 	}
 	// }
 
 	// // for _, s := range fleetByType[string(api.ShipRoleSURVEYOR)] {
-	// // 	ship := actors.NewShip(s, client)
+	// // 	ship := actors.NewShip(s, store, client, sched, bus)
 	// // 	extractMission.AssignShip(actors.MissionShipRoleSurveyor, ship)
 	// // }
 }
 
-func contractMission(client api.Invoker, r *repo.Repo, fleet map[string]*api.Ship) {
-	commandShip := actors.NewShip(fleet["BWIGGS-1"], client)
-	commandShip.SetMission(actors.NewContractMission(client, r))
+// contractMission runs contracts with the agent's command ship, so every
+// agent the kernel plays gets one.
+func contractMission(ctx context.Context, client api.Invoker, store actors.ShipStore, r *repo.Repo, w *world.World, bus *events.Bus, fleetByType map[string][]*api.Ship, sched *scheduler.Scheduler) {
+	commanders := fleetByType[string(api.ShipRoleCOMMAND)]
+	if len(commanders) == 0 {
+		slog.Warn("contractMission: no command ship")
+		return
+	}
+	commandShip := actors.NewShip(commanders[0], store, client, sched, bus)
+	commandShip.SetMission(actors.NewContractMission(client, r, w))
+	commandShip.Start(ctx)
 }
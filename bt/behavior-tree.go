@@ -1,12 +1,9 @@
+// Package bt is a small behavior tree library. Trees are generic over the
+// blackboard type T handed to every node's Tick, so nodes get their mission's
+// state typed rather than asserting it out of an any.
 package bt
 
-import (
-	"fmt"
-	"log/slog"
-	"reflect"
-)
-
-type Blackboard = any
+import "context"
 
 // BehaviorStatus represents the status of a behavior node.
 type BehaviorStatus int
@@ -20,41 +17,30 @@ const (
 	Running
 )
 
-// BehaviorNode defines the interface for behavior tree nodes.
-type BehaviorNode interface {
-	Tick(Blackboard) BehaviorStatus
-}
-
-func printResult(node any, status BehaviorStatus) {
-	// return
-	st := "Success"
-	switch status {
-	case 1:
-		st = "failure"
-	case 2:
-		st = "running"
-	}
-	slog.Debug(fmt.Sprintf("%s: %s", reflect.TypeOf(node).String(), st))
+// BehaviorNode defines the interface for behavior tree nodes ticked with a
+// blackboard of type T.
+type BehaviorNode[T any] interface {
+	Tick(context.Context, T) BehaviorStatus
 }
 
 // Sequence is a behavior node that executes its children in sequence.
-type Sequence struct {
-	children []BehaviorNode
+type Sequence[T any] struct {
+	children []BehaviorNode[T]
 }
 
 // NewSequence creates a new Sequence node with the given children.
 // A sequence will fail if any child fails.
-func NewSequence(children ...BehaviorNode) *Sequence {
-	return &Sequence{children: children}
+func NewSequence[T any](children ...BehaviorNode[T]) *Sequence[T] {
+	return &Sequence[T]{children: children}
 }
 
 // Tick executes each child node in sequence until one fails.
-func (s *Sequence) Tick(bb any) BehaviorStatus {
-	for _, child := range s.children {
-		status := child.Tick(bb)
-		printResult(child, status)
+func (s *Sequence[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	for i, child := range s.children {
+		status := tickChild(ctx, i, child, bb)
 
 		if status != Success {
+			abandonFrom(s.children, i+1, bb)
 			return status
 		}
 	}
@@ -62,44 +48,43 @@ func (s *Sequence) Tick(bb any) BehaviorStatus {
 }
 
 // Selector is a behavior node that executes its children until one succeeds.
-type Selector struct {
-	children []BehaviorNode
+type Selector[T any] struct {
+	children []BehaviorNode[T]
 }
 
 // NewSelector creates a new Selector node with the given children.
-func NewSelector(children ...BehaviorNode) *Selector {
-	return &Selector{children: children}
+func NewSelector[T any](children ...BehaviorNode[T]) *Selector[T] {
+	return &Selector[T]{children: children}
 }
 
 // NewOr creates a new Selector node with the given children.
-func NewOr(children ...BehaviorNode) *Selector {
-	return &Selector{children: children}
+func NewOr[T any](children ...BehaviorNode[T]) *Selector[T] {
+	return &Selector[T]{children: children}
 }
 
 // Tick executes each child node until one succeeds.
-func (s *Selector) Tick(bb any) BehaviorStatus {
-	for _, child := range s.children {
-		status := child.Tick(bb)
-		// printResult(child, status)
+func (s *Selector[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	for i, child := range s.children {
+		status := tickChild(ctx, i, child, bb)
 		if status != Failure {
+			abandonFrom(s.children, i+1, bb)
 			return status
 		}
 	}
 	return Failure
 }
 
-type Inversion struct {
-	child BehaviorNode
+type Inversion[T any] struct {
+	child BehaviorNode[T]
 }
 
-func Invert(child BehaviorNode) *Inversion {
-	return &Inversion{child}
+func Invert[T any](child BehaviorNode[T]) *Inversion[T] {
+	return &Inversion[T]{child}
 }
 
 // Tick executes each child node until one succeeds.
-func (i *Inversion) Tick(bb any) BehaviorStatus {
-	r := i.child.Tick(bb)
-	printResult(i.child, r)
+func (i *Inversion[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	r := tickChild(ctx, 0, i.child, bb)
 
 	if r == Success {
 		return Failure
@@ -110,30 +95,38 @@ func (i *Inversion) Tick(bb any) BehaviorStatus {
 	return Running
 }
 
-func AlwaysSucceed(child BehaviorNode) BehaviorNode {
-	return &AlwaysSucceedNode{child: child}
+func AlwaysSucceed[T any](child BehaviorNode[T]) BehaviorNode[T] {
+	return &AlwaysSucceedNode[T]{child: child}
 }
 
-type AlwaysSucceedNode struct {
-	child BehaviorNode
+type AlwaysSucceedNode[T any] struct {
+	child BehaviorNode[T]
 }
 
-func (s *AlwaysSucceedNode) Tick(bb any) BehaviorStatus {
-	r := s.child.Tick(bb)
-	printResult(s.child, r)
+func (s *AlwaysSucceedNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	tickChild(ctx, 0, s.child, bb)
 	return Success
 }
 
-func AlwaysFail(child BehaviorNode) BehaviorNode {
-	return &AlwaysFailNode{child: child}
+func AlwaysFail[T any](child BehaviorNode[T]) BehaviorNode[T] {
+	return &AlwaysFailNode[T]{child: child}
 }
 
-type AlwaysFailNode struct {
-	child BehaviorNode
+type AlwaysFailNode[T any] struct {
+	child BehaviorNode[T]
 }
 
-func (s *AlwaysFailNode) Tick(bb any) BehaviorStatus {
-	r := s.child.Tick(bb)
-	printResult(s.child, r)
+func (s *AlwaysFailNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	tickChild(ctx, 0, s.child, bb)
 	return Failure
 }
+
+func (s *Sequence[T]) Children() []BehaviorNode[T]          { return s.children }
+func (s *Selector[T]) Children() []BehaviorNode[T]          { return s.children }
+func (i *Inversion[T]) Children() []BehaviorNode[T]         { return []BehaviorNode[T]{i.child} }
+func (s *AlwaysSucceedNode[T]) Children() []BehaviorNode[T] { return []BehaviorNode[T]{s.child} }
+func (s *AlwaysFailNode[T]) Children() []BehaviorNode[T]    { return []BehaviorNode[T]{s.child} }
+
+func (i *Inversion[T]) Name() string         { return "Invert" }
+func (s *AlwaysSucceedNode[T]) Name() string { return "AlwaysSucceed" }
+func (s *AlwaysFailNode[T]) Name() string    { return "AlwaysFail" }
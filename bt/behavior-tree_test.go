@@ -0,0 +1,116 @@
+package bt
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/clock"
+)
+
+// board is a blackboard with a game clock, for the nodes that measure time.
+type board struct {
+	clk *clock.Fake
+}
+
+func (b *board) Clock() clock.Clock { return b.clk }
+
+func newBoard() *board {
+	return &board{clk: clock.NewFake(time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC))}
+}
+
+// stub is a leaf returning status, counting its ticks.
+type stub struct {
+	status BehaviorStatus
+	ticks  int
+}
+
+func (s *stub) Tick(ctx context.Context, bb *board) BehaviorStatus {
+	s.ticks++
+	return s.status
+}
+
+func leaves(statuses ...BehaviorStatus) ([]BehaviorNode[*board], []*stub) {
+	nodes := []BehaviorNode[*board]{}
+	stubs := []*stub{}
+	for _, s := range statuses {
+		leaf := &stub{status: s}
+		nodes = append(nodes, leaf)
+		stubs = append(stubs, leaf)
+	}
+	return nodes, stubs
+}
+
+func tick(node BehaviorNode[*board], bb *board) BehaviorStatus {
+	return node.Tick(context.Background(), bb)
+}
+
+func ticks(stubs []*stub) []int {
+	n := []int{}
+	for _, s := range stubs {
+		n = append(n, s.ticks)
+	}
+	return n
+}
+
+func TestSequenceAndSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     func(...BehaviorNode[*board]) BehaviorNode[*board]
+		children []BehaviorStatus
+		want     BehaviorStatus
+		ticked   []int
+	}{
+		{"sequence succeeds", seq, []BehaviorStatus{Success, Success}, Success, []int{1, 1}},
+		{"sequence stops at failure", seq, []BehaviorStatus{Success, Failure, Success}, Failure, []int{1, 1, 0}},
+		{"sequence stops at running", seq, []BehaviorStatus{Running, Success}, Running, []int{1, 0}},
+		{"selector fails", sel, []BehaviorStatus{Failure, Failure}, Failure, []int{1, 1}},
+		{"selector stops at success", sel, []BehaviorStatus{Failure, Success, Failure}, Success, []int{1, 1, 0}},
+		{"selector stops at running", sel, []BehaviorStatus{Running, Success}, Running, []int{1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			children, stubs := leaves(tt.children...)
+			if got := tick(tt.node(children...), newBoard()); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if got := ticks(stubs); !slices.Equal(got, tt.ticked) {
+				t.Errorf("children ticked %v, want %v", got, tt.ticked)
+			}
+		})
+	}
+}
+
+func seq(children ...BehaviorNode[*board]) BehaviorNode[*board] { return NewSequence(children...) }
+func sel(children ...BehaviorNode[*board]) BehaviorNode[*board] { return NewSelector(children...) }
+
+func TestInvertAndAlways(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  func(BehaviorNode[*board]) BehaviorNode[*board]
+		child BehaviorStatus
+		want  BehaviorStatus
+	}{
+		{"invert success", inv, Success, Failure},
+		{"invert failure", inv, Failure, Success},
+		{"invert running", inv, Running, Running},
+		{"always succeed", AlwaysSucceed[*board], Failure, Success},
+		{"always fail", AlwaysFail[*board], Success, Failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child := &stub{status: tt.child}
+			if got := tick(tt.node(child), newBoard()); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if child.ticks != 1 {
+				t.Errorf("child ticked %d times, want 1", child.ticks)
+			}
+		})
+	}
+}
+
+func inv(child BehaviorNode[*board]) BehaviorNode[*board] { return Invert(child) }
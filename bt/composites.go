@@ -0,0 +1,256 @@
+package bt
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+)
+
+// memory is a stateful node's state per blackboard. A mission's tree is
+// shared by every ship it ticks, concurrently, so nodes can't keep their
+// progress in their own fields.
+type memory[T comparable, V any] struct {
+	mu sync.Mutex
+	m  map[T]V
+}
+
+func (m *memory[T, V]) get(bb T) V {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.m[bb]
+}
+
+func (m *memory[T, V]) set(bb T, v V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.m == nil {
+		m.m = map[T]V{}
+	}
+	m.m[bb] = v
+}
+
+func (m *memory[T, V]) forget(bb T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, bb)
+}
+
+// stateful is a node that keeps state per blackboard from one tick to the
+// next.
+type stateful[T any] interface {
+	// abandon drops the state that only holds while the node keeps being
+	// ticked, called when its parent stops ticking it part way.
+	abandon(bb T)
+	// forget drops everything the node keeps for bb.
+	forget(bb T)
+}
+
+// Reset drops the state node and every node under it keep for bb, as if
+// they had never been ticked with it. Call it when bb is discarded, or the
+// tree is no longer ticked with it, so the tree doesn't hold on to it.
+func Reset[T any](node BehaviorNode[T], bb T) {
+	if s, ok := node.(stateful[T]); ok {
+		s.forget(bb)
+	}
+	for _, child := range Children(node) {
+		Reset(child, bb)
+	}
+}
+
+// abandon drops the running state of node and every node under it, e.g. a
+// running Timeout's start, after a parent skipped it. Otherwise the branch
+// would pick up where it left off the next time it's entered. What isn't
+// tied to running, like a Cooldown's last finish, is kept.
+func abandon[T any](node BehaviorNode[T], bb T) {
+	if s, ok := node.(stateful[T]); ok {
+		s.abandon(bb)
+	}
+	for _, child := range Children(node) {
+		abandon(child, bb)
+	}
+}
+
+// abandonFrom abandons children[from:], which the tick skipped.
+func abandonFrom[T any](children []BehaviorNode[T], from int, bb T) {
+	for _, child := range children[from:] {
+		abandon(child, bb)
+	}
+}
+
+// Policy decides when a Parallel node succeeds or fails.
+type Policy int
+
+const (
+	// RequireOne is met as soon as one child reports the status.
+	RequireOne Policy = iota
+	// RequireAll is met once every child reports the status.
+	RequireAll
+)
+
+// Parallel is a behavior node that ticks all its children every tick.
+type Parallel[T any] struct {
+	success  Policy
+	failure  Policy
+	children []BehaviorNode[T]
+}
+
+// NewParallel creates a new Parallel node with the given children. It fails
+// when the failure policy is met, succeeds when the success policy is met,
+// and is running otherwise.
+func NewParallel[T any](success, failure Policy, children ...BehaviorNode[T]) *Parallel[T] {
+	return &Parallel[T]{success: success, failure: failure, children: children}
+}
+
+// Tick executes every child and applies the policies to their statuses.
+func (p *Parallel[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	successes, failures := 0, 0
+	running := []BehaviorNode[T]{}
+	for i, child := range p.children {
+		status := tickChild(ctx, i, child, bb)
+
+		switch status {
+		case Success:
+			successes++
+		case Failure:
+			failures++
+		case Running:
+			running = append(running, child)
+		}
+	}
+
+	met := func(policy Policy, n int) bool {
+		if policy == RequireOne {
+			return n > 0
+		}
+		return n == len(p.children)
+	}
+
+	status := Running
+	if met(p.failure, failures) {
+		status = Failure
+	} else if met(p.success, successes) {
+		status = Success
+	}
+	if status != Running {
+		// the children still running are cut off
+		for _, child := range running {
+			abandon(child, bb)
+		}
+	}
+	return status
+}
+
+// RandomSelector is a behavior node that executes its children in a random
+// order until one succeeds.
+type RandomSelector[T any] struct {
+	children []BehaviorNode[T]
+}
+
+// NewRandomSelector creates a new RandomSelector node with the given
+// children, shuffled again on every tick.
+func NewRandomSelector[T any](children ...BehaviorNode[T]) *RandomSelector[T] {
+	return &RandomSelector[T]{children: children}
+}
+
+// Tick executes each child in a random order until one succeeds.
+func (s *RandomSelector[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	order := rand.Perm(len(s.children))
+	for n, i := range order {
+		child := s.children[i]
+		status := tickChild(ctx, i, child, bb)
+		if status != Failure {
+			for _, skipped := range order[n+1:] {
+				abandon(s.children[skipped], bb)
+			}
+			return status
+		}
+	}
+	return Failure
+}
+
+// MemSequence is a Sequence that remembers its running child. The next tick
+// resumes at that child instead of running the children before it again.
+type MemSequence[T comparable] struct {
+	children []BehaviorNode[T]
+	running  memory[T, int]
+}
+
+// NewMemSequence creates a new MemSequence node with the given children.
+func NewMemSequence[T comparable](children ...BehaviorNode[T]) *MemSequence[T] {
+	return &MemSequence[T]{children: children}
+}
+
+// Tick executes each child in sequence from the running one until one fails.
+func (s *MemSequence[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	for i := s.running.get(bb); i < len(s.children); i++ {
+		child := s.children[i]
+		status := tickChild(ctx, i, child, bb)
+
+		if status == Running {
+			s.running.set(bb, i)
+			abandonFrom(s.children, i+1, bb)
+			return Running
+		}
+		if status == Failure {
+			s.running.forget(bb)
+			abandonFrom(s.children, i+1, bb)
+			return Failure
+		}
+	}
+	s.running.forget(bb)
+	return Success
+}
+
+// MemSelector is a Selector that remembers its running child. The next tick
+// resumes at that child instead of trying the children before it again.
+type MemSelector[T comparable] struct {
+	children []BehaviorNode[T]
+	running  memory[T, int]
+}
+
+// NewMemSelector creates a new MemSelector node with the given children.
+func NewMemSelector[T comparable](children ...BehaviorNode[T]) *MemSelector[T] {
+	return &MemSelector[T]{children: children}
+}
+
+// Tick executes each child from the running one until one succeeds.
+func (s *MemSelector[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	for i := s.running.get(bb); i < len(s.children); i++ {
+		child := s.children[i]
+		status := tickChild(ctx, i, child, bb)
+
+		if status == Running {
+			s.running.set(bb, i)
+			abandonFrom(s.children, i+1, bb)
+			return Running
+		}
+		if status == Success {
+			s.running.forget(bb)
+			abandonFrom(s.children, i+1, bb)
+			return Success
+		}
+	}
+	s.running.forget(bb)
+	return Failure
+}
+
+func (p *Parallel[T]) Children() []BehaviorNode[T]       { return p.children }
+func (s *RandomSelector[T]) Children() []BehaviorNode[T] { return s.children }
+func (s *MemSequence[T]) Children() []BehaviorNode[T]    { return s.children }
+func (s *MemSelector[T]) Children() []BehaviorNode[T]    { return s.children }
+
+func (s *MemSequence[T]) abandon(bb T) { s.running.forget(bb) }
+func (s *MemSequence[T]) forget(bb T)  { s.running.forget(bb) }
+func (s *MemSelector[T]) abandon(bb T) { s.running.forget(bb) }
+func (s *MemSelector[T]) forget(bb T)  { s.running.forget(bb) }
+
+func (p *Parallel[T]) Name() string {
+	policy := func(p Policy) string {
+		if p == RequireAll {
+			return "all"
+		}
+		return "one"
+	}
+	return fmt.Sprintf("Parallel(success=%s, failure=%s)", policy(p.success), policy(p.failure))
+}
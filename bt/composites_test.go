@@ -0,0 +1,180 @@
+package bt
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	tests := []struct {
+		name             string
+		success, failure Policy
+		children         []BehaviorStatus
+		want             BehaviorStatus
+	}{
+		{"one success", RequireOne, RequireAll, []BehaviorStatus{Running, Success}, Success},
+		{"all success waits", RequireAll, RequireOne, []BehaviorStatus{Running, Success}, Running},
+		{"all success", RequireAll, RequireOne, []BehaviorStatus{Success, Success}, Success},
+		{"one failure", RequireAll, RequireOne, []BehaviorStatus{Success, Failure}, Failure},
+		{"all failure waits", RequireOne, RequireAll, []BehaviorStatus{Failure, Running}, Running},
+		{"failure before success", RequireOne, RequireOne, []BehaviorStatus{Success, Failure}, Failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			children, stubs := leaves(tt.children...)
+			if got := tick(NewParallel(tt.success, tt.failure, children...), newBoard()); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			for i, s := range stubs {
+				if s.ticks != 1 {
+					t.Errorf("child %d ticked %d times, want 1", i, s.ticks)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelAbandonsRunning(t *testing.T) {
+	bb := newBoard()
+	timeout := Timeout[*board](time.Minute, &stub{status: Running})
+	p := NewParallel(RequireOne, RequireAll, &stub{status: Success}, timeout)
+
+	if got := tick(p, bb); got != Success {
+		t.Fatalf("got %s, want success", got)
+	}
+	if started := timeout.started.get(bb); !started.IsZero() {
+		t.Errorf("running child cut off still started at %s", started)
+	}
+}
+
+func TestRandomSelector(t *testing.T) {
+	for range 20 {
+		children, stubs := leaves(Failure, Success, Failure)
+		if got := tick(NewRandomSelector(children...), newBoard()); got != Success {
+			t.Fatalf("got %s, want success", got)
+		}
+		if stubs[1].ticks != 1 {
+			t.Fatalf("succeeding child ticked %d times, want 1", stubs[1].ticks)
+		}
+	}
+
+	children, stubs := leaves(Failure, Failure, Failure)
+	if got := tick(NewRandomSelector(children...), newBoard()); got != Failure {
+		t.Fatalf("got %s, want failure", got)
+	}
+	if got := ticks(stubs); !slices.Equal(got, []int{1, 1, 1}) {
+		t.Fatalf("children ticked %v, want each once", got)
+	}
+}
+
+func TestMemSequence(t *testing.T) {
+	bb := newBoard()
+	children, stubs := leaves(Success, Running, Success)
+	s := NewMemSequence(children...)
+
+	if got := tick(s, bb); got != Running {
+		t.Fatalf("got %s, want running", got)
+	}
+	if got := tick(s, bb); got != Running {
+		t.Fatalf("got %s, want running", got)
+	}
+	if got := ticks(stubs); !slices.Equal(got, []int{1, 2, 0}) {
+		t.Fatalf("children ticked %v, want the running one resumed", got)
+	}
+
+	stubs[1].status = Success
+	if got := tick(s, bb); got != Success {
+		t.Fatalf("got %s, want success", got)
+	}
+	if got := tick(s, bb); got != Success {
+		t.Fatalf("got %s, want success", got)
+	}
+	if got := ticks(stubs); !slices.Equal(got, []int{2, 4, 2}) {
+		t.Fatalf("children ticked %v, want a fresh start after success", got)
+	}
+}
+
+func TestMemSelector(t *testing.T) {
+	bb := newBoard()
+	children, stubs := leaves(Failure, Running, Success)
+	s := NewMemSelector(children...)
+
+	tick(s, bb)
+	if got := tick(s, bb); got != Running {
+		t.Fatalf("got %s, want running", got)
+	}
+	if got := ticks(stubs); !slices.Equal(got, []int{1, 2, 0}) {
+		t.Fatalf("children ticked %v, want the running one resumed", got)
+	}
+
+	stubs[1].status = Failure
+	if got := tick(s, bb); got != Success {
+		t.Fatalf("got %s, want success", got)
+	}
+	if got := ticks(stubs); !slices.Equal(got, []int{1, 3, 1}) {
+		t.Fatalf("children ticked %v", got)
+	}
+}
+
+// guarded puts node behind a condition, with a fallback for when the
+// condition fails, so the branch can be left and entered again.
+func guarded(node BehaviorNode[*board]) (BehaviorNode[*board], *stub) {
+	cond := &stub{status: Success}
+	return NewSelector(NewSequence(cond, node), &stub{status: Success}), cond
+}
+
+func TestMemNodesAbandoned(t *testing.T) {
+	tests := []struct {
+		name string
+		node func(...BehaviorNode[*board]) BehaviorNode[*board]
+		kids []BehaviorStatus
+	}{
+		{"sequence", memSeq, []BehaviorStatus{Success, Running}},
+		{"selector", memSel, []BehaviorStatus{Failure, Running}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bb := newBoard()
+			children, stubs := leaves(tt.kids...)
+			tree, cond := guarded(tt.node(children...))
+
+			tick(tree, bb)
+			cond.status = Failure
+			tick(tree, bb)
+			cond.status = Success
+			tick(tree, bb)
+
+			if got := ticks(stubs); !slices.Equal(got, []int{2, 2}) {
+				t.Fatalf("children ticked %v, want the abandoned branch to start over", got)
+			}
+		})
+	}
+}
+
+func memSeq(children ...BehaviorNode[*board]) BehaviorNode[*board] {
+	return NewMemSequence(children...)
+}
+
+func memSel(children ...BehaviorNode[*board]) BehaviorNode[*board] {
+	return NewMemSelector(children...)
+}
+
+func TestReset(t *testing.T) {
+	bb, other := newBoard(), newBoard()
+	s := NewMemSequence[*board](&stub{status: Success}, &stub{status: Running})
+	tree := Named("tree", NewSequence[*board](s))
+
+	tick(tree, bb)
+	tick(tree, other)
+	Reset[*board](tree, bb)
+
+	if _, ok := s.running.m[bb]; ok {
+		t.Error("reset blackboard still remembered")
+	}
+	if _, ok := s.running.m[other]; !ok {
+		t.Error("other blackboard forgotten")
+	}
+}
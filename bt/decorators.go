@@ -0,0 +1,185 @@
+package bt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/clock"
+)
+
+// Clocked is a blackboard that knows the game time, for the nodes that
+// measure it. Game time is the ships clock, so a simulation on a fake clock
+// times out and cools down as fast as it runs.
+type Clocked interface {
+	comparable
+	Clock() clock.Clock
+}
+
+// RepeatNode succeeds once its child has succeeded n times, running the
+// child once per tick.
+type RepeatNode[T comparable] struct {
+	n     int
+	child BehaviorNode[T]
+	done  memory[T, int]
+}
+
+// RepeatN runs child until it has succeeded n times, e.g. buying a good in
+// several batches. It fails, and starts counting over, when child fails.
+func RepeatN[T comparable](n int, child BehaviorNode[T]) *RepeatNode[T] {
+	return &RepeatNode[T]{n: n, child: child}
+}
+
+func (r *RepeatNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	status := tickChild(ctx, 0, r.child, bb)
+
+	switch status {
+	case Failure:
+		r.done.forget(bb)
+		return Failure
+	case Success:
+		done := r.done.get(bb) + 1
+		if done >= r.n {
+			r.done.forget(bb)
+			return Success
+		}
+		r.done.set(bb, done)
+	}
+	return Running
+}
+
+// RepeatUntilFailureNode runs its child once per tick until it fails.
+type RepeatUntilFailureNode[T any] struct {
+	child BehaviorNode[T]
+}
+
+// RepeatUntilFailure runs child every tick while it succeeds, and succeeds
+// once it fails.
+func RepeatUntilFailure[T any](child BehaviorNode[T]) *RepeatUntilFailureNode[T] {
+	return &RepeatUntilFailureNode[T]{child: child}
+}
+
+func (r *RepeatUntilFailureNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	status := tickChild(ctx, 0, r.child, bb)
+
+	if status == Failure {
+		return Success
+	}
+	return Running
+}
+
+// RetryNode retries a failing child on later ticks.
+type RetryNode[T comparable] struct {
+	n        int
+	child    BehaviorNode[T]
+	failures memory[T, int]
+}
+
+// RetryN runs child again on the next tick when it fails, up to n attempts
+// in all, and then fails.
+func RetryN[T comparable](n int, child BehaviorNode[T]) *RetryNode[T] {
+	return &RetryNode[T]{n: n, child: child}
+}
+
+func (r *RetryNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	status := tickChild(ctx, 0, r.child, bb)
+
+	switch status {
+	case Success:
+		r.failures.forget(bb)
+		return Success
+	case Failure:
+		failures := r.failures.get(bb) + 1
+		if failures >= r.n {
+			r.failures.forget(bb)
+			return Failure
+		}
+		r.failures.set(bb, failures)
+	}
+	return Running
+}
+
+// TimeoutNode fails a child that has been running for too long.
+type TimeoutNode[T Clocked] struct {
+	limit   time.Duration
+	child   BehaviorNode[T]
+	started memory[T, time.Time]
+}
+
+// Timeout fails once child has been running for limit of game time, and
+// otherwise returns what child returns.
+func Timeout[T Clocked](limit time.Duration, child BehaviorNode[T]) *TimeoutNode[T] {
+	return &TimeoutNode[T]{limit: limit, child: child}
+}
+
+func (t *TimeoutNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	now := bb.Clock().Now()
+	started := t.started.get(bb)
+	if started.IsZero() {
+		started = now
+		t.started.set(bb, started)
+	}
+	if now.Sub(started) >= t.limit {
+		t.started.forget(bb)
+		abandon(t.child, bb)
+		return Failure
+	}
+
+	status := tickChild(ctx, 0, t.child, bb)
+
+	if status != Running {
+		t.started.forget(bb)
+	}
+	return status
+}
+
+// CooldownNode keeps a child from running again too soon.
+type CooldownNode[T Clocked] struct {
+	period   time.Duration
+	child    BehaviorNode[T]
+	finished memory[T, time.Time]
+}
+
+// Cooldown fails without ticking child until period of game time has passed
+// since child last succeeded or failed.
+func Cooldown[T Clocked](period time.Duration, child BehaviorNode[T]) *CooldownNode[T] {
+	return &CooldownNode[T]{period: period, child: child}
+}
+
+func (c *CooldownNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	now := bb.Clock().Now()
+	if finished := c.finished.get(bb); !finished.IsZero() && now.Sub(finished) < c.period {
+		abandon(c.child, bb)
+		return Failure
+	}
+
+	status := tickChild(ctx, 0, c.child, bb)
+
+	if status != Running {
+		c.finished.set(bb, bb.Clock().Now())
+	}
+	return status
+}
+
+func (r *RepeatNode[T]) Children() []BehaviorNode[T]             { return []BehaviorNode[T]{r.child} }
+func (r *RepeatUntilFailureNode[T]) Children() []BehaviorNode[T] { return []BehaviorNode[T]{r.child} }
+func (r *RetryNode[T]) Children() []BehaviorNode[T]              { return []BehaviorNode[T]{r.child} }
+func (t *TimeoutNode[T]) Children() []BehaviorNode[T]            { return []BehaviorNode[T]{t.child} }
+func (c *CooldownNode[T]) Children() []BehaviorNode[T]           { return []BehaviorNode[T]{c.child} }
+
+func (r *RepeatNode[T]) abandon(bb T)  { r.done.forget(bb) }
+func (r *RepeatNode[T]) forget(bb T)   { r.done.forget(bb) }
+func (r *RetryNode[T]) abandon(bb T)   { r.failures.forget(bb) }
+func (r *RetryNode[T]) forget(bb T)    { r.failures.forget(bb) }
+func (t *TimeoutNode[T]) abandon(bb T) { t.started.forget(bb) }
+func (t *TimeoutNode[T]) forget(bb T)  { t.started.forget(bb) }
+
+// a cooldown holds while the branch isn't ticked, it's only forgotten
+func (c *CooldownNode[T]) abandon(bb T) {}
+func (c *CooldownNode[T]) forget(bb T)  { c.finished.forget(bb) }
+
+func (r *RepeatNode[T]) Name() string             { return fmt.Sprintf("RepeatN(%d)", r.n) }
+func (r *RepeatUntilFailureNode[T]) Name() string { return "RepeatUntilFailure" }
+func (r *RetryNode[T]) Name() string              { return fmt.Sprintf("RetryN(%d)", r.n) }
+func (t *TimeoutNode[T]) Name() string            { return fmt.Sprintf("Timeout(%s)", t.limit) }
+func (c *CooldownNode[T]) Name() string           { return fmt.Sprintf("Cooldown(%s)", c.period) }
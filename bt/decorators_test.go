@@ -0,0 +1,165 @@
+package bt
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func statuses(node BehaviorNode[*board], bb *board, n int) []BehaviorStatus {
+	got := []BehaviorStatus{}
+	for range n {
+		got = append(got, tick(node, bb))
+	}
+	return got
+}
+
+func TestRepeatAndRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  func(child BehaviorNode[*board]) BehaviorNode[*board]
+		child BehaviorStatus
+		want  []BehaviorStatus
+	}{
+		{"repeat counts successes", repeat3, Success, []BehaviorStatus{Running, Running, Success, Running}},
+		{"repeat fails with child", repeat3, Failure, []BehaviorStatus{Failure, Failure}},
+		{"repeat waits on running", repeat3, Running, []BehaviorStatus{Running, Running, Running, Running}},
+		{"repeat until failure runs", untilFailure, Success, []BehaviorStatus{Running, Running}},
+		{"repeat until failure ends", untilFailure, Failure, []BehaviorStatus{Success}},
+		{"retry counts failures", retry3, Failure, []BehaviorStatus{Running, Running, Failure, Running}},
+		{"retry succeeds with child", retry3, Success, []BehaviorStatus{Success, Success}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statuses(tt.node(&stub{status: tt.child}), newBoard(), len(tt.want))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func repeat3(child BehaviorNode[*board]) BehaviorNode[*board] { return RepeatN(3, child) }
+func retry3(child BehaviorNode[*board]) BehaviorNode[*board]  { return RetryN(3, child) }
+func untilFailure(child BehaviorNode[*board]) BehaviorNode[*board] {
+	return RepeatUntilFailure(child)
+}
+
+func TestCountersAbandoned(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  func(child BehaviorNode[*board]) BehaviorNode[*board]
+		child BehaviorStatus
+	}{
+		{"repeat", repeat3, Success},
+		{"retry", retry3, Failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bb := newBoard()
+			tree, cond := guarded(tt.node(&stub{status: tt.child}))
+
+			// counts two of three, then the branch is left and entered again
+			statuses(tree, bb, 2)
+			cond.status = Failure
+			tick(tree, bb)
+			cond.status = Success
+
+			if got := statuses(tree, bb, 2); !slices.Equal(got, []BehaviorStatus{Running, Running}) {
+				t.Fatalf("got %v, want the count started over", got)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	bb := newBoard()
+	child := &stub{status: Running}
+	timeout := Timeout[*board](time.Minute, child)
+
+	if got := tick(timeout, bb); got != Running {
+		t.Fatalf("got %s, want running", got)
+	}
+	bb.clk.Advance(30 * time.Second)
+	if got := tick(timeout, bb); got != Running {
+		t.Fatalf("got %s, want running before the limit", got)
+	}
+	bb.clk.Advance(30 * time.Second)
+	if got := tick(timeout, bb); got != Failure {
+		t.Fatalf("got %s, want failure at the limit", got)
+	}
+	if child.ticks != 2 {
+		t.Fatalf("child ticked %d times, want not ticked once timed out", child.ticks)
+	}
+	if got := tick(timeout, bb); got != Running {
+		t.Fatalf("got %s, want a fresh start after timing out", got)
+	}
+
+	child.status = Success
+	bb.clk.Advance(30 * time.Second)
+	tick(timeout, bb)
+	bb.clk.Advance(time.Hour)
+	child.status = Running
+	if got := tick(timeout, bb); got != Running {
+		t.Fatalf("got %s, want a fresh start after the child finished", got)
+	}
+}
+
+func TestTimeoutAbandoned(t *testing.T) {
+	bb := newBoard()
+	tree, cond := guarded(Timeout[*board](time.Minute, &stub{status: Running}))
+
+	tick(tree, bb)
+	cond.status = Failure
+	tick(tree, bb)
+	bb.clk.Advance(time.Hour)
+	cond.status = Success
+
+	if got := tick(tree, bb); got != Running {
+		t.Fatalf("got %s, want the abandoned timeout to start over", got)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	bb := newBoard()
+	child := &stub{status: Success}
+	cooldown := Cooldown[*board](time.Minute, child)
+
+	if got := tick(cooldown, bb); got != Success {
+		t.Fatalf("got %s, want success", got)
+	}
+	bb.clk.Advance(30 * time.Second)
+	if got := tick(cooldown, bb); got != Failure {
+		t.Fatalf("got %s, want failure while cooling down", got)
+	}
+	if child.ticks != 1 {
+		t.Fatalf("child ticked %d times while cooling down", child.ticks)
+	}
+	bb.clk.Advance(30 * time.Second)
+	if got := tick(cooldown, bb); got != Success {
+		t.Fatalf("got %s, want success once cooled down", got)
+	}
+}
+
+func TestCooldownAbandonedAndReset(t *testing.T) {
+	bb := newBoard()
+	cooldown := Cooldown[*board](time.Minute, &stub{status: Success})
+	tree, cond := guarded(cooldown)
+
+	tick(tree, bb)
+	cond.status = Failure
+	tick(tree, bb)
+	cond.status = Success
+
+	// the cooldown outlasts leaving the branch
+	if got := tick(cooldown, bb); got != Failure {
+		t.Fatalf("got %s, want the cooldown kept", got)
+	}
+
+	Reset(tree, bb)
+	if got := tick(cooldown, bb); got != Success {
+		t.Fatalf("got %s, want the cooldown forgotten on reset", got)
+	}
+}
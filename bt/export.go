@@ -0,0 +1,109 @@
+package bt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exportColors fill the nodes of exported trees by their last status.
+var exportColors = map[BehaviorStatus]string{
+	Success: "#9be79b",
+	Failure: "#f19c9c",
+	Running: "#f5dc82",
+}
+
+// exportNode is a node of the tree being exported, with its path and the
+// status it last returned.
+type exportNode struct {
+	id     string
+	name   string
+	parent string
+	leaf   bool
+	status BehaviorStatus
+	ticked bool
+}
+
+func walk[T any](root BehaviorNode[T], rec *Recorder) []exportNode {
+	nodes := []exportNode{}
+
+	var visit func(node BehaviorNode[T], path, parent string)
+	visit = func(node BehaviorNode[T], path, parent string) {
+		children := Children(node)
+		status, ticked := rec.Status(path)
+		n := exportNode{
+			id:     "n" + strings.ReplaceAll(path, ".", "_"),
+			name:   Name(node),
+			parent: parent,
+			leaf:   len(children) == 0,
+			status: status,
+			ticked: ticked,
+		}
+		nodes = append(nodes, n)
+
+		for i, child := range children {
+			visit(child, path+"."+strconv.Itoa(i), n.id)
+		}
+	}
+	visit(root, "0", "")
+
+	return nodes
+}
+
+// DOT renders the tree under root as a Graphviz digraph. With rec, nodes
+// are filled by the status they returned in the recorded tick.
+func DOT[T any](root BehaviorNode[T], rec *Recorder) string {
+	var b strings.Builder
+	b.WriteString("digraph bt {\n")
+	b.WriteString("\tnode [fontname=\"Helvetica\", shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+
+	for _, n := range walk(root, rec) {
+		attrs := []string{"label=" + strconv.Quote(n.name)}
+		if n.leaf {
+			attrs = append(attrs, "style=filled")
+		}
+		if n.ticked {
+			attrs = append(attrs, "fillcolor="+strconv.Quote(exportColors[n.status]))
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", n.id, strings.Join(attrs, ", "))
+		if n.parent != "" {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", n.parent, n.id)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the tree under root as a Mermaid flowchart. With rec,
+// nodes are styled by the status they returned in the recorded tick.
+func Mermaid[T any](root BehaviorNode[T], rec *Recorder) string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	nodes := walk(root, rec)
+	for _, n := range nodes {
+		label := strings.ReplaceAll(n.name, `"`, "#quot;")
+		if n.leaf {
+			fmt.Fprintf(&b, "\t%s[\"%s\"]\n", n.id, label)
+		} else {
+			fmt.Fprintf(&b, "\t%s([\"%s\"])\n", n.id, label)
+		}
+		if n.parent != "" {
+			fmt.Fprintf(&b, "\t%s --> %s\n", n.parent, n.id)
+		}
+	}
+
+	if rec != nil {
+		for _, s := range []BehaviorStatus{Success, Failure, Running} {
+			fmt.Fprintf(&b, "\tclassDef %s fill:%s\n", s, exportColors[s])
+		}
+		for _, n := range nodes {
+			if n.ticked {
+				fmt.Fprintf(&b, "\tclass %s %s\n", n.id, n.status)
+			}
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,207 @@
+package bt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func (s BehaviorStatus) String() string {
+	switch s {
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	case Running:
+		return "running"
+	}
+	return "unknown"
+}
+
+// ParseStatus returns the status s names, as printed by String.
+func ParseStatus(s string) (BehaviorStatus, error) {
+	for _, status := range []BehaviorStatus{Success, Failure, Running} {
+		if status.String() == s {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown behavior status %q", s)
+}
+
+// Namer is a node that names itself, nodes without a name go by their type.
+type Namer interface {
+	Name() string
+}
+
+// Parent is a node with children, composites and decorators.
+type Parent[T any] interface {
+	Children() []BehaviorNode[T]
+}
+
+// Name returns node's name, or its type name without the package and type
+// arguments.
+func Name(node any) string {
+	if n, ok := node.(Namer); ok {
+		return n.Name()
+	}
+	t := reflect.TypeOf(node)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	name, _, _ := strings.Cut(t.Name(), "[")
+	return name
+}
+
+// Children returns node's children, or nil for a leaf.
+func Children[T any](node BehaviorNode[T]) []BehaviorNode[T] {
+	if p, ok := node.(Parent[T]); ok {
+		return p.Children()
+	}
+	return nil
+}
+
+// NamedNode gives a node a name to show in exported trees, it ticks exactly
+// as the node it wraps.
+type NamedNode[T any] struct {
+	name string
+	node BehaviorNode[T]
+}
+
+// Named names node, e.g. to label a sequence by what it achieves.
+func Named[T any](name string, node BehaviorNode[T]) *NamedNode[T] {
+	return &NamedNode[T]{name: name, node: node}
+}
+
+func (n *NamedNode[T]) Tick(ctx context.Context, bb T) BehaviorStatus {
+	return n.node.Tick(ctx, bb)
+}
+
+func (n *NamedNode[T]) Name() string { return n.name }
+
+func (n *NamedNode[T]) Children() []BehaviorNode[T] { return Children(n.node) }
+
+// the node's children are reached through Children, only its own state is
+// passed on
+func (n *NamedNode[T]) abandon(bb T) {
+	if s, ok := n.node.(stateful[T]); ok {
+		s.abandon(bb)
+	}
+}
+
+func (n *NamedNode[T]) forget(bb T) {
+	if s, ok := n.node.(stateful[T]); ok {
+		s.forget(bb)
+	}
+}
+
+// Visit is a node ticked during a tick, with the status it returned.
+type Visit struct {
+	// Path is the node's position in the tree: "0" for the root, "0.2" for
+	// its third child.
+	Path     string
+	Name     string
+	Status   BehaviorStatus
+	Duration time.Duration
+}
+
+// Recorder collects the nodes visited during a tick, in the order they were
+// entered, and the status each returned.
+type Recorder struct {
+	mu     sync.Mutex
+	visits []Visit
+	byPath map[string]int
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{byPath: map[string]int{}}
+}
+
+// Status returns the status the node at path returned, if it was ticked.
+func (r *Recorder) Status(path string) (BehaviorStatus, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.byPath[path]
+	if !ok {
+		return 0, false
+	}
+	return r.visits[i].Status, true
+}
+
+// Visits returns the nodes visited, parents before their children.
+func (r *Recorder) Visits() []Visit {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.visits)
+}
+
+// Record adds a visit made outside of Tick, e.g. one read back from a
+// stored tick trace.
+func (r *Recorder) Record(v Visit) {
+	i := r.enter(v.Path, v.Name)
+	r.leave(i, v.Status, v.Duration)
+}
+
+// enter records a visit to the node at path before it's ticked, so parents
+// are listed before their children, and returns its index for leave.
+func (r *Recorder) enter(path, name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.visits = append(r.visits, Visit{Path: path, Name: name})
+	r.byPath[path] = len(r.visits) - 1
+	return len(r.visits) - 1
+}
+
+func (r *Recorder) leave(i int, s BehaviorStatus, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.visits[i].Status = s
+	r.visits[i].Duration = d
+}
+
+type pathKey struct{}
+
+type tickPath struct {
+	rec  *Recorder
+	path string
+}
+
+// WithRecorder records the nodes of the tree ticked with ctx through Tick
+// into rec.
+func WithRecorder(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, pathKey{}, tickPath{rec: rec, path: "0"})
+}
+
+// Tick ticks the root of a tree, recording its visit when ctx carries a
+// Recorder.
+func Tick[T any](ctx context.Context, node BehaviorNode[T], bb T) BehaviorStatus {
+	p, ok := ctx.Value(pathKey{}).(tickPath)
+	if !ok {
+		return node.Tick(ctx, bb)
+	}
+
+	start := time.Now()
+	i := p.rec.enter(p.path, Name(node))
+	status := node.Tick(ctx, bb)
+	p.rec.leave(i, status, time.Since(start))
+	return status
+}
+
+// tickChild ticks a composite or decorator's i'th child.
+func tickChild[T any](ctx context.Context, i int, child BehaviorNode[T], bb T) BehaviorStatus {
+	if p, ok := ctx.Value(pathKey{}).(tickPath); ok {
+		p.path += "." + strconv.Itoa(i)
+		ctx = context.WithValue(ctx, pathKey{}, p)
+	}
+	return Tick(ctx, child, bb)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/backtest"
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/spf13/cobra"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var backtestFlags struct {
+	ships    []string
+	credits  int
+	since    time.Duration
+	maxUnits int
+	slippage float64
+	recovery time.Duration
+}
+
+func init() {
+	backtestCmd.Flags().StringSliceVar(&backtestFlags.ships, "ship", nil, "fleet ships to simulate, defaults to every hauler")
+	backtestCmd.Flags().IntVar(&backtestFlags.credits, "credits", backtest.DefaultConfig.Credits, "starting credits")
+	backtestCmd.Flags().DurationVar(&backtestFlags.since, "since", 0, "only replay the most recent window of snapshots, e.g. 24h")
+	backtestCmd.Flags().IntVar(&backtestFlags.maxUnits, "max-units", backtest.DefaultConfig.MaxUnits, "units bought per purchase transaction")
+	backtestCmd.Flags().Float64Var(&backtestFlags.slippage, "slippage", backtest.DefaultConfig.Slippage, "price move per trade volume of our own trading")
+	backtestCmd.Flags().DurationVar(&backtestFlags.recovery, "recovery", backtest.DefaultConfig.Recovery, "half life of our price impact")
+	rootCmd.AddCommand(backtestCmd)
+}
+
+var backtestCmd = &cobra.Command{
+	Use:   "backtest",
+	Short: "replays recorded market snapshots against the trade strategy",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBacktest(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func runBacktest() error {
+	r, err := repo.GetRepo()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	snapshots, err := r.GetMarketSnapshots()
+	if err != nil {
+		return err
+	}
+	coords, err := r.GetWaypointCoords()
+	if err != nil {
+		return err
+	}
+	fleet, err := r.GetFleet()
+	if err != nil {
+		return err
+	}
+
+	cfg := backtest.DefaultConfig
+	cfg.Credits = backtestFlags.credits
+	cfg.MaxUnits = backtestFlags.maxUnits
+	cfg.Slippage = backtestFlags.slippage
+	cfg.Recovery = backtestFlags.recovery
+	if backtestFlags.since > 0 && len(snapshots) > 0 {
+		cfg.Start = snapshots[len(snapshots)-1].RecordedAt.Add(-backtestFlags.since)
+	}
+
+	for _, s := range fleet {
+		if len(backtestFlags.ships) > 0 && !slices.Contains(backtestFlags.ships, s.Symbol) {
+			continue
+		}
+		if len(backtestFlags.ships) == 0 && s.Registration.Role != api.ShipRoleHAULER {
+			continue
+		}
+		cfg.Ships = append(cfg.Ships, backtest.ShipConfig{
+			Symbol:   s.Symbol,
+			Waypoint: string(s.Nav.WaypointSymbol),
+			Speed:    s.Engine.Speed,
+			Cargo:    s.Cargo.Capacity,
+			Fuel:     s.Fuel.Capacity,
+		})
+	}
+
+	report, err := backtest.Run(cfg, snapshots, coords)
+	if err != nil {
+		return err
+	}
+
+	p := message.NewPrinter(language.English)
+
+	fmt.Printf("%s - %s (%s)\n\n", report.Start.Format(time.RFC3339), report.End.Format(time.RFC3339), report.End.Sub(report.Start))
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	fmt.Fprintln(w, "SHIP\tCREDITS\tTRIPS\tIDLE\tFUEL\tFUEL COST")
+	for _, s := range report.Ships {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%s\n", s.Symbol, p.Sprintf("%d", s.Credits), s.Trips, s.Idle.Round(time.Second), s.Fuel, p.Sprintf("%d", s.FuelCost))
+	}
+	w.Flush()
+
+	fmt.Println()
+	p.Printf("Earned: %d\n", report.Earned())
+	p.Printf("Credits: %d\n", report.Credits)
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/bwiggs/spacetraders-go/actors"
+	"github.com/bwiggs/spacetraders-go/bt"
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/spf13/cobra"
+)
+
+var btFlags struct {
+	format string
+	role   string
+	ship   string
+}
+
+// btMissions builds the missions whose trees can be exported. Building a
+// tree needs no client, repo or world, those are only used when it's ticked.
+var btMissions = map[string]func() actors.Mission{
+	"contract":     func() actors.Mission { return actors.NewContractMission(nil, nil, nil) },
+	"trade":        func() actors.Mission { return actors.NewTradeMission(nil, nil, nil) },
+	"extraction":   func() actors.Mission { return actors.NewExtractionMission(nil, nil, nil, "") },
+	"market-recon": func() actors.Mission { return actors.NewMarketReconMission(nil, nil, nil) },
+}
+
+func init() {
+	btCmd.Flags().StringVar(&btFlags.format, "format", "dot", "output format, dot or mermaid")
+	btCmd.Flags().StringVar(&btFlags.role, "role", "", "ship role of the tree to export, for missions with a tree per role")
+	btCmd.Flags().StringVar(&btFlags.ship, "ship", "", "color the tree by the statuses of this ship's last recorded tick")
+	rootCmd.AddCommand(btCmd)
+}
+
+var btCmd = &cobra.Command{
+	Use:   "bt <mission>",
+	Short: "prints a mission's behavior tree as Graphviz DOT or Mermaid",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := printBehaviorTree(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func printBehaviorTree(name string) error {
+	newMission, ok := btMissions[name]
+	if !ok {
+		return fmt.Errorf("unknown mission %q, expected one of %s", name, strings.Join(sortedKeys(btMissions), ", "))
+	}
+
+	trees := newMission().Trees()
+	role := btFlags.role
+	if role == "" && len(trees) == 1 {
+		for r := range trees {
+			role = r
+		}
+	}
+	tree, ok := trees[role]
+	if !ok {
+		return fmt.Errorf("mission %s has a tree per role, pick one with --role: %s", name, strings.Join(sortedKeys(trees), ", "))
+	}
+
+	var rec *bt.Recorder
+	if btFlags.ship != "" {
+		var err error
+		if rec, err = lastTick(btFlags.ship, tree); err != nil {
+			return err
+		}
+	}
+
+	switch btFlags.format {
+	case "dot":
+		fmt.Print(bt.DOT(tree, rec))
+	case "mermaid":
+		fmt.Print(bt.Mermaid(tree, rec))
+	default:
+		return fmt.Errorf("unknown format %q, expected dot or mermaid", btFlags.format)
+	}
+	return nil
+}
+
+// lastTick reads ship's last stored tick trace back into a Recorder, once
+// it's checked the tick was of tree.
+func lastTick(ship string, tree bt.BehaviorNode[*actors.Blackboard]) (*bt.Recorder, error) {
+	r, err := repo.GetRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	trace, err := r.GetLastTickTrace(ship)
+	if err != nil {
+		return nil, err
+	}
+	if trace == nil || len(trace.Nodes) == 0 {
+		return nil, fmt.Errorf("no ticks recorded for %s", ship)
+	}
+	if root := trace.Nodes[0].Name; root != bt.Name(tree) {
+		return nil, fmt.Errorf("%s last ticked %s, rooted at %s, not this tree", ship, trace.Mission, root)
+	}
+
+	rec := bt.NewRecorder()
+	for _, n := range trace.Nodes {
+		status, err := bt.ParseStatus(n.Status)
+		if err != nil {
+			return nil, err
+		}
+		rec.Record(bt.Visit{Path: n.Path, Name: n.Name, Status: status, Duration: n.Duration})
+	}
+	return rec, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"log"
+	"log/slog"
+	"net/http"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/mockserver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var mockFlags struct {
+	addr    string
+	seed    int64
+	systems int
+	agent   string
+}
+
+func init() {
+	mockCmd.Flags().StringVar(&mockFlags.addr, "addr", "localhost:8080", "address to listen on")
+	mockCmd.Flags().Int64Var(&mockFlags.seed, "seed", mockserver.DefaultConfig.Seed, "seed for the generated universe")
+	mockCmd.Flags().IntVar(&mockFlags.systems, "systems", mockserver.DefaultConfig.Systems, "number of systems to generate")
+	mockCmd.Flags().StringVar(&mockFlags.agent, "agent", "MOCK", "agent registered with the ST_API_TOKEN token")
+	rootCmd.AddCommand(mockCmd)
+}
+
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "runs a local in-memory SpaceTraders server",
+	Long: `Runs a local in-memory SpaceTraders server. Point the bot at it with
+ST_BASE_URL=http://localhost:8080/v2, the agent given by --agent is registered
+with the token in ST_API_TOKEN.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		server := mockserver.New(mockserver.Config{
+			Seed:    mockFlags.seed,
+			Systems: mockFlags.systems,
+		})
+
+		token := viper.GetString("API_TOKEN")
+		if token == "" {
+			token = "mock"
+		}
+		agent, err := server.AddAgent(mockFlags.agent, api.FactionSymbolCOSMIC, token)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		handler, err := server.Handler("/v2")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		slog.Info("mock server listening", "url", "http://"+mockFlags.addr+"/v2", "agent", agent.Symbol, "headquarters", agent.Headquarters)
+		log.Fatal(http.ListenAndServe(mockFlags.addr, handler))
+	},
+}
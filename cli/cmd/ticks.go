@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/spf13/cobra"
+)
+
+var ticksFlags struct {
+	since       time.Duration
+	until       time.Duration
+	changesOnly bool
+}
+
+func init() {
+	ticksCmd.Flags().DurationVar(&ticksFlags.since, "since", time.Hour, "replay ticks started after this long ago")
+	ticksCmd.Flags().DurationVar(&ticksFlags.until, "until", 0, "replay ticks started before this long ago")
+	ticksCmd.Flags().BoolVar(&ticksFlags.changesOnly, "changes-only", false, "only replay ticks that changed the blackboard")
+	rootCmd.AddCommand(ticksCmd)
+}
+
+var ticksCmd = &cobra.Command{
+	Use:   "ticks <ship>",
+	Short: "replays a ship's recorded behavior tree ticks, the nodes visited and the blackboard changes",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := replayTicks(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func replayTicks(ship string) error {
+	r, err := repo.GetRepo()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	now := time.Now()
+	traces, err := r.GetTickTraces(ship, now.Add(-ticksFlags.since), now.Add(-ticksFlags.until))
+	if err != nil {
+		return err
+	}
+	if len(traces) == 0 {
+		fmt.Printf("no ticks recorded for %s in that range\n", ship)
+		return nil
+	}
+
+	for _, t := range traces {
+		if ticksFlags.changesOnly && len(t.Changes) == 0 {
+			continue
+		}
+
+		fmt.Printf("%s %s %s (%s)\n", t.StartedAt.Local().Format(time.DateTime), t.Mission, t.Status, t.Duration.Round(time.Millisecond))
+		for _, n := range t.Nodes {
+			fmt.Printf("%s%s: %s (%s)\n", strings.Repeat("  ", n.Depth()+1), n.Name, n.Status, n.Duration.Round(time.Millisecond))
+		}
+		for _, c := range t.Changes {
+			fmt.Printf("  %s: %q -> %q\n", c.Field, c.From, c.To)
+		}
+		fmt.Println()
+	}
+	return nil
+}
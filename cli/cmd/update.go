@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
@@ -47,24 +48,24 @@ var updateCmd = &cobra.Command{
 		case "all":
 			fallthrough
 		case "agents":
-			err = tasks.UpdateAgents(client, r)
+			err = tasks.UpdateAgents(context.Background(), client, r)
 		case "system":
-			err = tasks.ScanSystem(client, r, target)
+			err = tasks.ScanSystem(context.Background(), client, r, target)
 		case "markets":
-			err = tasks.ScanMarkets(client, r, target)
+			err = tasks.ScanMarkets(context.Background(), client, r, target)
 		case "market":
-			err = tasks.ScanMarket(client, r, target)
+			err = tasks.ScanMarket(context.Background(), client, r, target)
 		case "shipyard":
-			err = tasks.ScanShipyard(client, r, target)
+			err = tasks.ScanShipyard(context.Background(), client, r, target)
 		case "shipyards":
 			slog.Info("scan shipyards", "system", target)
-			err = tasks.ScanShipyards(client, r, target)
+			err = tasks.ScanShipyards(context.Background(), client, r, target)
 		case "systems":
-			err = tasks.UpdateSystems(client, r)
+			err = tasks.UpdateSystems(context.Background(), client, r)
 		case "waypoints":
-			err = tasks.ScanWaypoints(client, r, target)
+			err = tasks.ScanWaypoints(context.Background(), client, r, target)
 		case "fleet":
-			err = tasks.UpdateFleet(client, r)
+			err = tasks.UpdateFleet(context.Background(), client, r)
 		}
 
 		if err != nil {
@@ -84,7 +85,7 @@ func updateSystemData(system string) error {
 		return err
 	}
 
-	err = tasks.ScanSystem(client, r, system)
+	err = tasks.ScanSystem(context.Background(), client, r, system)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultProbeInterval    = 15 * time.Second
+	defaultMaxProbeInterval = 2 * time.Minute
+	probeTimeout            = 10 * time.Second
+)
+
+// Breaker stops traffic to the api while it's degraded. After Threshold
+// consecutive 5xx responses or failed connections the breaker opens: requests
+// block instead of being sent, and Probe is polled with a growing interval
+// until it succeeds and the breaker closes again.
+type Breaker struct {
+	Threshold        int
+	Probe            func(ctx context.Context) error
+	ProbeInterval    time.Duration
+	MaxProbeInterval time.Duration
+
+	mu       sync.Mutex
+	clock    clock.Clock
+	failures int
+	open     bool
+	// healthy is closed while the breaker is, waiters block on it otherwise
+	healthy chan struct{}
+}
+
+// NewBreaker builds a breaker that waits out its probe intervals on clk.
+func NewBreaker(clk clock.Clock, probe func(ctx context.Context) error) *Breaker {
+	b := &Breaker{
+		clock:            clock.Or(clk),
+		Threshold:        defaultBreakerThreshold,
+		Probe:            probe,
+		ProbeInterval:    defaultProbeInterval,
+		MaxProbeInterval: defaultMaxProbeInterval,
+		healthy:          make(chan struct{}),
+	}
+	close(b.healthy)
+	return b
+}
+
+var (
+	apiBreaker     *Breaker
+	apiBreakerOnce sync.Once
+)
+
+// APIBreaker returns the breaker shared by every client in the process, when
+// the api is down it's down for every agent. It probes with GetStatus.
+func APIBreaker() *Breaker {
+	apiBreakerOnce.Do(func() {
+		apiBreaker = NewBreaker(clock.Real(), statusProbe)
+	})
+	return apiBreaker
+}
+
+// statusProbe calls GetStatus around the breaker and the rate limiter.
+func statusProbe(ctx context.Context) error {
+	viper.SetDefault("BASE_URL", "https://api.spacetraders.io/v2")
+	c, err := api.NewClient(viper.GetString("BASE_URL"), TokenProvider{}, api.WithClient(&http.Client{
+		Transport: &ErrorTransport{Base: http.DefaultTransport},
+	}))
+	if err != nil {
+		return err
+	}
+	_, err = c.GetStatus(ctx)
+	return err
+}
+
+// SetClock waits out later probe intervals on clk, e.g. the kernel's fake
+// clock in a simulation.
+func (b *Breaker) SetClock(clk clock.Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = clock.Or(clk)
+}
+
+// Open reports whether traffic is currently paused.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// Wait blocks while the breaker is open. Ship actors and tasks call it before
+// doing any work, so everything pauses together and resumes on recovery.
+func (b *Breaker) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	healthy := b.healthy
+	b.mu.Unlock()
+
+	select {
+	case <-healthy:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+}
+
+func (b *Breaker) failure(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.open || b.failures < b.Threshold {
+		return
+	}
+
+	b.open = true
+	b.healthy = make(chan struct{})
+	slog.Warn("api degraded, pausing until it recovers", "failures", b.failures, "last", reason)
+	go b.probe()
+}
+
+// probe polls Probe, backing off, until the api answers and then closes the
+// breaker.
+func (b *Breaker) probe() {
+	interval := b.ProbeInterval
+	for {
+		b.mu.Lock()
+		clk := b.clock
+		b.mu.Unlock()
+		clk.Sleep(interval)
+
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		err := b.Probe(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+
+		slog.Warn("api still degraded", "err", err, "next", interval)
+		interval = min(interval*2, b.MaxProbeInterval)
+	}
+
+	b.mu.Lock()
+	b.open = false
+	b.failures = 0
+	close(b.healthy)
+	b.mu.Unlock()
+
+	slog.Info("api recovered, resuming")
+}
+
+// BreakerTransport holds requests while its breaker is open and reports every
+// response to it.
+type BreakerTransport struct {
+	Base    http.RoundTripper
+	Breaker *Breaker
+}
+
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.Breaker.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	res, err := t.Base.RoundTrip(req)
+	switch {
+	case err != nil:
+		// the caller giving up says nothing about the api
+		if req.Context().Err() == nil {
+			t.Breaker.failure(err.Error())
+		}
+	case res.StatusCode >= http.StatusInternalServerError:
+		t.Breaker.failure(res.Status)
+	default:
+		t.Breaker.success()
+	}
+	return res, err
+}
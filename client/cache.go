@@ -0,0 +1,264 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/bwiggs/spacetraders-go/repo"
+)
+
+// DefaultCacheTTLs are how long CachingInvoker keeps each endpoint's
+// responses. Systems, waypoints, jump gates and factions only change with a
+// server reset. Shipyards and markets are cached per agent, since what they
+// show depends on whether one of the agent's ships is there, and markets only
+// briefly as their prices move with every trade.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"GetSystem":          24 * time.Hour,
+	"GetSystems":         24 * time.Hour,
+	"GetSystemWaypoints": 24 * time.Hour,
+	"GetWaypoint":        24 * time.Hour,
+	"GetJumpGate":        24 * time.Hour,
+	"GetFactions":        time.Hour,
+	"GetFaction":         time.Hour,
+	"GetShipyard":        5 * time.Minute,
+	"GetMarket":          30 * time.Second,
+}
+
+// CachingInvoker serves the static game data endpoints from memory, and
+// waypoints from the repo, instead of spending rate limit on them. Cached
+// responses are shared between callers, who must treat them as read-only.
+//
+// A market's cached response is dropped when one of the agent's ships trades
+// there. Every other operation is passed through untouched.
+type CachingInvoker struct {
+	api.Invoker
+
+	// TTLs overrides DefaultCacheTTLs per operation, a zero TTL disables
+	// caching for it.
+	TTLs map[string]time.Duration
+
+	scope string
+	repo  *repo.Repo
+	cache *ResponseCache
+}
+
+// NewCachingInvoker caches next's responses in the process wide
+// StaticCache. Agent specific responses are keyed by scope, usually the agent
+// symbol. r may be nil to only cache in memory.
+func NewCachingInvoker(next api.Invoker, scope string, r *repo.Repo) *CachingInvoker {
+	return &CachingInvoker{
+		Invoker: next,
+		TTLs:    DefaultCacheTTLs,
+		scope:   scope,
+		repo:    r,
+		cache:   StaticCache(),
+	}
+}
+
+// ResponseCache holds api responses until they expire or the cache is
+// flushed.
+type ResponseCache struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+func NewResponseCache(clk clock.Clock) *ResponseCache {
+	return &ResponseCache{clock: clk, entries: map[string]cacheEntry{}}
+}
+
+var (
+	staticCache     *ResponseCache
+	staticCacheOnce sync.Once
+)
+
+// StaticCache returns the cache shared by every client in the process, so
+// agents don't each fetch the same systems and waypoints.
+func StaticCache() *ResponseCache {
+	staticCacheOnce.Do(func() {
+		staticCache = NewResponseCache(clock.Real())
+	})
+	return staticCache
+}
+
+func (c *ResponseCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock.Now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *ResponseCache) set(key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: value, expires: c.clock.Now().Add(ttl)}
+}
+
+func (c *ResponseCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Flush drops every cached response, the reset watcher calls it when the
+// universe is regenerated.
+func (c *ResponseCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// cached returns the cached response for op and params, calling fetch on a
+// miss. Errors are never cached.
+func cached[T any](c *CachingInvoker, op string, scoped bool, params any, fetch func() (T, error)) (T, error) {
+	ttl := c.TTLs[op]
+	if ttl <= 0 {
+		return fetch()
+	}
+
+	key := c.key(op, scoped, params)
+	if v, ok := c.cache.get(key); ok {
+		return v.(T), nil
+	}
+
+	res, err := fetch()
+	if err != nil {
+		return res, err
+	}
+	c.cache.set(key, res, ttl)
+	return res, nil
+}
+
+func (c *CachingInvoker) key(op string, scoped bool, params any) string {
+	if scoped {
+		return fmt.Sprintf("%s/%s:%+v", c.scope, op, params)
+	}
+	return fmt.Sprintf("%s:%+v", op, params)
+}
+
+func (c *CachingInvoker) GetSystem(ctx context.Context, params api.GetSystemParams) (*api.GetSystemOK, error) {
+	return cached(c, "GetSystem", false, params, func() (*api.GetSystemOK, error) {
+		return c.Invoker.GetSystem(ctx, params)
+	})
+}
+
+func (c *CachingInvoker) GetSystems(ctx context.Context, params api.GetSystemsParams) (*api.GetSystemsOK, error) {
+	return cached(c, "GetSystems", false, params, func() (*api.GetSystemsOK, error) {
+		return c.Invoker.GetSystems(ctx, params)
+	})
+}
+
+// GetSystemWaypoints also stores the waypoints in the repo, where GetWaypoint
+// can find them after a restart.
+func (c *CachingInvoker) GetSystemWaypoints(ctx context.Context, params api.GetSystemWaypointsParams) (*api.GetSystemWaypointsOK, error) {
+	return cached(c, "GetSystemWaypoints", false, params, func() (*api.GetSystemWaypointsOK, error) {
+		res, err := c.Invoker.GetSystemWaypoints(ctx, params)
+		if err == nil && c.repo != nil {
+			if err := c.repo.WithContext(ctx).UpsertWaypoints(res.Data); err != nil {
+				return nil, err
+			}
+		}
+		return res, err
+	})
+}
+
+// GetWaypoint reads through the repo before asking the api.
+func (c *CachingInvoker) GetWaypoint(ctx context.Context, params api.GetWaypointParams) (*api.GetWaypointOK, error) {
+	return cached(c, "GetWaypoint", false, params, func() (*api.GetWaypointOK, error) {
+		if c.repo != nil {
+			wp, err := c.repo.WithContext(ctx).GetWaypoint(params.WaypointSymbol)
+			if err != nil {
+				return nil, err
+			}
+			if wp != nil {
+				return &api.GetWaypointOK{Data: *wp}, nil
+			}
+		}
+
+		res, err := c.Invoker.GetWaypoint(ctx, params)
+		if err == nil && c.repo != nil {
+			if err := c.repo.WithContext(ctx).UpsertWaypoints([]api.Waypoint{res.Data}); err != nil {
+				return nil, err
+			}
+		}
+		return res, err
+	})
+}
+
+func (c *CachingInvoker) GetJumpGate(ctx context.Context, params api.GetJumpGateParams) (*api.GetJumpGateOK, error) {
+	return cached(c, "GetJumpGate", false, params, func() (*api.GetJumpGateOK, error) {
+		return c.Invoker.GetJumpGate(ctx, params)
+	})
+}
+
+func (c *CachingInvoker) GetFactions(ctx context.Context, params api.GetFactionsParams) (*api.GetFactionsOK, error) {
+	return cached(c, "GetFactions", false, params, func() (*api.GetFactionsOK, error) {
+		return c.Invoker.GetFactions(ctx, params)
+	})
+}
+
+func (c *CachingInvoker) GetFaction(ctx context.Context, params api.GetFactionParams) (*api.GetFactionOK, error) {
+	return cached(c, "GetFaction", false, params, func() (*api.GetFactionOK, error) {
+		return c.Invoker.GetFaction(ctx, params)
+	})
+}
+
+func (c *CachingInvoker) GetShipyard(ctx context.Context, params api.GetShipyardParams) (*api.GetShipyardOK, error) {
+	return cached(c, "GetShipyard", true, params, func() (*api.GetShipyardOK, error) {
+		return c.Invoker.GetShipyard(ctx, params)
+	})
+}
+
+func (c *CachingInvoker) GetMarket(ctx context.Context, params api.GetMarketParams) (*api.GetMarketOK, error) {
+	return cached(c, "GetMarket", true, params.WaypointSymbol, func() (*api.GetMarketOK, error) {
+		return c.Invoker.GetMarket(ctx, params)
+	})
+}
+
+// forgetMarket drops the cached market at waypoint after a trade moved its
+// prices.
+func (c *CachingInvoker) forgetMarket(waypoint api.WaypointSymbol) {
+	c.cache.delete(c.key("GetMarket", true, string(waypoint)))
+}
+
+func (c *CachingInvoker) PurchaseCargo(ctx context.Context, request api.OptPurchaseCargoReq, params api.PurchaseCargoParams) (*api.PurchaseCargoCreated, error) {
+	res, err := c.Invoker.PurchaseCargo(ctx, request, params)
+	if err == nil {
+		c.forgetMarket(res.Data.Transaction.WaypointSymbol)
+	}
+	return res, err
+}
+
+func (c *CachingInvoker) SellCargo(ctx context.Context, request api.OptSellCargoReq, params api.SellCargoParams) (*api.SellCargoCreated, error) {
+	res, err := c.Invoker.SellCargo(ctx, request, params)
+	if err == nil {
+		c.forgetMarket(res.Data.Transaction.WaypointSymbol)
+	}
+	return res, err
+}
+
+func (c *CachingInvoker) RefuelShip(ctx context.Context, request api.OptRefuelShipReq, params api.RefuelShipParams) (*api.RefuelShipOK, error) {
+	res, err := c.Invoker.RefuelShip(ctx, request, params)
+	if err == nil {
+		c.forgetMarket(res.Data.Transaction.WaypointSymbol)
+	}
+	return res, err
+}
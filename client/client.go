@@ -2,60 +2,188 @@ package client
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/spf13/viper"
-	"golang.org/x/time/rate"
 )
 
-var theClient *Client
+var theInvoker api.Invoker
 
-type TokenProvider struct{}
+// TokenProvider supplies the bearer tokens for one agent. Empty tokens fall
+// back to ST_API_TOKEN, so the zero value is the process-wide agent. The
+// account token, needed to register agents, can also come from
+// ST_ACCOUNT_TOKEN.
+type TokenProvider struct {
+	Agent   string
+	Account string
+}
 
 func (tp TokenProvider) AccountToken(ctx context.Context, operationName api.OperationName) (api.AccountToken, error) {
+	if tp.Account != "" {
+		return api.AccountToken{Token: tp.Account}, nil
+	}
+	if token := viper.GetString("ACCOUNT_TOKEN"); token != "" {
+		return api.AccountToken{Token: token}, nil
+	}
 	return api.AccountToken{Token: viper.GetString("API_TOKEN")}, nil
 }
 
 func (tp TokenProvider) AgentToken(ctx context.Context, operationName api.OperationName) (api.AgentToken, error) {
+	if tp.Agent != "" {
+		return api.AgentToken{Token: tp.Agent}, nil
+	}
 	return api.AgentToken{Token: viper.GetString("API_TOKEN")}, nil
 }
 
 type Client struct {
 	*api.Client
-	rateLimiter *rate.Limiter
+	rateLimiter *RateLimitedTransport
+	recorder    *RecordingTransport
+	httpClient  *http.Client
 }
 
+// GetClient returns the process-wide client for the ST_API_TOKEN agent.
 func GetClient() (api.Invoker, error) {
-	if theClient == nil {
-
-		// limit to 2 requests per second with a burst of 1
-		// NOTE: use 2 requests per second ran a little too close to the sun and ended up returning some 429s.
-		rateLimiter := rate.NewLimiter(rate.Every(600*time.Millisecond), 1)
-
-		// use a rate-limited transport
-		opt := api.WithClient(&http.Client{
-			Timeout: 3 * time.Second,
-			Transport: &RateLimitedTransport{
-				Base:    http.DefaultTransport,
-				Limiter: rateLimiter,
-			},
-		})
-
-		viper.SetDefault("BASE_URL", "https://api.spacetraders.io/v2")
-		apiClient, err := api.NewClient(viper.GetString("BASE_URL"), TokenProvider{}, opt)
+	if theInvoker == nil {
+		invoker, err := NewClient("", TokenProvider{})
 		if err != nil {
 			return nil, err
 		}
-		theClient = &Client{Client: apiClient, rateLimiter: rateLimiter}
+		theInvoker = invoker
 	}
 
-	return theClient, nil
+	return theInvoker, nil
 }
 
+// NewClient builds a client acting for a single agent, with its own rate
+// limiter. When symbol is set the agent's ST_RECORD and ST_REPLAY sessions
+// live in their own files, e.g. session.AGENT.jsonl.
+func NewClient(symbol string, tokens TokenProvider) (api.Invoker, error) {
+	// pace requests against the server's static and burst pools, the
+	// limiter adapts to the x-ratelimit-* headers and backs off on 429s.
+	rateLimiter := NewRateLimitedTransport(http.DefaultTransport)
+	rateLimiter.Timeout = 3 * time.Second
+
+	// split the budget between priorities when they compete for it
+	viper.SetDefault("RATE_SHARE_SHIP_ACTION", DefaultShares[PriorityShipAction])
+	viper.SetDefault("RATE_SHARE_MISSION_QUERY", DefaultShares[PriorityMissionQuery])
+	viper.SetDefault("RATE_SHARE_BACKGROUND", DefaultShares[PriorityBackground])
+	rateLimiter.SetShare(PriorityShipAction, viper.GetFloat64("RATE_SHARE_SHIP_ACTION"))
+	rateLimiter.SetShare(PriorityMissionQuery, viper.GetFloat64("RATE_SHARE_MISSION_QUERY"))
+	rateLimiter.SetShare(PriorityBackground, viper.GetFloat64("RATE_SHARE_BACKGROUND"))
+	var transport http.RoundTripper = rateLimiter
+
+	// ST_RECORD captures every exchange with the server to a JSONL file. It
+	// sits above the limiter, so the 429s it retries aren't recorded and
+	// later replayed as responses.
+	var recorder *RecordingTransport
+	if path := viper.GetString("RECORD"); path != "" {
+		var err error
+		recorder, err = NewRecordingTransport(transport, sessionPath(path, symbol))
+		if err != nil {
+			return nil, err
+		}
+		transport = recorder
+	}
+
+	// ST_REPLAY serves a recorded session instead of the live server,
+	// there's no budget to pace against offline.
+	if path := viper.GetString("REPLAY"); path != "" {
+		replay, err := NewReplayTransport(sessionPath(path, symbol))
+		if err != nil {
+			return nil, err
+		}
+		transport = replay
+	}
+
+	// hold every request while the api is degraded
+	transport = &BreakerTransport{Base: transport, Breaker: APIBreaker()}
+
+	// use a rate-limited transport, decoding error bodies into typed errors
+	httpClient := &http.Client{
+		Transport: &ErrorTransport{Base: transport},
+	}
+
+	viper.SetDefault("BASE_URL", "https://api.spacetraders.io/v2")
+	apiClient, err := api.NewClient(viper.GetString("BASE_URL"), tokens, api.WithClient(httpClient))
+	if err != nil {
+		return nil, err
+	}
+
+	// retry transient failures, checking ship state before resending mutations
+	return NewRetryInvoker(&Client{Client: apiClient, rateLimiter: rateLimiter, recorder: recorder, httpClient: httpClient}), nil
+}
+
+// WithTokens returns a client acting with tokens that shares invoker's
+// transport, so its requests draw on the same rate limit budget and land in
+// the same ST_RECORD session. invoker must be a client from NewClient. The
+// session stays invoker's, closing the returned client does nothing.
+func WithTokens(invoker api.Invoker, tokens TokenProvider) (api.Invoker, error) {
+	if r, ok := invoker.(*RetryInvoker); ok {
+		invoker = r.Invoker
+	}
+	c, ok := invoker.(*Client)
+	if !ok {
+		return nil, errors.New("client: WithTokens needs a client from NewClient")
+	}
+
+	apiClient, err := api.NewClient(viper.GetString("BASE_URL"), tokens, api.WithClient(c.httpClient))
+	if err != nil {
+		return nil, err
+	}
+	return NewRetryInvoker(&Client{Client: apiClient, rateLimiter: c.rateLimiter, httpClient: c.httpClient}), nil
+}
+
+// sessionPath puts symbol before the extension of a session file.
+func sessionPath(path, symbol string) string {
+	if symbol == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + symbol + ext
+}
+
+// GetRateLimitPressure returns the share of the request budget currently in
+// use, from 0 (idle) to 1 (exhausted or backing off after a 429).
 func (c *Client) GetRateLimitPressure() float64 {
-	return c.rateLimiter.Tokens()
+	return c.rateLimiter.Pressure()
+}
+
+// RateLimitPressure returns invoker's rate limit pressure when it's a client
+// from NewClient, and 0 for anything else, e.g. a mock.
+func RateLimitPressure(invoker api.Invoker) float64 {
+	if r, ok := invoker.(*RetryInvoker); ok {
+		invoker = r.Invoker
+	}
+	if c, ok := invoker.(*Client); ok {
+		return c.GetRateLimitPressure()
+	}
+	return 0
+}
+
+// Close flushes and closes the client's ST_RECORD session, if it has one.
+func (c *Client) Close() error {
+	if c.recorder == nil {
+		return nil
+	}
+	return c.recorder.Close()
+}
+
+// Close closes invoker when it's a client from NewClient, and does nothing
+// for anything else.
+func Close(invoker api.Invoker) error {
+	if r, ok := invoker.(*RetryInvoker); ok {
+		invoker = r.Invoker
+	}
+	if c, ok := invoker.(*Client); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // NavigateShip invokes navigate-ship operation.
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+// SpaceTraders error codes, see
+// https://github.com/SpaceTradersAPI/api-docs/blob/main/models/ErrorCodes.json
+const (
+	CodeCooldownConflict          = 4000
+	CodeWaypointNoAccess          = 4001
+	CodeNavigateInTransit         = 4200
+	CodeNavigateInvalidDest       = 4201
+	CodeNavigateOutsideSystem     = 4202
+	CodeNavigateInsufficientFuel  = 4203
+	CodeNavigateSameDestination   = 4204
+	CodeShipInTransit             = 4214
+	CodeShipCargoExceedsLimit     = 4217
+	CodeShipCargoMissing          = 4218
+	CodeShipCargoUnitCount        = 4219
+	CodeShipSurveyVerification    = 4220
+	CodeShipSurveyExpiration      = 4221
+	CodeShipSurveyWaypointType    = 4222
+	CodeShipSurveyOrbit           = 4223
+	CodeShipSurveyExhausted       = 4224
+	CodeShipNotInOrbit            = 4236
+	CodeShipNotDocked             = 4244
+	CodeContractDeadline          = 4503
+	CodeContractFulfilled         = 4504
+	CodeContractNotAccepted       = 4505
+	CodeMarketInsufficientCredits = 4600
+	CodeMarketNoPurchase          = 4601
+	CodeMarketNotSold             = 4602
+	CodeMarketNotFound            = 4603
+	CodeMarketTradeUnitLimit      = 4604
+)
+
+// APIError is the error body the SpaceTraders API returns with every non 2xx
+// response. The typed errors below embed it, so errors.As can match either the
+// specific failure or any API error.
+type APIError struct {
+	StatusCode int
+	Code       int             `json:"code"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("spacetraders: %d: %s", e.Code, e.Message)
+}
+
+// Temporary reports whether the request may succeed if sent again unchanged.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// CooldownError is returned when a ship action is attempted during cooldown.
+type CooldownError struct {
+	*APIError
+	Cooldown api.Cooldown
+}
+
+func (e *CooldownError) Unwrap() error { return e.APIError }
+
+// InsufficientFuelError is returned when a ship can't reach its destination
+// with the fuel it is carrying.
+type InsufficientFuelError struct {
+	*APIError
+	ShipSymbol    string `json:"shipSymbol"`
+	FuelRequired  int    `json:"fuelRequired"`
+	FuelAvailable int    `json:"fuelAvailable"`
+}
+
+func (e *InsufficientFuelError) Unwrap() error { return e.APIError }
+
+// ShipInTransitError is returned when a ship action requires the ship to have
+// arrived.
+type ShipInTransitError struct {
+	*APIError
+	DepartureSymbol   string    `json:"departureSymbol"`
+	DestinationSymbol string    `json:"destinationSymbol"`
+	Arrival           time.Time `json:"arrival"`
+	SecondsToArrival  int       `json:"secondsToArrival"`
+}
+
+func (e *ShipInTransitError) Unwrap() error { return e.APIError }
+
+// ShipNavStatusError is returned when a ship is docked and needs to be in
+// orbit, or the other way around.
+type ShipNavStatusError struct {
+	*APIError
+}
+
+func (e *ShipNavStatusError) Unwrap() error { return e.APIError }
+
+// SurveyError is returned when a survey can't be used for extraction because
+// it is exhausted, expired or doesn't belong to the ships waypoint.
+type SurveyError struct {
+	*APIError
+}
+
+func (e *SurveyError) Unwrap() error { return e.APIError }
+
+// Exhausted reports whether the survey has been used up.
+func (e *SurveyError) Exhausted() bool {
+	return e.Code == CodeShipSurveyExhausted
+}
+
+// CargoError is returned when cargo doesn't fit in the hold or the ship isn't
+// carrying the requested goods.
+type CargoError struct {
+	*APIError
+}
+
+func (e *CargoError) Unwrap() error { return e.APIError }
+
+// MarketVolumeExceededError is returned when a trade is larger than the
+// markets trade volume.
+type MarketVolumeExceededError struct {
+	*APIError
+	WaypointSymbol string `json:"waypointSymbol"`
+	TradeSymbol    string `json:"tradeSymbol"`
+	Units          int    `json:"units"`
+	TradeVolume    int    `json:"tradeVolume"`
+}
+
+func (e *MarketVolumeExceededError) Unwrap() error { return e.APIError }
+
+// MarketTradeError is returned when the market doesn't buy or sell the good.
+type MarketTradeError struct {
+	*APIError
+}
+
+func (e *MarketTradeError) Unwrap() error { return e.APIError }
+
+// InsufficientCreditsError is returned when the agent can't afford a purchase.
+type InsufficientCreditsError struct {
+	*APIError
+	CreditsAvailable int `json:"creditsAvailable"`
+	TotalPrice       int `json:"totalPrice"`
+}
+
+func (e *InsufficientCreditsError) Unwrap() error { return e.APIError }
+
+// ContractError is returned when a contract can no longer be acted on.
+type ContractError struct {
+	*APIError
+}
+
+func (e *ContractError) Unwrap() error { return e.APIError }
+
+// RateLimitError is returned once a 429 outlasts the transports retries.
+type RateLimitError struct {
+	*APIError
+	RetryAfter float64 `json:"retryAfter"`
+}
+
+func (e *RateLimitError) Unwrap() error { return e.APIError }
+
+// decodeError reads an error response body into the most specific error type
+// for its code. Unknown codes are returned as a plain *APIError.
+func decodeError(res *http.Response) error {
+	buf, err := io.ReadAll(res.Body)
+	if err != nil {
+		return &APIError{StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+	}
+
+	var body struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal(buf, &body); err != nil || body.Error == nil {
+		return &APIError{StatusCode: res.StatusCode, Code: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+	}
+
+	e := body.Error
+	e.StatusCode = res.StatusCode
+
+	return typedError(e)
+}
+
+func typedError(e *APIError) error {
+	var typed error
+	switch e.Code {
+	case CodeCooldownConflict:
+		ce := &CooldownError{APIError: e}
+		var data struct {
+			Cooldown json.RawMessage `json:"cooldown"`
+		}
+		if json.Unmarshal(e.Data, &data) == nil && data.Cooldown != nil {
+			ce.Cooldown.UnmarshalJSON(data.Cooldown)
+		}
+		return ce
+	case CodeNavigateInsufficientFuel:
+		typed = &InsufficientFuelError{APIError: e}
+	case CodeNavigateInTransit, CodeShipInTransit:
+		typed = &ShipInTransitError{APIError: e}
+	case CodeShipNotInOrbit, CodeShipNotDocked:
+		return &ShipNavStatusError{APIError: e}
+	case CodeShipSurveyVerification, CodeShipSurveyExpiration, CodeShipSurveyWaypointType, CodeShipSurveyOrbit, CodeShipSurveyExhausted:
+		return &SurveyError{APIError: e}
+	case CodeShipCargoExceedsLimit, CodeShipCargoMissing, CodeShipCargoUnitCount:
+		return &CargoError{APIError: e}
+	case CodeMarketTradeUnitLimit:
+		typed = &MarketVolumeExceededError{APIError: e}
+	case CodeMarketNoPurchase, CodeMarketNotSold, CodeMarketNotFound:
+		return &MarketTradeError{APIError: e}
+	case CodeMarketInsufficientCredits:
+		typed = &InsufficientCreditsError{APIError: e}
+	case CodeContractDeadline, CodeContractFulfilled, CodeContractNotAccepted:
+		return &ContractError{APIError: e}
+	case http.StatusTooManyRequests:
+		typed = &RateLimitError{APIError: e}
+	default:
+		return e
+	}
+
+	// the remaining types are flat decodes of the data field
+	if len(e.Data) > 0 {
+		json.Unmarshal(e.Data, typed)
+	}
+	return typed
+}
+
+// ErrorTransport turns non 2xx responses into typed errors. ogen only reports
+// the status code of an unexpected response and drops the body, so the body is
+// decoded here before it is lost.
+type ErrorTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *ErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < http.StatusBadRequest {
+		return res, nil
+	}
+
+	defer res.Body.Close()
+	return nil, decodeError(res)
+}
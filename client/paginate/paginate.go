@@ -0,0 +1,251 @@
+// Package paginate iterates over the server's paged list operations.
+package paginate
+
+import (
+	"context"
+	"iter"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+// MaxPageLimit is the most items the server returns per page.
+const MaxPageLimit = 20
+
+// PageFunc fetches one page of a list operation.
+type PageFunc[T any] func(ctx context.Context, page, limit int) ([]T, api.Meta, error)
+
+// Page is one page of results, Meta.Page is where to resume from.
+type Page[T any] struct {
+	Data []T
+	Meta api.Meta
+}
+
+type pageConfig struct {
+	start    int
+	limit    int
+	parallel int
+}
+
+type PageOption func(*pageConfig)
+
+// StartPage resumes from page n instead of the first page.
+func StartPage(n int) PageOption {
+	return func(c *pageConfig) {
+		c.start = max(1, n)
+	}
+}
+
+// PageLimit sets the page size, capped at MaxPageLimit.
+func PageLimit(n int) PageOption {
+	return func(c *pageConfig) {
+		c.limit = min(max(1, n), MaxPageLimit)
+	}
+}
+
+// Parallel fetches up to n pages at once once the first page has told us how
+// many there are. Pages are still yielded in order, and every request still
+// waits its turn with the rate limiter.
+func Parallel(n int) PageOption {
+	return func(c *pageConfig) {
+		c.parallel = max(1, n)
+	}
+}
+
+// Pages iterates over the pages of a list operation. It stops after the last
+// page by the server's total, on an empty page, on the first error (yielded
+// with an empty page) or when ctx is done.
+func Pages[T any](ctx context.Context, fetch PageFunc[T], opts ...PageOption) iter.Seq2[Page[T], error] {
+	cfg := pageConfig{start: 1, limit: MaxPageLimit, parallel: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(yield func(Page[T], error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		first, err := fetchPage(ctx, fetch, cfg.start, cfg.limit)
+		if err != nil {
+			yield(Page[T]{}, err)
+			return
+		}
+		if len(first.Data) == 0 || !yield(first, nil) {
+			return
+		}
+
+		last := lastPage(first.Meta.Total, cfg.limit)
+		if cfg.parallel > 1 {
+			parallelPages(ctx, fetch, cfg, cfg.start+1, last, yield)
+			return
+		}
+
+		for page := cfg.start + 1; page <= last; page++ {
+			p, err := fetchPage(ctx, fetch, page, cfg.limit)
+			if err != nil {
+				yield(Page[T]{}, err)
+				return
+			}
+			if len(p.Data) == 0 || !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+
+// All iterates over every item of a list operation, see Pages.
+func All[T any](ctx context.Context, fetch PageFunc[T], opts ...PageOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range Pages(ctx, fetch, opts...) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Data {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Collect gathers every item of a list operation.
+func Collect[T any](ctx context.Context, fetch PageFunc[T], opts ...PageOption) ([]T, error) {
+	items := []T{}
+	for page, err := range Pages(ctx, fetch, opts...) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Data...)
+	}
+	return items, nil
+}
+
+func fetchPage[T any](ctx context.Context, fetch PageFunc[T], page, limit int) (Page[T], error) {
+	if err := ctx.Err(); err != nil {
+		return Page[T]{}, err
+	}
+	data, meta, err := fetch(ctx, page, limit)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{Data: data, Meta: meta}, nil
+}
+
+// LastPage returns the number of the last page described by meta.
+func LastPage(meta api.Meta) int {
+	return lastPage(meta.Total, meta.Limit)
+}
+
+func lastPage(total, limit int) int {
+	return (total + max(1, limit) - 1) / max(1, limit)
+}
+
+type pageResult[T any] struct {
+	page Page[T]
+	err  error
+}
+
+// parallelPages fetches pages from..to with at most cfg.parallel requests in
+// flight or waiting to be yielded, yielding them in order.
+func parallelPages[T any](ctx context.Context, fetch PageFunc[T], cfg pageConfig, from, to int, yield func(Page[T], error) bool) {
+	if from > to {
+		return
+	}
+
+	results := make([]chan pageResult[T], to-from+1)
+	for i := range results {
+		results[i] = make(chan pageResult[T], 1)
+	}
+
+	// a slot is taken to fetch a page and given back once it's been yielded
+	slots := make(chan struct{}, cfg.parallel)
+	go func() {
+		for i := range results {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int) {
+				p, err := fetchPage(ctx, fetch, from+i, cfg.limit)
+				results[i] <- pageResult[T]{page: p, err: err}
+			}(i)
+		}
+	}()
+
+	for i := range results {
+		var r pageResult[T]
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			yield(Page[T]{}, ctx.Err())
+			return
+		}
+		<-slots
+
+		if r.err != nil {
+			yield(Page[T]{}, r.err)
+			return
+		}
+		if len(r.page.Data) == 0 || !yield(r.page, nil) {
+			return
+		}
+	}
+}
+
+// MyShips lists the agent's ships.
+func MyShips(c api.Invoker) PageFunc[api.Ship] {
+	return func(ctx context.Context, page, limit int) ([]api.Ship, api.Meta, error) {
+		res, err := c.GetMyShips(ctx, api.GetMyShipsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+		if err != nil {
+			return nil, api.Meta{}, err
+		}
+		return res.Data, res.Meta, nil
+	}
+}
+
+// Agents lists every agent on the server.
+func Agents(c api.Invoker) PageFunc[api.Agent] {
+	return func(ctx context.Context, page, limit int) ([]api.Agent, api.Meta, error) {
+		res, err := c.GetAgents(ctx, api.GetAgentsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+		if err != nil {
+			return nil, api.Meta{}, err
+		}
+		return res.Data, res.Meta, nil
+	}
+}
+
+// Contracts lists the agent's contracts, oldest first.
+func Contracts(c api.Invoker) PageFunc[api.Contract] {
+	return func(ctx context.Context, page, limit int) ([]api.Contract, api.Meta, error) {
+		res, err := c.GetContracts(ctx, api.GetContractsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+		if err != nil {
+			return nil, api.Meta{}, err
+		}
+		return res.Data, res.Meta, nil
+	}
+}
+
+// Systems lists every system in the universe.
+func Systems(c api.Invoker) PageFunc[api.System] {
+	return func(ctx context.Context, page, limit int) ([]api.System, api.Meta, error) {
+		res, err := c.GetSystems(ctx, api.GetSystemsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+		if err != nil {
+			return nil, api.Meta{}, err
+		}
+		return res.Data, res.Meta, nil
+	}
+}
+
+// SystemWaypoints lists the waypoints in a system.
+func SystemWaypoints(c api.Invoker, system string) PageFunc[api.Waypoint] {
+	return func(ctx context.Context, page, limit int) ([]api.Waypoint, api.Meta, error) {
+		res, err := c.GetSystemWaypoints(ctx, api.GetSystemWaypointsParams{SystemSymbol: system, Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+		if err != nil {
+			return nil, api.Meta{}, err
+		}
+		return res.Data, res.Meta, nil
+	}
+}
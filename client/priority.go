@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Priority orders requests competing for the rate limit budget.
+type Priority int
+
+const (
+	// PriorityShipAction is for ship commands: navigating, docking, trading,
+	// extracting. A ship is idle until these go through.
+	PriorityShipAction Priority = iota
+	// PriorityMissionQuery is for reads a mission needs to make its next
+	// decision. Requests without a priority are treated as mission queries.
+	PriorityMissionQuery
+	// PriorityBackground is for scheduled crawls of systems, markets and
+	// shipyards that can wait.
+	PriorityBackground
+
+	numPriorities
+)
+
+// DefaultShares splits the budget between priorities when they compete. A
+// class on its own may use the whole budget.
+var DefaultShares = [numPriorities]float64{
+	PriorityShipAction:   0.6,
+	PriorityMissionQuery: 0.3,
+	PriorityBackground:   0.1,
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityShipAction:
+		return "ship-action"
+	case PriorityMissionQuery:
+		return "mission-query"
+	case PriorityBackground:
+		return "background"
+	}
+	return "unknown"
+}
+
+type priorityKey struct{}
+
+// WithPriority tags requests made with ctx with the given priority.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority ctx was tagged with, or
+// PriorityMissionQuery.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= 0 && p < numPriorities {
+		return p
+	}
+	return PriorityMissionQuery
+}
+
+type waiter struct {
+	ready chan struct{}
+}
+
+// SetShare sets the share of the rate budget the priority gets while other
+// priorities are waiting too.
+func (r *RateLimitedTransport) SetShare(p Priority, share float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shares[p] = share
+}
+
+// QueueLen returns how many requests of the priority are waiting on budget.
+func (r *RateLimitedTransport) QueueLen(p Priority) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queues[p])
+}
+
+// dispatch hands out slots to queued requests for as long as there is budget,
+// then arms a timer for when the next slot frees up. Must hold r.mu.
+func (r *RateLimitedTransport) dispatch() {
+	for {
+		p, ok := r.nextPriority()
+		if !ok {
+			// nothing waiting, start the next contention with a clean slate
+			r.served = [numPriorities]int{}
+			return
+		}
+
+		d, ok := r.take(time.Now())
+		if !ok {
+			r.schedule(d)
+			return
+		}
+
+		w := r.queues[p][0]
+		r.queues[p] = r.queues[p][1:]
+		r.served[p]++
+		close(w.ready)
+	}
+}
+
+// nextPriority picks the waiting priority that is furthest behind its share.
+func (r *RateLimitedTransport) nextPriority() (Priority, bool) {
+	best := Priority(-1)
+	bestScore := 0.0
+	for p := range numPriorities {
+		if len(r.queues[p]) == 0 {
+			continue
+		}
+		share := max(r.shares[p], 0.001)
+		score := float64(r.served[p]+1) / share
+		if best < 0 || score < bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best, best >= 0
+}
+
+func (r *RateLimitedTransport) schedule(d time.Duration) {
+	if r.timer != nil {
+		r.timer.Reset(d)
+		return
+	}
+	r.timer = time.AfterFunc(d, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.dispatch()
+	})
+}
+
+// dequeue drops a request whose context finished while it was waiting.
+func (r *RateLimitedTransport) dequeue(p Priority, w *waiter) {
+	for i, q := range r.queues[p] {
+		if q == w {
+			r.queues[p] = append(r.queues[p][:i], r.queues[p][i+1:]...)
+			return
+		}
+	}
+}
@@ -1,20 +1,311 @@
 package client
 
 import (
+	"context"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/bwiggs/spacetraders-go/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
+// SpaceTraders rate limit response headers.
+// https://docs.spacetraders.io/api-guide/rate-limits
+const (
+	HeaderRateLimitType           = "x-ratelimit-type"
+	HeaderRateLimitLimitBurst     = "x-ratelimit-limit-burst"
+	HeaderRateLimitLimitPerSecond = "x-ratelimit-limit-per-second"
+	HeaderRateLimitRemaining      = "x-ratelimit-remaining"
+	HeaderRateLimitReset          = "x-ratelimit-reset"
+	HeaderRateLimitBurstTime      = "x-ratelimit-burst-time"
+	HeaderRetryAfter              = "Retry-After"
+)
+
+// defaults used until the server tells us otherwise.
+const (
+	defaultLimitPerSecond = 2
+	defaultLimitBurst     = 30
+	defaultBurstPeriod    = 60 * time.Second
+	defaultMaxRetries     = 5
+	maxBackoff            = 30 * time.Second
+)
+
+// RateLimitedTransport paces requests to stay inside the SpaceTraders rate
+// limits. The server grants a static pool that refills every second and a burst
+// pool that is only drawn from once the static pool is empty, and refills once
+// per burst period. Both pools are modelled locally and corrected from the
+// x-ratelimit-* headers on every response. A 429 pauses all traffic for the
+// Retry-After duration and the request is sent again.
 type RateLimitedTransport struct {
-	Base    http.RoundTripper
-	Limiter *rate.Limiter
+	Base http.RoundTripper
+
+	// MaxRetries is the number of times a rate limited request is resent
+	// before the 429 is returned to the caller.
+	MaxRetries int
+
+	// Timeout bounds each attempt once it leaves the limiter. Time spent
+	// waiting for budget does not count against it.
+	Timeout time.Duration
+
+	mu             sync.Mutex
+	static         *rate.Limiter
+	burstLimit     int
+	burstRemaining int
+	burstPeriod    time.Duration
+	burstReset     time.Time
+	pausedUntil    time.Time
+
+	// requests waiting on budget, see priority.go
+	queues [numPriorities][]*waiter
+	shares [numPriorities]float64
+	served [numPriorities]int
+	timer  *time.Timer
+}
+
+func NewRateLimitedTransport(base http.RoundTripper) *RateLimitedTransport {
+	return &RateLimitedTransport{
+		Base:           base,
+		MaxRetries:     defaultMaxRetries,
+		static:         rate.NewLimiter(rate.Limit(defaultLimitPerSecond), defaultLimitPerSecond),
+		burstLimit:     defaultLimitBurst,
+		burstRemaining: defaultLimitBurst,
+		burstPeriod:    defaultBurstPeriod,
+		shares:         DefaultShares,
+	}
 }
 
 func (r *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	err := r.Limiter.Wait(req.Context())
+	for attempt := 0; ; attempt++ {
+		if err := r.wait(req); err != nil {
+			return nil, err
+		}
+
+		res, err := r.send(req)
+		if err != nil {
+			return nil, err
+		}
+
+		r.observe(res)
+
+		if res.StatusCode != http.StatusTooManyRequests || attempt >= r.MaxRetries {
+			return res, nil
+		}
+
+		// a 429 is rejected before the server processes it, so it is always
+		// safe to send the request again, including mutating requests.
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return res, nil
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return res, nil
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		res.Body.Close()
+
+		backoff := retryAfter(res, attempt)
+		r.pause(backoff)
+
+		slog.Warn("rate limited", "url", req.URL.Path, "attempt", attempt+1, "retryAfter", backoff)
+	}
+}
+
+// send makes one attempt. Once a request has been granted budget it's no
+// longer cancelled with the caller's context, only bounded by Timeout, so a
+// cancelled tick doesn't abandon a purchase the server may already have
+// applied and the response still reaches the ship's state.
+func (r *RateLimitedTransport) send(req *http.Request) (*http.Response, error) {
+	ctx := context.WithoutCancel(req.Context())
+	if r.Timeout <= 0 {
+		return r.Base.RoundTrip(req.WithContext(ctx))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	res, err := r.Base.RoundTrip(req.WithContext(ctx))
 	if err != nil {
+		cancel()
 		return nil, err
 	}
-	return r.Base.RoundTrip(req)
+
+	// keep the attempt alive until the caller is done reading the body
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// wait queues the request by its priority and blocks until the dispatcher
+// grants it a slot in either pool.
+func (r *RateLimitedTransport) wait(req *http.Request) error {
+	ctx := req.Context()
+	prio := PriorityFromContext(ctx)
+
+	_, span := telemetry.Tracer().Start(ctx, "ratelimit.wait", trace.WithAttributes(attribute.Int("priority", int(prio))))
+	defer span.End()
+	w := &waiter{ready: make(chan struct{})}
+
+	r.mu.Lock()
+	r.queues[prio] = append(r.queues[prio], w)
+	r.dispatch()
+	r.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.dequeue(prio, w)
+		r.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// take claims a slot from the static pool, falling back to the burst pool. When
+// neither has one it returns how long until it is worth trying again.
+func (r *RateLimitedTransport) take(now time.Time) (time.Duration, bool) {
+	if now.Before(r.pausedUntil) {
+		return r.pausedUntil.Sub(now), false
+	}
+
+	if r.static.AllowN(now, 1) {
+		return 0, true
+	}
+
+	if !r.burstReset.IsZero() && now.After(r.burstReset) {
+		r.burstRemaining = r.burstLimit
+		r.burstReset = time.Time{}
+	}
+
+	if r.burstRemaining > 0 {
+		r.burstRemaining--
+		if r.burstReset.IsZero() {
+			r.burstReset = now.Add(r.burstPeriod)
+		}
+		return 0, true
+	}
+
+	// next static token, or the burst pool refilling if that comes first
+	missing := 1 - r.static.TokensAt(now)
+	d := time.Duration(missing / float64(r.static.Limit()) * float64(time.Second))
+	if !r.burstReset.IsZero() {
+		d = min(d, r.burstReset.Sub(now))
+	}
+	return max(d, time.Millisecond), false
+}
+
+// observe syncs the local model with the limits reported by the server.
+func (r *RateLimitedTransport) observe(res *http.Response) {
+	h := res.Header
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if perSecond, err := strconv.Atoi(h.Get(HeaderRateLimitLimitPerSecond)); err == nil && perSecond > 0 {
+		if r.static.Limit() != rate.Limit(perSecond) {
+			r.static.SetLimit(rate.Limit(perSecond))
+			r.static.SetBurst(perSecond)
+		}
+	}
+
+	if burst, err := strconv.Atoi(h.Get(HeaderRateLimitLimitBurst)); err == nil && burst > 0 {
+		r.burstLimit = burst
+	}
+
+	if secs, err := strconv.Atoi(h.Get(HeaderRateLimitBurstTime)); err == nil && secs > 0 {
+		r.burstPeriod = time.Duration(secs) * time.Second
+	}
+
+	remaining, err := strconv.Atoi(h.Get(HeaderRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	reset, err := time.Parse(time.RFC3339, h.Get(HeaderRateLimitReset))
+	if err != nil {
+		return
+	}
+
+	// other requests may still be in flight, so within the same window only
+	// ever lower the local count. A new window is taken as reported.
+	if reset.After(r.burstReset) {
+		r.burstRemaining = remaining
+	} else {
+		r.burstRemaining = min(r.burstRemaining, remaining)
+	}
+	r.burstReset = reset
+}
+
+func (r *RateLimitedTransport) pause(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(r.pausedUntil) {
+		r.pausedUntil = until
+	}
+	r.burstRemaining = 0
+}
+
+// Pressure returns how much of the request budget is currently used, from 0
+// (both pools full) to 1 (both pools empty or paused after a 429).
+func (r *RateLimitedTransport) Pressure() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(r.pausedUntil) {
+		return 1
+	}
+
+	burst := r.burstRemaining
+	if !r.burstReset.IsZero() && now.After(r.burstReset) {
+		burst = r.burstLimit
+	}
+
+	capacity := float64(r.static.Burst() + r.burstLimit)
+	available := math.Max(0, r.static.TokensAt(now)) + float64(burst)
+
+	return math.Min(1, math.Max(0, 1-available/capacity))
+}
+
+// retryAfter returns how long to wait before resending a rate limited request.
+// Retry-After is fractional seconds; without it fall back to exponential backoff.
+func retryAfter(res *http.Response, attempt int) time.Duration {
+	if secs, err := strconv.ParseFloat(res.Header.Get(HeaderRetryAfter), 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return min(maxBackoff, time.Second<<attempt)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
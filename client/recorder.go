@@ -0,0 +1,191 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording is a single request/response pair, stored one per line in a JSONL
+// file. Request headers aren't kept so tokens never end up on disk.
+type Recording struct {
+	Time         time.Time     `json:"time"`
+	Duration     time.Duration `json:"duration"`
+	Method       string        `json:"method"`
+	URI          string        `json:"uri"`
+	RequestBody  string        `json:"requestBody,omitempty"`
+	Status       int           `json:"status"`
+	Header       http.Header   `json:"header,omitempty"`
+	ResponseBody string        `json:"responseBody,omitempty"`
+}
+
+func (r *Recording) key() string {
+	return r.Method + " " + r.URI + " " + r.RequestBody
+}
+
+// RecordingTransport writes every request and response that passes through it
+// to a JSONL file, to be served back later by a ReplayTransport. Registering an
+// agent isn't recorded, its response carries the new agent's token.
+type RecordingTransport struct {
+	Base http.RoundTripper
+
+	mu     sync.Mutex
+	out    *os.File
+	enc    *json.Encoder
+	closed bool
+}
+
+// NewRecordingTransport appends recordings to the file at path.
+func NewRecordingTransport(base http.RoundTripper, path string) (*RecordingTransport, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("recorder: open %s: %w", path, err)
+	}
+	return &RecordingTransport{Base: base, out: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/register") {
+		return t.Base.RoundTrip(req)
+	}
+
+	rec := Recording{
+		Time:   time.Now(),
+		Method: req.Method,
+		URI:    req.URL.RequestURI(),
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			buf, _ := io.ReadAll(body)
+			body.Close()
+			rec.RequestBody = string(buf)
+		}
+	}
+
+	res, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(buf))
+
+	rec.Duration = time.Since(rec.Time)
+	rec.Status = res.StatusCode
+	rec.Header = res.Header
+	rec.ResponseBody = string(buf)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return res, nil
+	}
+	if err := t.enc.Encode(rec); err != nil {
+		return nil, fmt.Errorf("recorder: write: %w", err)
+	}
+
+	return res, nil
+}
+
+// Close closes the recording file, exchanges after it are no longer
+// recorded. Closing it again does nothing.
+func (t *RecordingTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	return t.out.Close()
+}
+
+// ReplayTransport serves recorded responses instead of going to the network.
+// Requests are matched on method, uri and body. Repeated requests are answered
+// in the order they were recorded, and once those run out the last one is
+// served again, so polling loops keep working past the end of a capture.
+type ReplayTransport struct {
+	mu         sync.Mutex
+	recordings map[string][]*Recording
+	served     map[string]int
+}
+
+// NewReplayTransport loads the recordings in the JSONL file at path.
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay: open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	t := &ReplayTransport{
+		recordings: make(map[string][]*Recording),
+		served:     make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		rec := &Recording{}
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return nil, fmt.Errorf("replay: %s:%d: %w", path, line, err)
+		}
+		t.recordings[rec.key()] = append(t.recordings[rec.key()], rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("replay: read %s: %w", path, err)
+	}
+
+	return t, nil
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := Recording{Method: req.Method, URI: req.URL.RequestURI()}
+	if req.Body != nil {
+		buf, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		rec.RequestBody = string(buf)
+	}
+
+	t.mu.Lock()
+	key := rec.key()
+	recs := t.recordings[key]
+	if len(recs) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("replay: no recording for %s %s", rec.Method, rec.URI)
+	}
+	i := min(t.served[key], len(recs)-1)
+	t.served[key]++
+	found := recs[i]
+	t.mu.Unlock()
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Status, http.StatusText(found.Status)),
+		StatusCode:    found.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        found.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(found.ResponseBody))),
+		ContentLength: int64(len(found.ResponseBody)),
+		Request:       req,
+	}, nil
+}
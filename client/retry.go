@@ -0,0 +1,344 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+const (
+	defaultRetryAttempts = 3
+	defaultRetryBackoff  = 500 * time.Millisecond
+)
+
+// RetryInvoker retries api calls that fail with a transient error: attempt
+// timeouts, dropped connections and 5xx responses. 429s are left to the
+// RateLimitedTransport, which has already resent them by the time they get
+// here.
+//
+// Reads and the idempotent dock, orbit and flight mode calls are simply sent
+// again. A failed mutating call may still have been applied by the server, so
+// NavigateShip, PurchaseCargo and SellCargo first check the ships current
+// state and only resend when the original request didn't land. Trades note
+// the ships hold before their first attempt to compare against. Every other
+// operation is passed through untouched.
+type RetryInvoker struct {
+	api.Invoker
+
+	Attempts int
+	Backoff  time.Duration
+}
+
+func NewRetryInvoker(next api.Invoker) *RetryInvoker {
+	return &RetryInvoker{
+		Invoker:  next,
+		Attempts: defaultRetryAttempts,
+		Backoff:  defaultRetryBackoff,
+	}
+}
+
+// IsTransient reports whether err is worth retrying. Errors caused by the
+// callers own context being done never are, nor are 429s, which outlasted
+// the rate limiter's own retries.
+func IsTransient(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode != http.StatusTooManyRequests && apiErr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// next reports whether another attempt should be made after err, sleeping
+// through the backoff first.
+func (r *RetryInvoker) next(ctx context.Context, op string, attempt int, err error) bool {
+	if attempt+1 >= r.Attempts || !IsTransient(ctx, err) {
+		return false
+	}
+
+	backoff := r.Backoff << attempt
+	slog.Warn("retrying api call", "op", op, "attempt", attempt+1, "backoff", backoff, "err", err)
+
+	return sleep(ctx, backoff) == nil
+}
+
+func retry[T any](r *RetryInvoker, ctx context.Context, op string, fn func() (T, error)) (T, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := fn()
+		if err == nil || !r.next(ctx, op, attempt, err) {
+			return res, err
+		}
+	}
+}
+
+// NavigateShip only resends the request when the ship isn't already headed
+// for the destination.
+func (r *RetryInvoker) NavigateShip(ctx context.Context, request api.OptNavigateShipReq, params api.NavigateShipParams) (*api.NavigateShipOK, error) {
+	dest := request.Value.WaypointSymbol
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		res, err := r.Invoker.NavigateShip(ctx, request, params)
+		if err == nil || !r.next(ctx, "NavigateShip", attempt, err) {
+			return res, err
+		}
+
+		ship, verr := r.Invoker.GetMyShip(ctx, api.GetMyShipParams{ShipSymbol: params.ShipSymbol})
+		if verr != nil {
+			// can't tell if the ship left, resending could send it somewhere twice
+			return nil, err
+		}
+
+		nav := ship.Data.Nav
+		if nav.Route.Destination.Symbol == dest && nav.Route.DepartureTime.After(start.Add(-time.Minute)) {
+			slog.Info("NavigateShip: request landed before failing, not resending", "ship", params.ShipSymbol, "dest", dest)
+			return &api.NavigateShipOK{Data: api.NavigateShipOKData{Fuel: ship.Data.Fuel, Nav: nav}}, nil
+		}
+	}
+}
+
+// PurchaseCargo compares the ships hold with its hold before the first
+// attempt before resending, so goods are never bought twice. Batches of the
+// same size bought moments apart can't be told apart in the markets
+// transaction log, the hold can.
+func (r *RetryInvoker) PurchaseCargo(ctx context.Context, request api.OptPurchaseCargoReq, params api.PurchaseCargoParams) (*api.PurchaseCargoCreated, error) {
+	good, units := request.Value.Symbol, request.Value.Units
+	held, herr := r.heldUnits(ctx, params.ShipSymbol, good)
+
+	for attempt := 0; ; attempt++ {
+		res, err := r.Invoker.PurchaseCargo(ctx, request, params)
+		// without the hold to compare against, a retry could buy twice
+		if err == nil || herr != nil || !r.next(ctx, "PurchaseCargo", attempt, err) {
+			return res, err
+		}
+
+		tx, cargo, agent, found, verr := r.findTrade(ctx, params.ShipSymbol, api.MarketTransactionTypePURCHASE, good, units, held+units)
+		if verr != nil {
+			return nil, err
+		}
+		if found {
+			slog.Info("PurchaseCargo: request landed before failing, not resending", "ship", params.ShipSymbol, "good", good)
+			return &api.PurchaseCargoCreated{Data: api.PurchaseCargoCreatedData{Agent: agent, Cargo: cargo, Transaction: tx}}, nil
+		}
+	}
+}
+
+// SellCargo compares the ships hold with its hold before the first attempt
+// before resending, so goods are never sold twice.
+func (r *RetryInvoker) SellCargo(ctx context.Context, request api.OptSellCargoReq, params api.SellCargoParams) (*api.SellCargoCreated, error) {
+	good, units := request.Value.Symbol, request.Value.Units
+	held, herr := r.heldUnits(ctx, params.ShipSymbol, good)
+
+	for attempt := 0; ; attempt++ {
+		res, err := r.Invoker.SellCargo(ctx, request, params)
+		// without the hold to compare against, a retry could sell twice
+		if err == nil || herr != nil || !r.next(ctx, "SellCargo", attempt, err) {
+			return res, err
+		}
+
+		tx, cargo, agent, found, verr := r.findTrade(ctx, params.ShipSymbol, api.MarketTransactionTypeSELL, good, units, held-units)
+		if verr != nil {
+			return nil, err
+		}
+		if found {
+			slog.Info("SellCargo: request landed before failing, not resending", "ship", params.ShipSymbol, "good", good)
+			return &api.SellCargoCreated{Data: api.SellCargoCreatedData{Agent: agent, Cargo: cargo, Transaction: tx}}, nil
+		}
+	}
+}
+
+// heldUnits returns how many units of good the ship holds.
+func (r *RetryInvoker) heldUnits(ctx context.Context, ship string, good api.TradeSymbol) (int, error) {
+	cargo, err := r.GetMyShipCargo(ctx, api.GetMyShipCargoParams{ShipSymbol: ship})
+	if err != nil {
+		return 0, err
+	}
+	return cargoUnits(cargo.Data, good), nil
+}
+
+func cargoUnits(cargo api.ShipCargo, good api.TradeSymbol) int {
+	for _, item := range cargo.Inventory {
+		if item.Symbol == good {
+			return item.Units
+		}
+	}
+	return 0
+}
+
+// findTrade reports whether a trade by the ship landed, going by whether its
+// hold now has want units of good. When it did, the markets latest matching
+// transaction and the agent are fetched so the caller can be handed a
+// complete response.
+func (r *RetryInvoker) findTrade(ctx context.Context, ship string, kind api.MarketTransactionType, good api.TradeSymbol, traded, want int) (api.MarketTransaction, api.ShipCargo, api.Agent, bool, error) {
+	tx := api.MarketTransaction{ShipSymbol: ship, TradeSymbol: string(good), Type: kind, Units: traded, Timestamp: time.Now()}
+
+	cargo, err := r.Invoker.GetMyShipCargo(ctx, api.GetMyShipCargoParams{ShipSymbol: ship})
+	if err != nil {
+		return tx, api.ShipCargo{}, api.Agent{}, false, err
+	}
+	if cargoUnits(cargo.Data, good) != want {
+		return tx, api.ShipCargo{}, api.Agent{}, false, nil
+	}
+
+	nav, err := r.Invoker.GetShipNav(ctx, api.GetShipNavParams{ShipSymbol: ship})
+	if err != nil {
+		return tx, api.ShipCargo{}, api.Agent{}, false, err
+	}
+	tx.WaypointSymbol = nav.Data.WaypointSymbol
+
+	market, err := r.Invoker.GetMarket(ctx, api.GetMarketParams{SystemSymbol: string(nav.Data.SystemSymbol), WaypointSymbol: string(nav.Data.WaypointSymbol)})
+	if err != nil {
+		return tx, api.ShipCargo{}, api.Agent{}, false, err
+	}
+	// the hold says it landed, the log only supplies the price
+	for _, t := range market.Data.Transactions {
+		if t.ShipSymbol == ship && t.Type == kind && t.TradeSymbol == string(good) && t.Units == traded {
+			tx = t
+		}
+	}
+
+	agent, err := r.Invoker.GetMyAgent(ctx)
+	if err != nil {
+		return tx, api.ShipCargo{}, api.Agent{}, false, err
+	}
+
+	return tx, cargo.Data, agent.Data, true, nil
+}
+
+// reads and idempotent writes are resent as is
+
+func (r *RetryInvoker) GetAgent(ctx context.Context, params api.GetAgentParams) (*api.GetAgentOK, error) {
+	return retry(r, ctx, "GetAgent", func() (*api.GetAgentOK, error) { return r.Invoker.GetAgent(ctx, params) })
+}
+
+func (r *RetryInvoker) GetAgents(ctx context.Context, params api.GetAgentsParams) (*api.GetAgentsOK, error) {
+	return retry(r, ctx, "GetAgents", func() (*api.GetAgentsOK, error) { return r.Invoker.GetAgents(ctx, params) })
+}
+
+func (r *RetryInvoker) GetConstruction(ctx context.Context, params api.GetConstructionParams) (*api.GetConstructionOK, error) {
+	return retry(r, ctx, "GetConstruction", func() (*api.GetConstructionOK, error) { return r.Invoker.GetConstruction(ctx, params) })
+}
+
+func (r *RetryInvoker) GetContract(ctx context.Context, params api.GetContractParams) (*api.GetContractOK, error) {
+	return retry(r, ctx, "GetContract", func() (*api.GetContractOK, error) { return r.Invoker.GetContract(ctx, params) })
+}
+
+func (r *RetryInvoker) GetContracts(ctx context.Context, params api.GetContractsParams) (*api.GetContractsOK, error) {
+	return retry(r, ctx, "GetContracts", func() (*api.GetContractsOK, error) { return r.Invoker.GetContracts(ctx, params) })
+}
+
+func (r *RetryInvoker) GetFaction(ctx context.Context, params api.GetFactionParams) (*api.GetFactionOK, error) {
+	return retry(r, ctx, "GetFaction", func() (*api.GetFactionOK, error) { return r.Invoker.GetFaction(ctx, params) })
+}
+
+func (r *RetryInvoker) GetFactions(ctx context.Context, params api.GetFactionsParams) (*api.GetFactionsOK, error) {
+	return retry(r, ctx, "GetFactions", func() (*api.GetFactionsOK, error) { return r.Invoker.GetFactions(ctx, params) })
+}
+
+func (r *RetryInvoker) GetJumpGate(ctx context.Context, params api.GetJumpGateParams) (*api.GetJumpGateOK, error) {
+	return retry(r, ctx, "GetJumpGate", func() (*api.GetJumpGateOK, error) { return r.Invoker.GetJumpGate(ctx, params) })
+}
+
+func (r *RetryInvoker) GetMarket(ctx context.Context, params api.GetMarketParams) (*api.GetMarketOK, error) {
+	return retry(r, ctx, "GetMarket", func() (*api.GetMarketOK, error) { return r.Invoker.GetMarket(ctx, params) })
+}
+
+func (r *RetryInvoker) GetMounts(ctx context.Context, params api.GetMountsParams) (*api.GetMountsOK, error) {
+	return retry(r, ctx, "GetMounts", func() (*api.GetMountsOK, error) { return r.Invoker.GetMounts(ctx, params) })
+}
+
+func (r *RetryInvoker) GetMyAgent(ctx context.Context) (*api.GetMyAgentOK, error) {
+	return retry(r, ctx, "GetMyAgent", func() (*api.GetMyAgentOK, error) { return r.Invoker.GetMyAgent(ctx) })
+}
+
+func (r *RetryInvoker) GetMyShip(ctx context.Context, params api.GetMyShipParams) (*api.GetMyShipOK, error) {
+	return retry(r, ctx, "GetMyShip", func() (*api.GetMyShipOK, error) { return r.Invoker.GetMyShip(ctx, params) })
+}
+
+func (r *RetryInvoker) GetMyShipCargo(ctx context.Context, params api.GetMyShipCargoParams) (*api.GetMyShipCargoOK, error) {
+	return retry(r, ctx, "GetMyShipCargo", func() (*api.GetMyShipCargoOK, error) { return r.Invoker.GetMyShipCargo(ctx, params) })
+}
+
+func (r *RetryInvoker) GetMyShips(ctx context.Context, params api.GetMyShipsParams) (*api.GetMyShipsOK, error) {
+	return retry(r, ctx, "GetMyShips", func() (*api.GetMyShipsOK, error) { return r.Invoker.GetMyShips(ctx, params) })
+}
+
+func (r *RetryInvoker) GetRepairShip(ctx context.Context, params api.GetRepairShipParams) (*api.GetRepairShipOK, error) {
+	return retry(r, ctx, "GetRepairShip", func() (*api.GetRepairShipOK, error) { return r.Invoker.GetRepairShip(ctx, params) })
+}
+
+func (r *RetryInvoker) GetScrapShip(ctx context.Context, params api.GetScrapShipParams) (*api.GetScrapShipOK, error) {
+	return retry(r, ctx, "GetScrapShip", func() (*api.GetScrapShipOK, error) { return r.Invoker.GetScrapShip(ctx, params) })
+}
+
+func (r *RetryInvoker) GetShipCooldown(ctx context.Context, params api.GetShipCooldownParams) (api.GetShipCooldownRes, error) {
+	return retry(r, ctx, "GetShipCooldown", func() (api.GetShipCooldownRes, error) { return r.Invoker.GetShipCooldown(ctx, params) })
+}
+
+func (r *RetryInvoker) GetShipModules(ctx context.Context, params api.GetShipModulesParams) (*api.GetShipModulesOK, error) {
+	return retry(r, ctx, "GetShipModules", func() (*api.GetShipModulesOK, error) { return r.Invoker.GetShipModules(ctx, params) })
+}
+
+func (r *RetryInvoker) GetShipNav(ctx context.Context, params api.GetShipNavParams) (*api.GetShipNavOK, error) {
+	return retry(r, ctx, "GetShipNav", func() (*api.GetShipNavOK, error) { return r.Invoker.GetShipNav(ctx, params) })
+}
+
+func (r *RetryInvoker) GetShipyard(ctx context.Context, params api.GetShipyardParams) (*api.GetShipyardOK, error) {
+	return retry(r, ctx, "GetShipyard", func() (*api.GetShipyardOK, error) { return r.Invoker.GetShipyard(ctx, params) })
+}
+
+func (r *RetryInvoker) GetStatus(ctx context.Context) (*api.GetStatusOK, error) {
+	return retry(r, ctx, "GetStatus", func() (*api.GetStatusOK, error) { return r.Invoker.GetStatus(ctx) })
+}
+
+func (r *RetryInvoker) GetSupplyChain(ctx context.Context) (*api.GetSupplyChainOK, error) {
+	return retry(r, ctx, "GetSupplyChain", func() (*api.GetSupplyChainOK, error) { return r.Invoker.GetSupplyChain(ctx) })
+}
+
+func (r *RetryInvoker) GetSystem(ctx context.Context, params api.GetSystemParams) (*api.GetSystemOK, error) {
+	return retry(r, ctx, "GetSystem", func() (*api.GetSystemOK, error) { return r.Invoker.GetSystem(ctx, params) })
+}
+
+func (r *RetryInvoker) GetSystemWaypoints(ctx context.Context, params api.GetSystemWaypointsParams) (*api.GetSystemWaypointsOK, error) {
+	return retry(r, ctx, "GetSystemWaypoints", func() (*api.GetSystemWaypointsOK, error) { return r.Invoker.GetSystemWaypoints(ctx, params) })
+}
+
+func (r *RetryInvoker) GetSystems(ctx context.Context, params api.GetSystemsParams) (*api.GetSystemsOK, error) {
+	return retry(r, ctx, "GetSystems", func() (*api.GetSystemsOK, error) { return r.Invoker.GetSystems(ctx, params) })
+}
+
+func (r *RetryInvoker) GetWaypoint(ctx context.Context, params api.GetWaypointParams) (*api.GetWaypointOK, error) {
+	return retry(r, ctx, "GetWaypoint", func() (*api.GetWaypointOK, error) { return r.Invoker.GetWaypoint(ctx, params) })
+}
+
+func (r *RetryInvoker) DockShip(ctx context.Context, params api.DockShipParams) (*api.DockShipOK, error) {
+	return retry(r, ctx, "DockShip", func() (*api.DockShipOK, error) { return r.Invoker.DockShip(ctx, params) })
+}
+
+func (r *RetryInvoker) OrbitShip(ctx context.Context, params api.OrbitShipParams) (*api.OrbitShipOK, error) {
+	return retry(r, ctx, "OrbitShip", func() (*api.OrbitShipOK, error) { return r.Invoker.OrbitShip(ctx, params) })
+}
+
+func (r *RetryInvoker) PatchShipNav(ctx context.Context, request api.OptPatchShipNavReq, params api.PatchShipNavParams) (*api.PatchShipNavOK, error) {
+	return retry(r, ctx, "PatchShipNav", func() (*api.PatchShipNavOK, error) { return r.Invoker.PatchShipNav(ctx, request, params) })
+}
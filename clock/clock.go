@@ -0,0 +1,52 @@
+// Package clock is the time source for ships, behaviors and the task
+// scheduler. Production code runs on the real clock; simulations and mission
+// tests swap in a Fake and fast-forward through transits and cooldowns.
+package clock
+
+import (
+	"context"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// Clock is clockwork's interface so it can be handed straight to gocron.
+type Clock = clockwork.Clock
+
+// Real returns the wall clock.
+func Real() Clock {
+	return clockwork.NewRealClock()
+}
+
+// Fake is a clock that only moves when told to.
+type Fake struct {
+	*clockwork.FakeClock
+}
+
+// NewFake returns a fake clock starting at t.
+func NewFake(t time.Time) *Fake {
+	return &Fake{FakeClock: clockwork.NewFakeClockAt(t)}
+}
+
+// FastForward advances the clock by step every time sleepers goroutines are
+// blocked on it, until ctx is done. With every ship and task waiting on the
+// clock, hours of game time pass as fast as the goroutines can respond.
+func (f *Fake) FastForward(ctx context.Context, sleepers int, step time.Duration) error {
+	for {
+		if err := f.BlockUntilContext(ctx, sleepers); err != nil {
+			return err
+		}
+		f.Advance(step)
+	}
+}
+
+// Or returns c, or the real clock when c is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+	return c
+}
+
+// Timer is clockwork's timer, fired by the clock that made it.
+type Timer = clockwork.Timer
@@ -0,0 +1,108 @@
+// Package db embeds the schema migrations so the kernel can build a fresh
+// database without the migrate cli.
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.up.sql
+var migrations embed.FS
+
+type migration struct {
+	version uint64
+	name    string
+}
+
+// Migrate applies every up migration newer than the database's version. It
+// keeps its place in the same schema_migrations table the migrate cli uses,
+// so `make migrate` and the kernel can take turns on one database.
+func Migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version uint64, dirty bool);
+CREATE UNIQUE INDEX IF NOT EXISTS version_unique ON schema_migrations (version);`); err != nil {
+		return err
+	}
+
+	var current uint64
+	var dirty bool
+	err := db.QueryRow(`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migrate: database is dirty at version %d, fix it and force the version with the migrate cli", current)
+	}
+
+	pending, err := pendingMigrations(current)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		buf, err := migrations.ReadFile("migrations/" + m.name)
+		if err != nil {
+			return err
+		}
+		if err := setVersion(db, m.version, true); err != nil {
+			return err
+		}
+		if _, err := db.Exec(string(buf)); err != nil {
+			return fmt.Errorf("migrate: %s: %w", m.name, err)
+		}
+		if err := setVersion(db, m.version, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingMigrations returns the up migrations after version, oldest first.
+func pendingMigrations(version uint64) ([]migration, error) {
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []migration{}
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("migrate: %s: expected VERSION_name.up.sql", e.Name())
+		}
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", e.Name(), err)
+		}
+		if v > version {
+			pending = append(pending, migration{version: v, name: e.Name()})
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].version < pending[j].version
+	})
+	return pending, nil
+}
+
+func setVersion(db *sql.DB, version uint64, dirty bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, dirty) VALUES (?, ?)`, version, dirty); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1 @@
+DROP TABLE market_snapshots;
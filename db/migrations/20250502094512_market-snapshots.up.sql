@@ -0,0 +1,16 @@
+CREATE TABLE market_snapshots (
+    waypoint TEXT NOT NULL,
+    good TEXT NOT NULL,
+    type TEXT NOT NULL,
+    supply TEXT,
+    volume INTEGER,
+    activity TEXT,
+    bid INTEGER,
+    ask INTEGER,
+    recorded_at TIMESTAMP NOT NULL,
+
+    FOREIGN KEY(waypoint) REFERENCES waypoints(symbol),
+    FOREIGN KEY(good) REFERENCES goods(symbol)
+);
+
+CREATE INDEX market_snapshots_recorded_at ON market_snapshots (recorded_at);
@@ -0,0 +1,2 @@
+DROP INDEX fleet_agent;
+ALTER TABLE fleet DROP COLUMN agent;
@@ -0,0 +1,4 @@
+-- existing ships pick up their agent on the next fleet update
+ALTER TABLE fleet ADD COLUMN agent TEXT;
+
+CREATE INDEX fleet_agent ON fleet (agent);
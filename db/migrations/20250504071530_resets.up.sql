@@ -0,0 +1,15 @@
+CREATE TABLE server_resets (
+    reset_date TEXT NOT NULL,
+    seen_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (reset_date)
+);
+
+CREATE TABLE agent_tokens (
+    symbol TEXT NOT NULL,
+    faction TEXT NOT NULL,
+    token TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (symbol)
+);
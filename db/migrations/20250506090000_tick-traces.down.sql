@@ -0,0 +1 @@
+DROP TABLE tick_traces;
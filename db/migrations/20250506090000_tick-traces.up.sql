@@ -0,0 +1,14 @@
+-- a ring buffer of every ship's most recent behavior tree ticks, trimmed
+-- on insert
+CREATE TABLE tick_traces (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    ship TEXT NOT NULL,
+    mission TEXT NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    duration INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    nodes TEXT NOT NULL,
+    changes TEXT NOT NULL
+);
+
+CREATE INDEX tick_traces_ship_started_at ON tick_traces (ship, started_at);
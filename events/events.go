@@ -0,0 +1,149 @@
+// Package events is the kernel's typed pub/sub bus for domain events: ships
+// arriving, cooldowns expiring, cargo changing, trades, contracts, market
+// scans, credits and crashing ships. Ship actors, tasks and the supervisor
+// publish as things happen, metrics, logging, the ui and missions subscribe
+// to the event types they care about instead of polling.
+//
+// A nil *Bus drops everything published to it, so code that emits events
+// works without one, e.g. from the cli.
+package events
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/repo"
+)
+
+// ShipArrived is published when a ship finishes a transit.
+type ShipArrived struct {
+	Ship     string
+	Waypoint string
+}
+
+// CooldownExpired is published when a ship's reactor cooldown runs out.
+type CooldownExpired struct {
+	Ship string
+}
+
+// CargoChanged is published with a ship's hold after anything changes it.
+type CargoChanged struct {
+	Ship  string
+	Cargo api.ShipCargo
+}
+
+// TradeExecuted is published for every purchase, sale and refuel.
+type TradeExecuted struct {
+	Ship        string
+	Transaction api.MarketTransaction
+}
+
+// ContractAccepted is published when an agent accepts a contract.
+type ContractAccepted struct {
+	Contract api.Contract
+}
+
+// ContractDelivered is published when a ship delivers goods to a contract.
+type ContractDelivered struct {
+	Contract api.Contract
+	Ship     string
+	Good     string
+	Units    int
+}
+
+// ContractFulfilled is published when a contract is fulfilled.
+type ContractFulfilled struct {
+	Contract api.Contract
+}
+
+// MarketScanned is published with every market fetched, priced or not.
+type MarketScanned struct {
+	Market api.Market
+}
+
+// CreditsChanged is published with an agent's balance whenever a response
+// reports it.
+type CreditsChanged struct {
+	Agent   string
+	Credits int64
+}
+
+// ShipCrashed is published when the supervisor recovers a ship's panicking
+// tick.
+type ShipCrashed struct {
+	Ship  string
+	Panic string
+	Stack string
+}
+
+// ShipQuarantined is published when a ship crashed too often and was parked
+// on an idle mission, it needs looking at.
+type ShipQuarantined struct {
+	Ship    string
+	Crashes int
+}
+
+// ShipTicked is published after every behavior tree tick of a ship, with the
+// nodes it visited and the blackboard fields it changed.
+type ShipTicked struct {
+	Trace repo.TickTrace
+}
+
+// Bus delivers each published event to the subscribers of its type.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[reflect.Type]map[int]func(any)
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: map[reflect.Type]map[int]func(any){}}
+}
+
+// Subscribe returns a channel receiving every later event of type E, and a
+// func to stop receiving. Publishing never waits for a subscriber, events
+// that don't fit in its buffer are dropped.
+func Subscribe[E any](b *Bus, buffer int) (<-chan E, func()) {
+	ch := make(chan E, buffer)
+	typ := reflect.TypeFor[E]()
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	if b.subs[typ] == nil {
+		b.subs[typ] = map[int]func(any){}
+	}
+	b.subs[typ][id] = func(e any) {
+		select {
+		case ch <- e.(E):
+		default:
+			slog.Warn("events: subscriber is behind, dropping event", "type", typ.String())
+		}
+	}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs[typ], id)
+			close(ch)
+		})
+	}
+}
+
+// Publish sends e to every subscriber of its type.
+func Publish[E any](b *Bus, e E) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, deliver := range b.subs[reflect.TypeFor[E]()] {
+		deliver(e)
+	}
+}
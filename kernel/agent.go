@@ -0,0 +1,126 @@
+package kernel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/scheduler"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Agent is one account the kernel plays. Each agent has its own token,
+// client, rate limiter, fleet and State, which its client keeps current.
+// Systems, waypoints and markets live in the kernel's repo and are shared by
+// every agent.
+//
+// The agent's ships are ticked by its Scheduler, so ships of one agent
+// only compete with each other for its rate limit.
+type Agent struct {
+	Symbol string
+	client api.Invoker
+	state  *State
+	sched  *scheduler.Scheduler
+
+	// base is the client from NewClient under the caching and state
+	// invokers, closed when the kernel stops
+	base api.Invoker
+}
+
+func (a *Agent) Client() api.Invoker {
+	return a.client
+}
+
+func (a *Agent) State() *State {
+	return a.state
+}
+
+func (a *Agent) Scheduler() *scheduler.Scheduler {
+	return a.sched
+}
+
+type agentConfig struct {
+	symbol string
+	token  string
+}
+
+// WithAgent plays symbol with token alongside any other configured agents.
+func WithAgent(symbol, token string) Option {
+	return func(k *Kernel) {
+		k.agentConfigs = append(k.agentConfigs, agentConfig{symbol: symbol, token: token})
+	}
+}
+
+// agentsFromEnv parses ST_AGENTS, a comma separated list of SYMBOL:TOKEN
+// pairs.
+func agentsFromEnv() ([]agentConfig, error) {
+	configs := []agentConfig{}
+	for _, pair := range strings.Split(viper.GetString("AGENTS"), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		symbol, token, ok := strings.Cut(pair, ":")
+		if !ok || symbol == "" || token == "" {
+			return nil, fmt.Errorf("ST_AGENTS: expected SYMBOL:TOKEN, got %q", pair)
+		}
+		configs = append(configs, agentConfig{symbol: symbol, token: token})
+	}
+	return configs, nil
+}
+
+// newAgent builds an agent's client and loads its fleet from r. A token
+// stored in r by a re-registration wins over the configured one, which went
+// stale with the reset.
+func newAgent(cfg agentConfig, r *repo.Repo, clk clock.Clock) (*Agent, error) {
+	if cfg.symbol != "" {
+		stored, err := r.GetAgentToken(cfg.symbol)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get token for agent %s", cfg.symbol)
+		}
+		if stored != "" {
+			cfg.token = stored
+		}
+	}
+
+	var invoker api.Invoker
+	var err error
+	if cfg.token == "" {
+		invoker, err = client.GetClient()
+	} else {
+		invoker, err = client.NewClient(cfg.symbol, client.TokenProvider{Agent: cfg.token})
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create client for agent %s", cfg.symbol)
+	}
+
+	state := NewState()
+	agent := &Agent{
+		Symbol: cfg.symbol,
+		client: NewStateInvoker(client.NewCachingInvoker(invoker, cfg.symbol, r), state),
+		base:   invoker,
+		state:  state,
+		sched: scheduler.New(clk, viper.GetInt("TICK_WORKERS"), func() float64 {
+			return client.RateLimitPressure(invoker)
+		}),
+	}
+
+	if cfg.symbol == "" {
+		return agent, nil
+	}
+
+	ships, err := r.GetAgentFleet(cfg.symbol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get fleet for agent %s", cfg.symbol)
+	}
+	// the stored fleet until the first api response replaces it
+	for _, s := range ships {
+		state.UpdateShip(s)
+	}
+
+	return agent, nil
+}
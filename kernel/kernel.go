@@ -1,6 +1,7 @@
 package kernel
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -10,8 +11,12 @@ import (
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/bot"
 	"github.com/bwiggs/spacetraders-go/client"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/bwiggs/spacetraders-go/events"
 	"github.com/bwiggs/spacetraders-go/repo"
 	"github.com/bwiggs/spacetraders-go/tasks"
+	"github.com/bwiggs/spacetraders-go/telemetry"
+	"github.com/bwiggs/spacetraders-go/world"
 	"github.com/lmittmann/tint"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
@@ -29,21 +34,44 @@ func init() {
 }
 
 type Kernel struct {
-	logger *slog.Logger
-	client api.Invoker
-	repo   *repo.Repo
-	state  *State
+	logger       *slog.Logger
+	dbPath       string
+	repo         *repo.Repo
+	world        *world.World
+	bus          *events.Bus
+	supervisor   *Supervisor
+	clock        clock.Clock
+	agents       []*Agent
+	agentConfigs []agentConfig
+
+	// ctx is cancelled on Stop, ending every ship
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// stopRecordingTicks stores the tick traces still queued and stops
+	stopRecordingTicks func()
+
+	stopTracing func(context.Context) error
 }
 
-func New() (*Kernel, error) {
+// shutdownTimeout bounds how long Stop waits for ships to finish the api
+// calls they have in flight.
+const shutdownTimeout = 30 * time.Second
 
-	logger := slog.Default()
+type Option func(*Kernel)
 
-	client, err := client.GetClient()
-	if err != nil {
-		logger.Error("failed to create client", "err", err)
-		return nil, err
+// WithClock runs ships and tasks on clk instead of the wall clock.
+func WithClock(clk clock.Clock) Option {
+	return func(k *Kernel) {
+		k.clock = clk
 	}
+}
+
+// New builds a kernel for the agents in ST_AGENTS and any given WithAgent,
+// or for the single ST_AGENT agent using ST_API_TOKEN when there are none.
+func New(opts ...Option) (*Kernel, error) {
+
+	logger := slog.Default()
 
 	dburl := viper.GetString("DB")
 	logger.Info("connecting to data repo: " + dburl)
@@ -52,86 +80,181 @@ func New() (*Kernel, error) {
 		slog.Error(err.Error())
 		return nil, err
 	}
+	if err := r.Migrate(); err != nil {
+		logger.Error("failed to migrate data repo", "err", err)
+		return nil, err
+	}
 
-	ships, err := r.GetFleet()
+	w, err := world.Load(r)
 	if err != nil {
-		slog.Error(errors.Wrap(err, "failed to get fleet").Error())
+		logger.Error("failed to load world", "err", err)
 		return nil, err
 	}
 
-	shipsBySymbol := make(map[string]*api.Ship)
-	for _, s := range ships {
-		shipsBySymbol[s.Symbol] = s
+	stopTracing, err := telemetry.Setup(context.Background())
+	if err != nil {
+		logger.Error("failed to set up tracing", "err", err)
+		return nil, err
+	}
+
+	configs, err := agentsFromEnv()
+	if err != nil {
+		logger.Error("failed to read agents", "err", err)
+		return nil, err
+	}
+
+	k := &Kernel{
+		dbPath:       dburl,
+		repo:         r,
+		world:        w,
+		bus:          events.NewBus(),
+		logger:       logger,
+		clock:        clock.Real(),
+		agentConfigs: configs,
+		stopTracing:  stopTracing,
+	}
+	k.ctx, k.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(k)
+	}
+
+	k.supervisor = NewSupervisor(k.clock, k.bus, logger)
+	client.APIBreaker().SetClock(k.clock)
+
+	if len(k.agentConfigs) == 0 {
+		k.agentConfigs = []agentConfig{{symbol: viper.GetString("AGENT")}}
+	}
+
+	for _, cfg := range k.agentConfigs {
+		agent, err := newAgent(cfg, r, k.clock)
+		if err != nil {
+			logger.Error("failed to create agent", "agent", cfg.symbol, "err", err)
+			return nil, err
+		}
+		agent.sched.Recover = k.supervisor.Recover
+		k.agents = append(k.agents, agent)
 	}
 
-	return &Kernel{
-		client: client,
-		repo:   r,
-		logger: logger,
-		state:  NewState(),
-	}, nil
+	return k, nil
 }
 
 func (k *Kernel) Logger() *slog.Logger {
 	return k.logger
 }
 
+// Client returns the first agent's client.
 func (k *Kernel) Client() api.Invoker {
-	return k.client
+	return k.agents[0].client
 }
 
 func (k *Kernel) Repo() *repo.Repo {
 	return k.repo
 }
 
+// World returns the in-memory systems, waypoints and markets shared by every
+// agent.
+func (k *Kernel) World() *world.World {
+	return k.world
+}
+
+// Bus returns the bus ships and tasks publish their domain events to.
+func (k *Kernel) Bus() *events.Bus {
+	return k.bus
+}
+
+// Supervisor returns the supervisor recovering every agent's ships.
+func (k *Kernel) Supervisor() *Supervisor {
+	return k.supervisor
+}
+
+// State returns the first agent's state.
 func (k *Kernel) State() *State {
-	return k.state
+	return k.agents[0].state
+}
+
+func (k *Kernel) Agents() []*Agent {
+	return k.agents
+}
+
+func (k *Kernel) Clock() clock.Clock {
+	return k.clock
 }
 
 func (k *Kernel) Start() error {
-	tasks.Start()
-	go k.initBackgroundTasks(k.client)
+	tasks.Start(k.clock, k.bus)
+	k.stopRecordingTicks = k.recordTicks()
 
-	bot.Start(k.client, k.repo)
+	// shared market data only needs scanning once, with the first agent
+	go k.initBackgroundTasks(k.agents[0].client)
+	go k.watchResets()
+
+	for _, a := range k.agents {
+		go k.initAgentTasks(a)
+		a.sched.Start()
+		bot.Start(k.ctx, a.client, a.state, k.repo, k.world, k.bus, a.sched)
+	}
 	return nil
 }
 
+// Stop lets every ship finish the api call it has in flight, so its result
+// is recorded, before closing the repo.
 func (k *Kernel) Stop() error {
 	k.logger.Info("cleaning up")
+	k.stopShips()
 	tasks.Stop()
 	k.repo.Close()
+	for _, a := range k.agents {
+		if err := client.Close(a.base); err != nil {
+			k.logger.Error("failed to close client", "agent", a.Symbol, "err", err)
+		}
+	}
+	if err := k.stopTracing(context.Background()); err != nil {
+		k.logger.Error("failed to flush traces", "err", err)
+	}
 	return nil
 }
 
-func (k *Kernel) initBackgroundTasks(client api.Invoker) error {
-	tasks.SetInterval(func() {
-		tasks.LogAgentMetrics(client)
+// stopShips stops every agent's ships and waits, up to shutdownTimeout, for
+// their ticks in progress to finish and be recorded.
+func (k *Kernel) stopShips() {
+	k.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	for _, a := range k.agents {
+		if err := a.sched.Stop(ctx); err != nil {
+			k.logger.Warn("gave up waiting for ships to stop", "agent", a.Symbol, "err", err)
+		}
+	}
+	if k.stopRecordingTicks != nil {
+		k.stopRecordingTicks()
+	}
+}
+
+func (k *Kernel) initAgentTasks(a *Agent) {
+	tasks.SetInterval("LogAgentMetrics", func(ctx context.Context) {
+		tasks.LogAgentMetrics(ctx, a.client)
 	}, 1*time.Minute)
 
-	// tasks.SetInterval(func() {
-	// 	tasks.UpdateFleet(client, k.repo)
-	// 	ships, err := k.repo.GetFleet()
-	// 	if err != nil {
-	// 		slog.Error(errors.Wrap(err, "failed to get fleet").Error())
-	// 	}
-	// 	k.state.ShipsBySymbol = make(map[string]*api.Ship)
-	// 	k.state.Ships = []*api.Ship{}
-	// 	for _, s := range ships {
-	// 		k.state.ShipsBySymbol[s.Symbol] = &s
-	// 		k.state.Ships = append(k.state.Ships, &s)
-	// 	}
-
-	// }, 30*time.Second)
+	// every ship response already updates the agent's state, this catches
+	// anything done outside the bot and keeps the stored fleet fresh
+	tasks.SetInterval("UpdateFleet", func(ctx context.Context) {
+		if err := tasks.UpdateFleet(ctx, a.client, k.repo); err != nil {
+			slog.Error(errors.Wrap(err, "failed to update fleet").Error(), "agent", a.Symbol)
+		}
+	}, 5*time.Minute)
+}
 
+func (k *Kernel) initBackgroundTasks(client api.Invoker) error {
 	ScanMarkets := false
 	if ScanMarkets {
-		tasks.SetInterval(func() {
+		tasks.SetInterval("ScanMarkets", func(ctx context.Context) {
 			slog.Info("task: scanning markets and shipyards")
-			err := tasks.ScanMarkets(client, k.repo, viper.GetString("SYSTEM"))
+			err := tasks.ScanMarkets(ctx, client, k.repo, viper.GetString("SYSTEM"))
 			if err != nil {
 				slog.Error(errors.Wrap(err, "failed to scan markets").Error())
 			}
-			err = tasks.ScanShipyards(client, k.repo, viper.GetString("SYSTEM"))
+			err = tasks.ScanShipyards(ctx, client, k.repo, viper.GetString("SYSTEM"))
 			if err != nil {
 				slog.Error(errors.Wrap(err, "failed to scan shipyards").Error())
 			}
@@ -0,0 +1,280 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/tasks"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// resetCheckInterval is how often the server's reset date is polled.
+const resetCheckInterval = 5 * time.Minute
+
+// watchResets polls the server status and rebuilds everything when the
+// server has been reset since the database was built.
+func (k *Kernel) watchResets() {
+	tasks.SetInterval("CheckReset", func(ctx context.Context) {
+		if err := k.checkReset(ctx); err != nil {
+			k.logger.Error(errors.Wrap(err, "reset check failed").Error())
+		}
+	}, resetCheckInterval)
+}
+
+func (k *Kernel) checkReset(ctx context.Context) error {
+	status, err := k.agents[0].client.GetStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := k.repo.GetResetDate()
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		// the first check on a database records which reset it belongs to,
+		// unless its agents are already gone from the server
+		stale, err := k.predatesReset(ctx)
+		if err != nil {
+			return err
+		}
+		if !stale {
+			return k.repo.AddResetDate(status.ResetDate)
+		}
+		current = "before-" + status.ResetDate
+	} else if current == status.ResetDate {
+		return nil
+	}
+
+	k.logger.Warn("server reset detected", "was", current, "now", status.ResetDate, "next", status.ServerResets.Next)
+
+	// the cached systems and waypoints belong to the old universe
+	client.StaticCache().Flush()
+
+	// the fresh database is built beside the current one while the ships
+	// keep running, so a failure leaves everything as it was and the next
+	// check picks up where this one stopped
+	fresh, err := k.rebuild(ctx, status.ResetDate)
+	if err != nil {
+		return errors.Wrap(err, "failed to rebuild after reset")
+	}
+
+	// the ships are gone with the old universe, let them finish before the
+	// repo closes
+	k.stopShips()
+	k.repo.Close()
+
+	// past here there's no repo or ships left to carry on with, a failure
+	// exits so a supervisor starts over against whichever database is in
+	// place
+	archive, err := swapDB(k.dbPath, fresh, current)
+	if err != nil {
+		k.logger.Error("failed to swap in the rebuilt database, exiting", "err", err)
+		os.Exit(1)
+	}
+	k.logger.Info("archived database", "path", archive)
+
+	k.logger.Info("rebuilt after reset, restarting")
+	if err := restart(); err != nil {
+		k.logger.Error("failed to restart, exiting", "err", err)
+		os.Exit(1)
+	}
+	return nil
+}
+
+// predatesReset reports whether the database was built before the current
+// reset, i.e. the server no longer knows the agents stored in it.
+func (k *Kernel) predatesReset(ctx context.Context) (bool, error) {
+	agents, err := k.repo.GetAgents()
+	if err != nil || len(agents) == 0 {
+		return false, err
+	}
+
+	_, err = k.agents[0].client.GetAgent(ctx, api.GetAgentParams{AgentSymbol: agents[0].Symbol})
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return true, nil
+	}
+	return false, err
+}
+
+// rebuild builds the database for resetDate beside the current one, e.g.
+// db/spacetraders.rebuild-2025-05-11.db, and re-registers every configured
+// agent in it. It can be run again after failing part way: agents already
+// registered keep their stored token, as the server won't register them
+// twice. The reset date is stored last, marking the database complete.
+func (k *Kernel) rebuild(ctx context.Context, resetDate string) (string, error) {
+	symbols := []string{}
+	for _, cfg := range k.agentConfigs {
+		if cfg.symbol != "" {
+			symbols = append(symbols, cfg.symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return "", errors.New("no agent symbols to register, set ST_AGENT or ST_AGENTS")
+	}
+
+	path := rebuildPath(k.dbPath, resetDate)
+	r, err := repo.NewRepo(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	if err := r.Migrate(); err != nil {
+		return "", err
+	}
+
+	viper.SetDefault("FACTION", string(api.FactionSymbolCOSMIC))
+	faction := api.FactionSymbol(viper.GetString("FACTION"))
+
+	for _, symbol := range symbols {
+		token, err := r.GetAgentToken(symbol)
+		if err != nil {
+			return "", err
+		}
+		if token == "" {
+			if token, err = register(ctx, r, k.agents[0].base, symbol, faction); err != nil {
+				return "", errors.Wrapf(err, "failed to register %s", symbol)
+			}
+			k.logger.Info("registered agent", "agent", symbol, "faction", faction)
+		}
+		if err := load(ctx, r, k.agents[0].base, symbol, token); err != nil {
+			return "", errors.Wrapf(err, "failed to load %s", symbol)
+		}
+	}
+
+	if err := r.AddResetDate(resetDate); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// register creates symbol on the fresh server and stores its token. It goes
+// through base's transport, sharing the running agents' rate limit budget.
+func register(ctx context.Context, r *repo.Repo, base api.Invoker, symbol string, faction api.FactionSymbol) (string, error) {
+	account, err := client.WithTokens(base, client.TokenProvider{})
+	if err != nil {
+		return "", err
+	}
+	defer client.Close(account)
+
+	res, err := account.Register(ctx, api.NewOptRegisterReq(api.RegisterReq{
+		Symbol:  symbol,
+		Faction: faction,
+	}))
+	if err != nil {
+		return "", err
+	}
+
+	token := res.Data.Token
+	return token, r.UpsertAgentToken(symbol, string(faction), token)
+}
+
+// load stores a registered agent and its fleet, and scans its headquarters
+// system. Everything it stores is upserted, so it's safe to run again. Like
+// register it goes through base's transport.
+func load(ctx context.Context, r *repo.Repo, base api.Invoker, symbol, token string) error {
+	agent, err := client.WithTokens(base, client.TokenProvider{Agent: token})
+	if err != nil {
+		return err
+	}
+	defer client.Close(agent)
+
+	res, err := agent.GetMyAgent(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.UpsertAgents([]api.Agent{res.Data}); err != nil {
+		return err
+	}
+	if err := tasks.UpdateFleet(ctx, agent, r); err != nil {
+		return err
+	}
+	return tasks.ScanSystem(ctx, agent, r, waypointSystem(res.Data.Headquarters))
+}
+
+// dbFile splits a database path into its file and any sqlite dsn options.
+func dbFile(path string) (string, string) {
+	file := strings.TrimPrefix(path, "file:")
+	file, query, _ := strings.Cut(file, "?")
+	return file, query
+}
+
+// rebuildPath is where the database for resetDate is built, with path's
+// dsn options.
+func rebuildPath(path, resetDate string) string {
+	file, query := dbFile(path)
+	ext := filepath.Ext(file)
+	rebuild := fmt.Sprintf("%s.rebuild-%s%s", strings.TrimSuffix(file, ext), resetDate, ext)
+	if query != "" {
+		rebuild += "?" + query
+	}
+	return rebuild
+}
+
+// swapDB archives the database at path for the previous reset and moves the
+// fresh one built at rebuild into its place.
+func swapDB(path, rebuild, previous string) (string, error) {
+	archive, err := archiveDB(path, previous)
+	if err != nil {
+		return "", err
+	}
+
+	file, _ := dbFile(path)
+	fresh, _ := dbFile(rebuild)
+	if err := renameDB(fresh, file); err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// renameDB moves a database file and any sqlite sidecar files.
+func renameDB(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	for _, sidecar := range []string{"-wal", "-shm"} {
+		if err := os.Rename(from+sidecar, to+sidecar); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveDB moves the database file, and any sqlite sidecar files, aside as
+// e.g. db/spacetraders-2025-04-27.db.
+func archiveDB(path, resetDate string) (string, error) {
+	file, _ := dbFile(path)
+
+	ext := filepath.Ext(file)
+	archive := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(file, ext), resetDate, ext)
+	if _, err := os.Stat(archive); err == nil {
+		return "", fmt.Errorf("archive %s already exists", archive)
+	}
+
+	if err := renameDB(file, archive); err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// waypointSystem returns the system of a waypoint symbol, X1-AB12-A1 is in
+// X1-AB12.
+func waypointSystem(waypoint string) string {
+	i := strings.LastIndex(waypoint, "-")
+	if i < 0 {
+		return waypoint
+	}
+	return waypoint[:i]
+}
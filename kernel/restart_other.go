@@ -0,0 +1,12 @@
+//go:build !unix
+
+package kernel
+
+import "os"
+
+// restart exits so a supervisor can start a fresh process, there's no exec to
+// replace this one with.
+func restart() error {
+	os.Exit(1)
+	return nil
+}
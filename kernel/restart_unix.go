@@ -0,0 +1,18 @@
+//go:build unix
+
+package kernel
+
+import (
+	"os"
+	"syscall"
+)
+
+// restart replaces the process with a fresh copy of itself, so every agent,
+// mission and task starts over against the rebuilt database.
+func restart() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
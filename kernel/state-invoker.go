@@ -0,0 +1,390 @@
+package kernel
+
+import (
+	"context"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+// StateInvoker keeps a State current from the responses of every call that
+// returns nav, fuel, cargo, cooldown, agent or contract data. It sits in the
+// kernel rather than the client package so State doesn't need exporting
+// setters; every other operation is passed through untouched.
+type StateInvoker struct {
+	api.Invoker
+
+	state *State
+}
+
+func NewStateInvoker(next api.Invoker, state *State) *StateInvoker {
+	return &StateInvoker{Invoker: next, state: state}
+}
+
+// ships
+
+func (i *StateInvoker) GetMyShip(ctx context.Context, params api.GetMyShipParams) (*api.GetMyShipOK, error) {
+	res, err := i.Invoker.GetMyShip(ctx, params)
+	if err == nil {
+		i.state.UpdateShip(&res.Data)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) GetMyShips(ctx context.Context, params api.GetMyShipsParams) (*api.GetMyShipsOK, error) {
+	res, err := i.Invoker.GetMyShips(ctx, params)
+	if err == nil {
+		for j := range res.Data {
+			i.state.UpdateShip(&res.Data[j])
+		}
+	}
+	return res, err
+}
+
+func (i *StateInvoker) PurchaseShip(ctx context.Context, request api.OptPurchaseShipReq) (*api.PurchaseShipCreated, error) {
+	res, err := i.Invoker.PurchaseShip(ctx, request)
+	if err == nil {
+		i.state.UpdateShip(&res.Data.Ship)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) RepairShip(ctx context.Context, params api.RepairShipParams) (*api.RepairShipOK, error) {
+	res, err := i.Invoker.RepairShip(ctx, params)
+	if err == nil {
+		i.state.UpdateShip(&res.Data.Ship)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) ScrapShip(ctx context.Context, params api.ScrapShipParams) (*api.ScrapShipOK, error) {
+	res, err := i.Invoker.ScrapShip(ctx, params)
+	if err == nil {
+		i.state.removeShip(params.ShipSymbol)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+// nav and fuel
+
+func (i *StateInvoker) GetShipNav(ctx context.Context, params api.GetShipNavParams) (*api.GetShipNavOK, error) {
+	res, err := i.Invoker.GetShipNav(ctx, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) DockShip(ctx context.Context, params api.DockShipParams) (*api.DockShipOK, error) {
+	res, err := i.Invoker.DockShip(ctx, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data.Nav)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) OrbitShip(ctx context.Context, params api.OrbitShipParams) (*api.OrbitShipOK, error) {
+	res, err := i.Invoker.OrbitShip(ctx, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data.Nav)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) NavigateShip(ctx context.Context, request api.OptNavigateShipReq, params api.NavigateShipParams) (*api.NavigateShipOK, error) {
+	res, err := i.Invoker.NavigateShip(ctx, request, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data.Nav)
+		i.state.updateFuel(params.ShipSymbol, res.Data.Fuel)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) PatchShipNav(ctx context.Context, request api.OptPatchShipNavReq, params api.PatchShipNavParams) (*api.PatchShipNavOK, error) {
+	res, err := i.Invoker.PatchShipNav(ctx, request, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data.Nav)
+		i.state.updateFuel(params.ShipSymbol, res.Data.Fuel)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) WarpShip(ctx context.Context, request api.OptWarpShipReq, params api.WarpShipParams) (*api.WarpShipOK, error) {
+	res, err := i.Invoker.WarpShip(ctx, request, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data.Nav)
+		i.state.updateFuel(params.ShipSymbol, res.Data.Fuel)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) JumpShip(ctx context.Context, request api.OptJumpShipReq, params api.JumpShipParams) (*api.JumpShipOK, error) {
+	res, err := i.Invoker.JumpShip(ctx, request, params)
+	if err == nil {
+		i.state.updateNav(params.ShipSymbol, res.Data.Nav)
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) RefuelShip(ctx context.Context, request api.OptRefuelShipReq, params api.RefuelShipParams) (*api.RefuelShipOK, error) {
+	res, err := i.Invoker.RefuelShip(ctx, request, params)
+	if err == nil {
+		i.state.updateFuel(params.ShipSymbol, res.Data.Fuel)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+// cargo
+
+func (i *StateInvoker) GetMyShipCargo(ctx context.Context, params api.GetMyShipCargoParams) (*api.GetMyShipCargoOK, error) {
+	res, err := i.Invoker.GetMyShipCargo(ctx, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) PurchaseCargo(ctx context.Context, request api.OptPurchaseCargoReq, params api.PurchaseCargoParams) (*api.PurchaseCargoCreated, error) {
+	res, err := i.Invoker.PurchaseCargo(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) SellCargo(ctx context.Context, request api.OptSellCargoReq, params api.SellCargoParams) (*api.SellCargoCreated, error) {
+	res, err := i.Invoker.SellCargo(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) Jettison(ctx context.Context, request api.OptJettisonReq, params api.JettisonParams) (*api.JettisonOK, error) {
+	res, err := i.Invoker.Jettison(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) TransferCargo(ctx context.Context, request api.OptTransferCargoReq, params api.TransferCargoParams) (*api.TransferCargoOK, error) {
+	res, err := i.Invoker.TransferCargo(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		if req, ok := request.Get(); ok {
+			i.state.addCargo(req.ShipSymbol, req.TradeSymbol, req.Units)
+		}
+	}
+	return res, err
+}
+
+func (i *StateInvoker) SupplyConstruction(ctx context.Context, request api.OptSupplyConstructionReq, params api.SupplyConstructionParams) (*api.SupplyConstructionCreated, error) {
+	res, err := i.Invoker.SupplyConstruction(ctx, request, params)
+	if err == nil {
+		if req, ok := request.Get(); ok {
+			i.state.updateCargo(req.ShipSymbol, res.Data.Cargo)
+		}
+	}
+	return res, err
+}
+
+// cooldowns
+
+func (i *StateInvoker) GetShipCooldown(ctx context.Context, params api.GetShipCooldownParams) (api.GetShipCooldownRes, error) {
+	res, err := i.Invoker.GetShipCooldown(ctx, params)
+	if ok, isOK := res.(*api.GetShipCooldownOK); err == nil && isOK {
+		i.state.updateCooldown(params.ShipSymbol, ok.Data)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) ExtractResources(ctx context.Context, request api.OptExtractResourcesReq, params api.ExtractResourcesParams) (*api.ExtractResourcesCreated, error) {
+	res, err := i.Invoker.ExtractResources(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) ExtractResourcesWithSurvey(ctx context.Context, request api.OptSurvey, params api.ExtractResourcesWithSurveyParams) (*api.ExtractResourcesWithSurveyCreated, error) {
+	res, err := i.Invoker.ExtractResourcesWithSurvey(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) SiphonResources(ctx context.Context, params api.SiphonResourcesParams) (*api.SiphonResourcesCreated, error) {
+	res, err := i.Invoker.SiphonResources(ctx, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) ShipRefine(ctx context.Context, request api.OptShipRefineReq, params api.ShipRefineParams) (*api.ShipRefineCreated, error) {
+	res, err := i.Invoker.ShipRefine(ctx, request, params)
+	if err == nil {
+		i.state.updateCargo(params.ShipSymbol, res.Data.Cargo)
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) CreateSurvey(ctx context.Context, params api.CreateSurveyParams) (*api.CreateSurveyCreated, error) {
+	res, err := i.Invoker.CreateSurvey(ctx, params)
+	if err == nil {
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) CreateShipShipScan(ctx context.Context, params api.CreateShipShipScanParams) (*api.CreateShipShipScanCreated, error) {
+	res, err := i.Invoker.CreateShipShipScan(ctx, params)
+	if err == nil {
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) CreateShipSystemScan(ctx context.Context, params api.CreateShipSystemScanParams) (*api.CreateShipSystemScanCreated, error) {
+	res, err := i.Invoker.CreateShipSystemScan(ctx, params)
+	if err == nil {
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) CreateShipWaypointScan(ctx context.Context, params api.CreateShipWaypointScanParams) (*api.CreateShipWaypointScanCreated, error) {
+	res, err := i.Invoker.CreateShipWaypointScan(ctx, params)
+	if err == nil {
+		i.state.updateCooldown(params.ShipSymbol, res.Data.Cooldown)
+	}
+	return res, err
+}
+
+// mounts and modules
+
+func (i *StateInvoker) InstallMount(ctx context.Context, request api.OptInstallMountReq, params api.InstallMountParams) (*api.InstallMountCreated, error) {
+	res, err := i.Invoker.InstallMount(ctx, request, params)
+	if err == nil {
+		i.state.EditShip(params.ShipSymbol, func(ship *api.Ship) {
+			ship.Mounts = res.Data.Mounts
+			ship.Cargo = res.Data.Cargo
+		})
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) RemoveMount(ctx context.Context, request api.OptRemoveMountReq, params api.RemoveMountParams) (*api.RemoveMountCreated, error) {
+	res, err := i.Invoker.RemoveMount(ctx, request, params)
+	if err == nil {
+		i.state.EditShip(params.ShipSymbol, func(ship *api.Ship) {
+			ship.Mounts = res.Data.Mounts
+			ship.Cargo = res.Data.Cargo
+		})
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) InstallShipModule(ctx context.Context, request api.OptInstallShipModuleReq, params api.InstallShipModuleParams) (*api.InstallShipModuleCreated, error) {
+	res, err := i.Invoker.InstallShipModule(ctx, request, params)
+	if err == nil {
+		i.state.EditShip(params.ShipSymbol, func(ship *api.Ship) {
+			ship.Modules = res.Data.Modules
+			ship.Cargo = res.Data.Cargo
+		})
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) RemoveShipModule(ctx context.Context, request api.OptRemoveShipModuleReq, params api.RemoveShipModuleParams) (*api.RemoveShipModuleCreated, error) {
+	res, err := i.Invoker.RemoveShipModule(ctx, request, params)
+	if err == nil {
+		i.state.EditShip(params.ShipSymbol, func(ship *api.Ship) {
+			ship.Modules = res.Data.Modules
+			ship.Cargo = res.Data.Cargo
+		})
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+// agent and contracts
+
+func (i *StateInvoker) GetMyAgent(ctx context.Context) (*api.GetMyAgentOK, error) {
+	res, err := i.Invoker.GetMyAgent(ctx)
+	if err == nil {
+		i.state.updateAgent(res.Data)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) GetContract(ctx context.Context, params api.GetContractParams) (*api.GetContractOK, error) {
+	res, err := i.Invoker.GetContract(ctx, params)
+	if err == nil {
+		i.state.updateContract(res.Data)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) GetContracts(ctx context.Context, params api.GetContractsParams) (*api.GetContractsOK, error) {
+	res, err := i.Invoker.GetContracts(ctx, params)
+	if err == nil {
+		for _, c := range res.Data {
+			i.state.updateContract(c)
+		}
+	}
+	return res, err
+}
+
+func (i *StateInvoker) NegotiateContract(ctx context.Context, params api.NegotiateContractParams) (*api.NegotiateContractCreated, error) {
+	res, err := i.Invoker.NegotiateContract(ctx, params)
+	if err == nil {
+		i.state.updateContract(res.Data.Contract)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) AcceptContract(ctx context.Context, params api.AcceptContractParams) (*api.AcceptContractOK, error) {
+	res, err := i.Invoker.AcceptContract(ctx, params)
+	if err == nil {
+		i.state.updateContract(res.Data.Contract)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
+
+func (i *StateInvoker) DeliverContract(ctx context.Context, request api.OptDeliverContractReq, params api.DeliverContractParams) (*api.DeliverContractOK, error) {
+	res, err := i.Invoker.DeliverContract(ctx, request, params)
+	if err == nil {
+		i.state.updateContract(res.Data.Contract)
+		if req, ok := request.Get(); ok {
+			i.state.updateCargo(req.ShipSymbol, res.Data.Cargo)
+		}
+	}
+	return res, err
+}
+
+func (i *StateInvoker) FulfillContract(ctx context.Context, params api.FulfillContractParams) (*api.FulfillContractOK, error) {
+	res, err := i.Invoker.FulfillContract(ctx, params)
+	if err == nil {
+		i.state.updateContract(res.Data.Contract)
+		i.state.updateAgent(res.Data.Agent)
+	}
+	return res, err
+}
@@ -1,31 +1,253 @@
 package kernel
 
-import "github.com/bwiggs/spacetraders-go/api"
+import (
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
 
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+// State is an agent's ships, contracts and agent record as the api last
+// reported them. StateInvoker keeps it current from every response that
+// carries any of them, so it's as fresh as the latest call any ship or task
+// made. It's the ships' only copy of themselves, each reads its nav, fuel,
+// cargo and cooldown from it. Systems and waypoints live in the kernel's
+// World.
+//
+// State is safe for concurrent use. Readers get copies and can subscribe to
+// be told about changes.
 type State struct {
-	ShipsBySymbol map[string]*api.Ship
-	Ships         []*api.Ship
-	Systems       map[string]*api.System
-	Waypoints     map[string]*api.Waypoint
-	Contracts     map[string]*api.Contract
+	mu        sync.RWMutex
+	agent     *api.Agent
+	ships     map[string]*api.Ship
+	contracts map[string]*api.Contract
+
+	subsMu sync.Mutex
+	nextID int
+	subs   map[int]chan StateEvent
+}
+
+// StateEventKind is what changed.
+type StateEventKind int
+
+const (
+	ShipChanged StateEventKind = iota
+	ShipRemoved
+	AgentChanged
+	ContractChanged
+)
+
+func (k StateEventKind) String() string {
+	switch k {
+	case ShipChanged:
+		return "ship"
+	case ShipRemoved:
+		return "ship removed"
+	case AgentChanged:
+		return "agent"
+	case ContractChanged:
+		return "contract"
+	}
+	return "unknown"
+}
+
+// StateEvent is published after a change is applied, Symbol is the ship's or
+// agent's symbol or the contract's id.
+type StateEvent struct {
+	Kind   StateEventKind
+	Symbol string
 }
 
 func NewState() *State {
 	return &State{
-		ShipsBySymbol: make(map[string]*api.Ship),
-		Ships:         make([]*api.Ship, 0),
-		Systems:       make(map[string]*api.System),
-		Waypoints:     make(map[string]*api.Waypoint),
-		Contracts:     make(map[string]*api.Contract),
+		ships:     make(map[string]*api.Ship),
+		contracts: make(map[string]*api.Contract),
+		subs:      make(map[int]chan StateEvent),
+	}
+}
+
+// Agent returns the agent record, if it's been seen yet.
+func (s *State) Agent() (api.Agent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.agent == nil {
+		return api.Agent{}, false
+	}
+	return *s.agent, true
+}
+
+// Ship returns the ship with symbol.
+func (s *State) Ship(symbol string) (api.Ship, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ship, ok := s.ships[symbol]
+	if !ok {
+		return api.Ship{}, false
 	}
+	return *ship, true
 }
 
+// Ships returns every ship, ordered by symbol.
+func (s *State) Ships() []api.Ship {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ships := make([]api.Ship, 0, len(s.ships))
+	for _, ship := range s.ships {
+		ships = append(ships, *ship)
+	}
+	sort.Slice(ships, func(i, j int) bool {
+		return ships[i].Symbol < ships[j].Symbol
+	})
+	return ships
+}
+
+// Contract returns the contract with id.
+func (s *State) Contract(id string) (api.Contract, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.contracts[id]
+	if !ok {
+		return api.Contract{}, false
+	}
+	return *c, true
+}
+
+// Contracts returns every contract, ordered by id.
+func (s *State) Contracts() []api.Contract {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contracts := make([]api.Contract, 0, len(s.contracts))
+	for _, c := range s.contracts {
+		contracts = append(contracts, *c)
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].ID < contracts[j].ID
+	})
+	return contracts
+}
+
+// UpdateShip stores a whole ship, e.g. from GetMyShip or the repo's fleet.
 func (s *State) UpdateShip(ship *api.Ship) {
-	s.ShipsBySymbol[ship.Symbol] = ship
-	for i := range s.Ships {
-		if s.Ships[i].Symbol == ship.Symbol {
-			s.Ships[i] = ship
-			break
+	stored := *ship
+	s.mu.Lock()
+	s.ships[ship.Symbol] = &stored
+	s.mu.Unlock()
+
+	s.publish(StateEvent{Kind: ShipChanged, Symbol: ship.Symbol})
+}
+
+// EditShip applies fn to a copy of the stored ship and stores the copy, so
+// snapshots already handed out never change underneath their readers. Ships
+// that haven't been seen whole yet are skipped.
+func (s *State) EditShip(symbol string, fn func(ship *api.Ship)) {
+	s.mu.Lock()
+	old, ok := s.ships[symbol]
+	if ok {
+		ship := *old
+		fn(&ship)
+		s.ships[symbol] = &ship
+	}
+	s.mu.Unlock()
+
+	if ok {
+		s.publish(StateEvent{Kind: ShipChanged, Symbol: symbol})
+	}
+}
+
+func (s *State) removeShip(symbol string) {
+	s.mu.Lock()
+	delete(s.ships, symbol)
+	s.mu.Unlock()
+
+	s.publish(StateEvent{Kind: ShipRemoved, Symbol: symbol})
+}
+
+func (s *State) updateNav(symbol string, nav api.ShipNav) {
+	s.EditShip(symbol, func(ship *api.Ship) { ship.Nav = nav })
+}
+
+func (s *State) updateFuel(symbol string, fuel api.ShipFuel) {
+	s.EditShip(symbol, func(ship *api.Ship) { ship.Fuel = fuel })
+}
+
+func (s *State) updateCargo(symbol string, cargo api.ShipCargo) {
+	s.EditShip(symbol, func(ship *api.Ship) { ship.Cargo = cargo })
+}
+
+func (s *State) updateCooldown(symbol string, cooldown api.Cooldown) {
+	s.EditShip(symbol, func(ship *api.Ship) { ship.Cooldown = cooldown })
+}
+
+// addCargo adds units of good to the ship's hold, for the receiving end of a
+// transfer, whose cargo isn't in the response.
+func (s *State) addCargo(symbol string, good api.TradeSymbol, units int) {
+	s.EditShip(symbol, func(ship *api.Ship) {
+		inventory := slices.Clone(ship.Cargo.Inventory)
+		i := slices.IndexFunc(inventory, func(item api.ShipCargoItem) bool {
+			return item.Symbol == good
+		})
+		if i < 0 {
+			inventory = append(inventory, api.ShipCargoItem{Symbol: good, Name: string(good)})
+			i = len(inventory) - 1
+		}
+		inventory[i].Units += units
+		ship.Cargo.Inventory = inventory
+		ship.Cargo.Units += units
+	})
+}
+
+func (s *State) updateAgent(agent api.Agent) {
+	s.mu.Lock()
+	s.agent = &agent
+	s.mu.Unlock()
+
+	s.publish(StateEvent{Kind: AgentChanged, Symbol: agent.Symbol})
+}
+
+func (s *State) updateContract(contract api.Contract) {
+	s.mu.Lock()
+	s.contracts[contract.ID] = &contract
+	s.mu.Unlock()
+
+	s.publish(StateEvent{Kind: ContractChanged, Symbol: contract.ID})
+}
+
+// Subscribe returns a channel receiving every later change, and a func to
+// stop receiving. Publishing never waits for a subscriber, events that don't
+// fit in its buffer are dropped.
+func (s *State) Subscribe(buffer int) (<-chan StateEvent, func()) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	ch := make(chan StateEvent, buffer)
+	s.subs[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.subsMu.Lock()
+			defer s.subsMu.Unlock()
+			delete(s.subs, id)
+			close(ch)
+		})
+	}
+}
+
+func (s *State) publish(e StateEvent) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	for _, ch := range s.subs {
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("state: subscriber is behind, dropping event", "kind", e.Kind, "symbol", e.Symbol)
 		}
 	}
 }
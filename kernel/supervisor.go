@@ -0,0 +1,119 @@
+package kernel
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/actors"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/bwiggs/spacetraders-go/events"
+	"github.com/bwiggs/spacetraders-go/scheduler"
+)
+
+// Supervisor recovers ship ticks that panic, so one bad ship doesn't take
+// down every agent. Each crash is logged with its stack and the ship's
+// blackboard, and the ship is restarted with a clean blackboard after a
+// backoff that doubles with every recent crash. A ship that keeps crashing is
+// quarantined on an idle mission and an alert is raised.
+type Supervisor struct {
+	// Backoff is the wait before restarting after a first crash.
+	Backoff time.Duration
+	// MaxBackoff caps the doubling backoff.
+	MaxBackoff time.Duration
+	// MaxCrashes within CrashWindow quarantines the ship.
+	MaxCrashes  int
+	CrashWindow time.Duration
+
+	clock  clock.Clock
+	bus    *events.Bus
+	logger *slog.Logger
+
+	mu          sync.Mutex
+	crashes     map[string][]time.Time
+	quarantined map[string]bool
+}
+
+func NewSupervisor(clk clock.Clock, bus *events.Bus, logger *slog.Logger) *Supervisor {
+	return &Supervisor{
+		Backoff:     5 * time.Second,
+		MaxBackoff:  5 * time.Minute,
+		MaxCrashes:  5,
+		CrashWindow: time.Hour,
+		clock:       clock.Or(clk),
+		bus:         bus,
+		logger:      logger,
+		crashes:     map[string][]time.Time{},
+		quarantined: map[string]bool{},
+	}
+}
+
+// Recover is a scheduler.Scheduler's Recover, it returns when to restart the
+// crashed task.
+func (s *Supervisor) Recover(t scheduler.Task, panicked any, stack []byte) time.Time {
+	now := s.clock.Now()
+	name := t.Name()
+
+	s.mu.Lock()
+	recent := []time.Time{}
+	for _, at := range s.crashes[name] {
+		if now.Sub(at) < s.CrashWindow {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	s.crashes[name] = recent
+	crashes := len(recent)
+	quarantine := crashes >= s.MaxCrashes && !s.quarantined[name]
+	if quarantine {
+		s.quarantined[name] = true
+	}
+	s.mu.Unlock()
+
+	ship, _ := t.(*actors.Ship)
+	blackboard := ""
+	if ship != nil {
+		blackboard = ship.Blackboard().String()
+	}
+
+	s.logger.Error("supervisor: recovered panicking ship",
+		"ship", name,
+		"panic", fmt.Sprint(panicked),
+		"crashes", crashes,
+		"blackboard", blackboard,
+		"stack", string(stack),
+	)
+	events.Publish(s.bus, events.ShipCrashed{Ship: name, Panic: fmt.Sprint(panicked), Stack: string(stack)})
+
+	if ship != nil && quarantine {
+		// swapped before the ship is ticked again, so the crashing mission
+		// doesn't get another go
+		ship.Quarantine(actors.NewIdleMission())
+		s.logger.Error("ALERT: ship quarantined after repeated crashes", "ship", name, "crashes", crashes, "window", s.CrashWindow)
+		events.Publish(s.bus, events.ShipQuarantined{Ship: name, Crashes: crashes})
+	} else if ship != nil {
+		ship.Restart()
+	}
+
+	backoff := s.Backoff
+	for i := 1; i < crashes && backoff < s.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return now.Add(min(backoff, s.MaxBackoff))
+}
+
+// Quarantined returns the ships parked after repeated crashes, ordered by
+// symbol.
+func (s *Supervisor) Quarantined() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ships := make([]string, 0, len(s.quarantined))
+	for name := range s.quarantined {
+		ships = append(ships, name)
+	}
+	sort.Strings(ships)
+	return ships
+}
@@ -0,0 +1,36 @@
+package kernel
+
+import (
+	"github.com/bwiggs/spacetraders-go/events"
+	"github.com/spf13/viper"
+)
+
+// defaultTickHistory is how many of each ship's most recent ticks are kept
+// when ST_TICK_HISTORY isn't set.
+const defaultTickHistory = 1000
+
+// recordTicks stores every ship's tick traces in the repo, trimmed to its
+// most recent ST_TICK_HISTORY. The returned func stops recording once the
+// traces already published are stored.
+func (k *Kernel) recordTicks() func() {
+	keep := viper.GetInt("TICK_HISTORY")
+	if keep <= 0 {
+		keep = defaultTickHistory
+	}
+
+	ticks, unsubscribe := events.Subscribe[events.ShipTicked](k.bus, 1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for t := range ticks {
+			if err := k.repo.InsertTickTrace(t.Trace, keep); err != nil {
+				k.logger.Warn("failed to record tick", "ship", t.Trace.Ship, "err", err)
+			}
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		<-done
+	}
+}
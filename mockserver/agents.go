@@ -0,0 +1,160 @@
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+type agent struct {
+	api.Agent
+	token     string
+	ships     []*ship
+	contracts []*contract
+}
+
+var agentSymbolPattern = regexp.MustCompile(`^[A-Z0-9_-]{3,14}$`)
+
+// register creates an agent at the headquarters of the next system, with the
+// same starting fleet and contract as the live game. Callers hold s.mu.
+func (s *Server) register(symbol string, faction api.FactionSymbol, token string) (*agent, error) {
+	if !agentSymbolPattern.MatchString(symbol) {
+		return nil, &Error{Status: http.StatusUnprocessableEntity, Code: codeValidation, Message: fmt.Sprintf("Agent symbol %s must be 3 to 14 uppercase characters.", symbol)}
+	}
+	for _, a := range s.agents {
+		if a.Symbol == symbol {
+			return nil, errConflict(codeAgentExists, map[string]any{"agentSymbol": symbol}, "Agent symbol %s has already been claimed.", symbol)
+		}
+	}
+	if faction == "" {
+		faction = api.FactionSymbolCOSMIC
+	}
+	if token == "" {
+		token = newToken()
+	}
+
+	hq := s.waypoints[s.systems[len(s.agents)%len(s.systems)].Symbol][0]
+
+	a := &agent{
+		Agent: api.Agent{
+			AccountId:       api.NewOptString(fmt.Sprintf("mock-%d", len(s.agents)+1)),
+			Symbol:          symbol,
+			Headquarters:    string(hq.Symbol),
+			Credits:         s.cfg.Credits,
+			StartingFaction: string(faction),
+		},
+		token: token,
+	}
+	s.agents = append(s.agents, a)
+	s.tokens[token] = a
+
+	s.addShip(a, api.ShipTypeSHIPCOMMANDFRIGATE, hq)
+	s.addShip(a, api.ShipTypeSHIPPROBE, hq)
+	s.newContract(a)
+
+	return a, nil
+}
+
+func (s *Server) Register(ctx context.Context, req api.OptRegisterReq) (*api.RegisterCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.register(req.Value.Symbol, req.Value.Faction, "")
+	if err != nil {
+		return nil, err
+	}
+
+	res := &api.RegisterCreated{Data: api.RegisterCreatedData{
+		Agent:    a.Agent,
+		Contract: a.contracts[0].Contract,
+		Faction: api.Faction{
+			Symbol:       req.Value.Faction,
+			Name:         string(req.Value.Faction),
+			Description:  "Mock faction.",
+			Headquarters: api.NewOptString(a.Headquarters),
+			Traits:       []api.FactionTrait{},
+			IsRecruiting: true,
+		},
+		Token: a.token,
+	}}
+	for _, sh := range a.ships {
+		res.Data.Ships = append(res.Data.Ships, s.shipView(sh))
+	}
+	return res, nil
+}
+
+func (s *Server) GetMyAgent(ctx context.Context) (*api.GetMyAgentOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &api.GetMyAgentOK{Data: agentFrom(ctx).Agent}, nil
+}
+
+func (s *Server) GetAgents(ctx context.Context, params api.GetAgentsParams) (*api.GetAgentsOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agents := make([]api.Agent, 0, len(s.agents))
+	for _, a := range s.agents {
+		public := a.Agent
+		public.AccountId = api.OptString{}
+		agents = append(agents, public)
+	}
+
+	data, meta, err := paginate(agents, params.Page, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetAgentsOK{Data: data, Meta: meta}, nil
+}
+
+func (s *Server) GetAgent(ctx context.Context, params api.GetAgentParams) (*api.GetAgentOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.agents {
+		if a.Symbol == params.AgentSymbol {
+			public := a.Agent
+			public.AccountId = api.OptString{}
+			return &api.GetAgentOK{Data: public}, nil
+		}
+	}
+	return nil, errNotFound("Agent", params.AgentSymbol)
+}
+
+func (s *Server) GetStatus(ctx context.Context) (*api.GetStatusOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waypoints := 0
+	for _, wps := range s.waypoints {
+		waypoints += len(wps)
+	}
+
+	return &api.GetStatusOK{
+		Status:      "SpaceTraders mock server is online.",
+		Version:     "mock",
+		ResetDate:   s.started.Format(time.DateOnly),
+		Description: "In-memory SpaceTraders universe for local development.",
+		Stats: api.GetStatusOKStats{
+			Agents:    len(s.agents),
+			Ships:     len(s.ships),
+			Systems:   len(s.systems),
+			Waypoints: waypoints,
+		},
+		Leaderboards: api.GetStatusOKLeaderboards{
+			MostCredits:         []api.GetStatusOKLeaderboardsMostCreditsItem{},
+			MostSubmittedCharts: []api.GetStatusOKLeaderboardsMostSubmittedChartsItem{},
+		},
+		ServerResets: api.GetStatusOKServerResets{
+			Next:      s.started.Add(7 * 24 * time.Hour).Format(time.RFC3339),
+			Frequency: "weekly",
+		},
+		Announcements: []api.GetStatusOKAnnouncementsItem{},
+		Links:         []api.GetStatusOKLinksItem{},
+	}, nil
+}
@@ -0,0 +1,236 @@
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+)
+
+const (
+	contractDeadline       = 7 * 24 * time.Hour
+	contractAcceptDeadline = 24 * time.Hour
+)
+
+type contract struct {
+	api.Contract
+	owner *agent
+}
+
+// newContract offers a a procurement contract for a good one of the markets in
+// its headquarters system imports. Callers hold s.mu.
+func (s *Server) newContract(a *agent) *contract {
+	type listing struct {
+		market string
+		good   *good
+	}
+	var imports []listing
+	for _, wp := range s.waypoints[systemOf(a.Headquarters)] {
+		m, ok := s.markets[string(wp.Symbol)]
+		if !ok {
+			continue
+		}
+		for _, g := range m.goods {
+			if g.typ == api.MarketTradeGoodTypeIMPORT {
+				imports = append(imports, listing{m.symbol, g})
+			}
+		}
+	}
+	pick := imports[s.rand.Intn(len(imports))]
+
+	units := 10 * (3 + s.rand.Intn(6))
+	value := units * pick.good.base
+	now := s.now()
+
+	c := &contract{owner: a, Contract: api.Contract{
+		ID:            fmt.Sprintf("mock%06x%04x", s.rand.Intn(1<<24), len(s.contracts)),
+		FactionSymbol: a.StartingFaction,
+		Type:          api.ContractTypePROCUREMENT,
+		Terms: api.ContractTerms{
+			Deadline: now.Add(contractDeadline),
+			Payment:  api.ContractPayment{OnAccepted: value / 4, OnFulfilled: value * 5 / 4},
+			Deliver: []api.ContractDeliverGood{{
+				TradeSymbol:       string(pick.good.symbol),
+				DestinationSymbol: pick.market,
+				UnitsRequired:     units,
+			}},
+		},
+		Expiration:       now.Add(contractAcceptDeadline),
+		DeadlineToAccept: api.NewOptDateTime(now.Add(contractAcceptDeadline)),
+	}}
+
+	a.contracts = append(a.contracts, c)
+	s.contracts[c.ID] = c
+	return c
+}
+
+func (s *Server) contract(a *agent, id string) (*contract, error) {
+	c, ok := s.contracts[id]
+	if !ok || c.owner != a {
+		return nil, errNotFound("Contract", id)
+	}
+	return c, nil
+}
+
+// requireOpen checks the contract can still be worked on.
+func (c *contract) requireOpen(now time.Time) error {
+	if c.Fulfilled {
+		return errBadRequest(client.CodeContractFulfilled, "Contract %s has already been fulfilled.", c.ID)
+	}
+	if !c.Accepted && now.After(c.Expiration) {
+		return errBadRequest(client.CodeContractDeadline, "Contract %s has expired.", c.ID)
+	}
+	if c.Accepted && now.After(c.Terms.Deadline) {
+		return errBadRequest(client.CodeContractDeadline, "Contract %s has missed its deadline.", c.ID)
+	}
+	return nil
+}
+
+func (s *Server) GetContracts(ctx context.Context, params api.GetContractsParams) (*api.GetContractsOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	contracts := make([]api.Contract, len(a.contracts))
+	for i, c := range a.contracts {
+		contracts[i] = c.Contract
+	}
+
+	data, meta, err := paginate(contracts, params.Page, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetContractsOK{Data: data, Meta: meta}, nil
+}
+
+func (s *Server) GetContract(ctx context.Context, params api.GetContractParams) (*api.GetContractOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.contract(agentFrom(ctx), params.ContractId)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetContractOK{Data: c.Contract}, nil
+}
+
+func (s *Server) AcceptContract(ctx context.Context, params api.AcceptContractParams) (*api.AcceptContractOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	c, err := s.contract(a, params.ContractId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.requireOpen(s.now()); err != nil {
+		return nil, err
+	}
+	if c.Accepted {
+		return nil, errBadRequest(codeValidation, "Contract %s has already been accepted.", c.ID)
+	}
+
+	c.Accepted = true
+	a.Credits += int64(c.Terms.Payment.OnAccepted)
+
+	return &api.AcceptContractOK{Data: api.AcceptContractOKData{Agent: a.Agent, Contract: c.Contract}}, nil
+}
+
+func (s *Server) DeliverContract(ctx context.Context, req api.OptDeliverContractReq, params api.DeliverContractParams) (*api.DeliverContractOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	c, err := s.contract(a, params.ContractId)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Accepted {
+		return nil, errBadRequest(client.CodeContractNotAccepted, "Contract %s has not been accepted.", c.ID)
+	}
+	if err := c.requireOpen(s.now()); err != nil {
+		return nil, err
+	}
+
+	sh, err := s.ship(a, req.Value.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireDocked(); err != nil {
+		return nil, err
+	}
+
+	var terms *api.ContractDeliverGood
+	for i, d := range c.Terms.Deliver {
+		if d.TradeSymbol == req.Value.TradeSymbol {
+			terms = &c.Terms.Deliver[i]
+		}
+	}
+	if terms == nil {
+		return nil, errBadRequest(codeValidation, "Contract %s does not require %s.", c.ID, req.Value.TradeSymbol)
+	}
+	if string(sh.Nav.WaypointSymbol) != terms.DestinationSymbol {
+		return nil, errBadRequest(codeValidation, "Contract %s requires delivery at %s.", c.ID, terms.DestinationSymbol)
+	}
+
+	units := min(req.Value.Units, terms.UnitsRequired-terms.UnitsFulfilled)
+	if err := sh.load(api.TradeSymbol(terms.TradeSymbol), -units); err != nil {
+		return nil, err
+	}
+	terms.UnitsFulfilled += units
+
+	return &api.DeliverContractOK{Data: api.DeliverContractOKData{Contract: c.Contract, Cargo: s.shipView(sh).Cargo}}, nil
+}
+
+func (s *Server) FulfillContract(ctx context.Context, params api.FulfillContractParams) (*api.FulfillContractOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	c, err := s.contract(a, params.ContractId)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Accepted {
+		return nil, errBadRequest(client.CodeContractNotAccepted, "Contract %s has not been accepted.", c.ID)
+	}
+	if err := c.requireOpen(s.now()); err != nil {
+		return nil, err
+	}
+	for _, d := range c.Terms.Deliver {
+		if d.UnitsFulfilled < d.UnitsRequired {
+			return nil, errBadRequest(codeValidation, "Contract %s still requires %d unit(s) of %s.", c.ID, d.UnitsRequired-d.UnitsFulfilled, d.TradeSymbol)
+		}
+	}
+
+	c.Fulfilled = true
+	a.Credits += int64(c.Terms.Payment.OnFulfilled)
+
+	return &api.FulfillContractOK{Data: api.FulfillContractOKData{Agent: a.Agent, Contract: c.Contract}}, nil
+}
+
+func (s *Server) NegotiateContract(ctx context.Context, params api.NegotiateContractParams) (*api.NegotiateContractCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	sh, err := s.ship(a, params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireDocked(); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	for _, c := range a.contracts {
+		if c.requireOpen(now) == nil {
+			return nil, errBadRequest(codeExistingContract, "Agent %s already has an active contract %s.", a.Symbol, c.ID)
+		}
+	}
+
+	c := s.newContract(a)
+	return &api.NegotiateContractCreated{Data: api.NegotiateContractCreatedData{Contract: c.Contract}}, nil
+}
@@ -0,0 +1,200 @@
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+)
+
+const (
+	extractCooldown = 70 * time.Second
+	surveyCooldown  = 60 * time.Second
+	surveyLifetime  = 15 * time.Minute
+)
+
+var surveySizes = []struct {
+	size        api.SurveySize
+	extractions int
+}{
+	{api.SurveySizeSMALL, 5},
+	{api.SurveySizeMODERATE, 10},
+	{api.SurveySizeLARGE, 20},
+}
+
+// survey tracks how many more extractions a survey is good for.
+type survey struct {
+	api.Survey
+	remaining int
+}
+
+var miningLasers = []api.ShipMountSymbol{
+	api.ShipMountSymbolMOUNTMININGLASERIII,
+	api.ShipMountSymbolMOUNTMININGLASERII,
+	api.ShipMountSymbolMOUNTMININGLASERI,
+}
+
+var surveyors = []api.ShipMountSymbol{
+	api.ShipMountSymbolMOUNTSURVEYORIII,
+	api.ShipMountSymbolMOUNTSURVEYORII,
+	api.ShipMountSymbolMOUNTSURVEYORI,
+}
+
+func (s *Server) CreateSurvey(ctx context.Context, params api.CreateSurveyParams) (*api.CreateSurveyCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	mount, ok := sh.mount(surveyors...)
+	if !ok {
+		return nil, errBadRequest(codeValidation, "Ship %s does not have a surveyor mount.", sh.Symbol)
+	}
+	if err := sh.requireOrbit(); err != nil {
+		return nil, err
+	}
+	if err := sh.requireCooldown(); err != nil {
+		return nil, err
+	}
+	deposits := s.deposits[string(sh.Nav.WaypointSymbol)]
+	if len(deposits) == 0 {
+		return nil, errBadRequest(client.CodeShipSurveyWaypointType, "Waypoint %s has no deposits to survey.", sh.Nav.WaypointSymbol)
+	}
+
+	res := &api.CreateSurveyCreated{}
+	for range mount.Strength.Or(1) {
+		size := surveySizes[s.rand.Intn(len(surveySizes))]
+		sv := &survey{remaining: size.extractions, Survey: api.Survey{
+			Signature:  fmt.Sprintf("%s-%06X", sh.Nav.WaypointSymbol, s.rand.Intn(1<<24)),
+			Symbol:     string(sh.Nav.WaypointSymbol),
+			Expiration: s.now().Add(surveyLifetime),
+			Size:       size.size,
+		}}
+		// surveys concentrate a few of the waypoints deposits
+		for range 3 + s.rand.Intn(4) {
+			sv.Deposits = append(sv.Deposits, api.SurveyDeposit{Symbol: string(deposits[s.rand.Intn(len(deposits))])})
+		}
+		s.surveys[sv.Signature] = sv
+		res.Data.Surveys = append(res.Data.Surveys, sv.Survey)
+	}
+
+	s.cooldown(sh, surveyCooldown)
+	res.Data.Cooldown = sh.Cooldown
+	return res, nil
+}
+
+func (s *Server) ExtractResources(ctx context.Context, req api.OptExtractResourcesReq, params api.ExtractResourcesParams) (*api.ExtractResourcesCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+
+	var sv *survey
+	if req.Value.Survey.Set {
+		if sv, err = s.survey(sh, req.Value.Survey.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := s.extract(sh, sv)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ExtractResourcesCreated{Data: api.ExtractResourcesCreatedData(data)}, nil
+}
+
+func (s *Server) ExtractResourcesWithSurvey(ctx context.Context, req api.OptSurvey, params api.ExtractResourcesWithSurveyParams) (*api.ExtractResourcesWithSurveyCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	sv, err := s.survey(sh, req.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.extract(sh, sv)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ExtractResourcesWithSurveyCreated{Data: data}, nil
+}
+
+// survey checks a survey submitted for extraction is one the mock issued and
+// that it is still usable by sh.
+func (s *Server) survey(sh *ship, submitted api.Survey) (*survey, error) {
+	sv, ok := s.surveys[submitted.Signature]
+	if !ok {
+		return nil, errBadRequest(client.CodeShipSurveyVerification, "Ship survey failed. Target signature %s could not be verified.", submitted.Signature)
+	}
+	if sv.Symbol != string(sh.Nav.WaypointSymbol) {
+		return nil, errBadRequest(client.CodeShipSurveyWaypointType, "Ship survey failed. Ship %s is not at the surveyed waypoint %s.", sh.Symbol, sv.Symbol)
+	}
+	if !s.now().Before(sv.Expiration) {
+		delete(s.surveys, sv.Signature)
+		return nil, errBadRequest(client.CodeShipSurveyExpiration, "Ship survey failed. Target signature %s has expired.", sv.Signature)
+	}
+	if sv.remaining <= 0 {
+		return nil, errConflict(client.CodeShipSurveyExhausted, nil, "Ship extract failed. Survey %s has been exhausted.", sv.Signature)
+	}
+	return sv, nil
+}
+
+// extract mines the waypoint under sh, drawing from the survey deposits when
+// one is given. The yield is capped by the space left in the hold.
+func (s *Server) extract(sh *ship, sv *survey) (api.ExtractResourcesWithSurveyCreatedData, error) {
+	laser, ok := sh.mount(miningLasers...)
+	if !ok {
+		return api.ExtractResourcesWithSurveyCreatedData{}, errBadRequest(codeValidation, "Ship %s does not have a mining laser.", sh.Symbol)
+	}
+	if err := sh.requireOrbit(); err != nil {
+		return api.ExtractResourcesWithSurveyCreatedData{}, err
+	}
+	if err := sh.requireCooldown(); err != nil {
+		return api.ExtractResourcesWithSurveyCreatedData{}, err
+	}
+
+	deposits := s.deposits[string(sh.Nav.WaypointSymbol)]
+	if sv != nil {
+		deposits = deposits[:0:0]
+		for _, d := range sv.Deposits {
+			deposits = append(deposits, api.TradeSymbol(d.Symbol))
+		}
+	}
+	if len(deposits) == 0 {
+		return api.ExtractResourcesWithSurveyCreatedData{}, errBadRequest(codeValidation, "Waypoint %s has no resources to extract.", sh.Nav.WaypointSymbol)
+	}
+
+	free := sh.Cargo.Capacity - sh.Cargo.Units
+	if free <= 0 {
+		return api.ExtractResourcesWithSurveyCreatedData{}, sh.fits(1)
+	}
+
+	strength := laser.Strength.Or(10)
+	units := min(free, strength/2+s.rand.Intn(strength/2+1))
+	units = max(1, units)
+	yield := api.ExtractionYield{Symbol: deposits[s.rand.Intn(len(deposits))], Units: units}
+	sh.load(yield.Symbol, yield.Units)
+
+	if sv != nil {
+		sv.remaining--
+	}
+
+	s.cooldown(sh, extractCooldown)
+	return api.ExtractResourcesWithSurveyCreatedData{
+		Cooldown:   sh.Cooldown,
+		Extraction: api.Extraction{ShipSymbol: sh.Symbol, Yield: yield},
+		Cargo:      s.shipView(sh).Cargo,
+		Events:     []api.ShipConditionEvent{},
+	}, nil
+}
@@ -0,0 +1,336 @@
+package mockserver
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+)
+
+// Market stock is modelled as a level between 0 (empty) and 1 (saturated).
+// Trades move the level and it recovers towards a target set by the goods role
+// in the market: exporters are well stocked, importers are short. Prices
+// follow the level and trade volumes grow with sustained activity.
+const (
+	exportStock   = 0.75
+	importStock   = 0.25
+	exchangeStock = 0.5
+
+	// time for stock and activity to recover half way to their targets
+	recoveryHalfLife = 10 * time.Minute
+
+	// spread between what the market asks and what it bids
+	spread = 0.04
+
+	maxTransactions = 30
+)
+
+type good struct {
+	symbol     api.TradeSymbol
+	typ        api.MarketTradeGoodType
+	base       int
+	baseVolume int
+	volume     int
+	stock      float64
+	traded     float64 // recent units, decays with recovery
+}
+
+type market struct {
+	symbol       string
+	goods        []*good
+	transactions []api.MarketTransaction
+	updated      time.Time
+}
+
+// addMarket opens a market at symbol for the given industries. Every market
+// also exchanges fuel. Callers hold s.mu.
+func (s *Server) addMarket(symbol string, industries ...[2][]api.TradeSymbol) {
+	m := &market{symbol: symbol, updated: s.now()}
+	add := func(sym api.TradeSymbol, typ api.MarketTradeGoodType) {
+		for _, g := range m.goods {
+			if g.symbol == sym {
+				return
+			}
+		}
+		base := basePrices[sym]
+		volume := min(60, max(10, 4000/base))
+		g := &good{symbol: sym, typ: typ, base: base, baseVolume: volume, volume: volume}
+		g.stock = g.target() + (s.rand.Float64()-0.5)*0.2
+		m.goods = append(m.goods, g)
+	}
+
+	for _, ind := range industries {
+		for _, sym := range ind[1] {
+			add(sym, api.MarketTradeGoodTypeEXPORT)
+		}
+	}
+	for _, ind := range industries {
+		for _, sym := range ind[0] {
+			add(sym, api.MarketTradeGoodTypeIMPORT)
+		}
+	}
+	add(api.TradeSymbolFUEL, api.MarketTradeGoodTypeEXCHANGE)
+
+	s.markets[symbol] = m
+}
+
+func (g *good) target() float64 {
+	switch g.typ {
+	case api.MarketTradeGoodTypeEXPORT:
+		return exportStock
+	case api.MarketTradeGoodTypeIMPORT:
+		return importStock
+	}
+	return exchangeStock
+}
+
+// price is the mid market price, from 1.6x the base price when empty down to
+// 0.4x when saturated.
+func (g *good) price() float64 {
+	return float64(g.base) * (1.6 - 1.2*g.stock)
+}
+
+// tick recovers stock and decays activity for the time since the last update.
+func (m *market) tick(now time.Time) {
+	elapsed := now.Sub(m.updated)
+	if elapsed <= 0 {
+		return
+	}
+	m.updated = now
+
+	k := 1 - math.Pow(0.5, elapsed.Seconds()/recoveryHalfLife.Seconds())
+	for _, g := range m.goods {
+		g.stock += (g.target() - g.stock) * k
+		g.traded *= 1 - k
+
+		// busy markets deepen, quiet ones return to their usual volume
+		switch {
+		case g.traded > 4*float64(g.volume):
+			g.volume = min(3*g.baseVolume, g.volume+g.volume/4+1)
+		case g.traded < float64(g.volume) && g.volume > g.baseVolume:
+			g.volume = max(g.baseVolume, g.volume-g.volume/10-1)
+		}
+	}
+}
+
+func (g *good) view() api.MarketTradeGood {
+	p := g.price()
+	return api.MarketTradeGood{
+		Symbol:        g.symbol,
+		Type:          g.typ,
+		TradeVolume:   g.volume,
+		Supply:        supplyLevel(g.stock),
+		Activity:      api.NewOptActivityLevel(activityLevel(g.traded / float64(g.volume))),
+		PurchasePrice: int(math.Ceil(p * (1 + spread))),
+		SellPrice:     int(math.Floor(p * (1 - spread))),
+	}
+}
+
+func supplyLevel(stock float64) api.SupplyLevel {
+	switch {
+	case stock < 0.2:
+		return api.SupplyLevelSCARCE
+	case stock < 0.4:
+		return api.SupplyLevelLIMITED
+	case stock < 0.6:
+		return api.SupplyLevelMODERATE
+	case stock < 0.8:
+		return api.SupplyLevelHIGH
+	}
+	return api.SupplyLevelABUNDANT
+}
+
+func activityLevel(turnover float64) api.ActivityLevel {
+	switch {
+	case turnover < 1:
+		return api.ActivityLevelWEAK
+	case turnover < 3:
+		return api.ActivityLevelGROWING
+	}
+	return api.ActivityLevelSTRONG
+}
+
+// marketGood returns the market at waypoint and its listing for sym.
+func (s *Server) marketGood(waypoint string, sym api.TradeSymbol) (*market, *good, error) {
+	m, ok := s.markets[waypoint]
+	if !ok {
+		return nil, nil, errNotFound("Market", waypoint)
+	}
+	m.tick(s.now())
+	for _, g := range m.goods {
+		if g.symbol == sym {
+			return m, g, nil
+		}
+	}
+	return m, nil, nil
+}
+
+// trade settles a purchase or sale of units of g for a, moving the markets
+// stock and recording the transaction. Cargo is left to the caller.
+func (s *Server) trade(a *agent, sh *ship, m *market, g *good, typ api.MarketTransactionType, units int) (api.MarketTransaction, error) {
+	v := g.view()
+	price := v.SellPrice
+	if typ == api.MarketTransactionTypePURCHASE {
+		price = v.PurchasePrice
+	}
+	total := price * units
+
+	if typ == api.MarketTransactionTypePURCHASE {
+		if a.Credits < int64(total) {
+			return api.MarketTransaction{}, errBadRequest(client.CodeMarketInsufficientCredits, "Agent has insufficient funds. Total cost is %d, agent has %d credits.", total, a.Credits).
+				withData(map[string]any{"creditsAvailable": a.Credits, "totalPrice": total})
+		}
+		a.Credits -= int64(total)
+		g.stock = math.Max(0, g.stock-float64(units)/float64(g.volume*20))
+	} else {
+		a.Credits += int64(total)
+		g.stock = math.Min(1, g.stock+float64(units)/float64(g.volume*20))
+	}
+	g.traded += float64(units)
+
+	tx := api.MarketTransaction{
+		WaypointSymbol: api.WaypointSymbol(m.symbol),
+		ShipSymbol:     sh.Symbol,
+		TradeSymbol:    string(g.symbol),
+		Type:           typ,
+		Units:          units,
+		PricePerUnit:   price,
+		TotalPrice:     total,
+		Timestamp:      s.now(),
+	}
+	m.transactions = append(m.transactions, tx)
+	if len(m.transactions) > maxTransactions {
+		m.transactions = m.transactions[len(m.transactions)-maxTransactions:]
+	}
+	return tx, nil
+}
+
+// present reports whether a has a ship at waypoint. Like the live game,
+// prices and transactions are only visible with a ship on site.
+func (a *agent) present(s *Server, waypoint string) bool {
+	for _, sh := range a.ships {
+		s.refresh(sh)
+		if string(sh.Nav.WaypointSymbol) == waypoint && sh.Nav.Status != api.ShipNavStatusINTRANSIT {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) GetMarket(ctx context.Context, params api.GetMarketParams) (*api.GetMarketOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.markets[params.WaypointSymbol]
+	if !ok {
+		return nil, errNotFound("Market", params.WaypointSymbol)
+	}
+	m.tick(s.now())
+
+	res := api.Market{
+		Symbol:   m.symbol,
+		Exports:  []api.TradeGood{},
+		Imports:  []api.TradeGood{},
+		Exchange: []api.TradeGood{},
+	}
+	for _, g := range m.goods {
+		tg := api.TradeGood{Symbol: g.symbol, Name: string(g.symbol), Description: string(g.symbol)}
+		switch g.typ {
+		case api.MarketTradeGoodTypeEXPORT:
+			res.Exports = append(res.Exports, tg)
+		case api.MarketTradeGoodTypeIMPORT:
+			res.Imports = append(res.Imports, tg)
+		default:
+			res.Exchange = append(res.Exchange, tg)
+		}
+	}
+
+	if agentFrom(ctx).present(s, m.symbol) {
+		for _, g := range m.goods {
+			res.TradeGoods = append(res.TradeGoods, g.view())
+		}
+		res.Transactions = append([]api.MarketTransaction{}, m.transactions...)
+	}
+
+	return &api.GetMarketOK{Data: res}, nil
+}
+
+func (s *Server) PurchaseCargo(ctx context.Context, req api.OptPurchaseCargoReq, params api.PurchaseCargoParams) (*api.PurchaseCargoCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	sh, m, g, err := s.tradeCheck(a, params.ShipSymbol, req.Value.Symbol, req.Value.Units)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil || g.typ == api.MarketTradeGoodTypeIMPORT {
+		return nil, errBadRequest(client.CodeMarketNoPurchase, "Market purchase failed. Trade good %s is not available at %s.", req.Value.Symbol, m.symbol)
+	}
+	if err := sh.fits(req.Value.Units); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.trade(a, sh, m, g, api.MarketTransactionTypePURCHASE, req.Value.Units)
+	if err != nil {
+		return nil, err
+	}
+	sh.load(g.symbol, req.Value.Units)
+
+	return &api.PurchaseCargoCreated{Data: api.PurchaseCargoCreatedData{
+		Agent:       a.Agent,
+		Cargo:       s.shipView(sh).Cargo,
+		Transaction: tx,
+	}}, nil
+}
+
+func (s *Server) SellCargo(ctx context.Context, req api.OptSellCargoReq, params api.SellCargoParams) (*api.SellCargoCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	sh, m, g, err := s.tradeCheck(a, params.ShipSymbol, req.Value.Symbol, req.Value.Units)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil || g.typ == api.MarketTradeGoodTypeEXPORT {
+		return nil, errBadRequest(client.CodeMarketNotSold, "Market sell failed. Trade good %s is not listed at %s.", req.Value.Symbol, m.symbol)
+	}
+	if err := sh.load(g.symbol, -req.Value.Units); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.trade(a, sh, m, g, api.MarketTransactionTypeSELL, req.Value.Units)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SellCargoCreated{Data: api.SellCargoCreatedData{
+		Agent:       a.Agent,
+		Cargo:       s.shipView(sh).Cargo,
+		Transaction: tx,
+	}}, nil
+}
+
+// tradeCheck validates the parts of a trade common to buying and selling.
+func (s *Server) tradeCheck(a *agent, shipSymbol string, sym api.TradeSymbol, units int) (*ship, *market, *good, error) {
+	sh, err := s.ship(a, shipSymbol)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := sh.requireDocked(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	m, g, err := s.marketGood(string(sh.Nav.WaypointSymbol), sym)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if g != nil && units > g.volume {
+		return nil, nil, nil, errBadRequest(client.CodeMarketTradeUnitLimit, "Market trade failed. Trade volume of %s at %s is limited to %d unit(s) per transaction.", sym, m.symbol, g.volume).
+			withData(map[string]any{"waypointSymbol": m.symbol, "tradeSymbol": sym, "units": units, "tradeVolume": g.volume})
+	}
+	return sh, m, g, nil
+}
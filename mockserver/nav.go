@@ -0,0 +1,193 @@
+package mockserver
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+)
+
+// https://github.com/SpaceTradersAPI/api-docs/wiki/Travel-Fuel-and-Time
+var flightModes = map[api.ShipNavFlightMode]struct {
+	multiplier float64
+	fuel       func(distance int) int
+}{
+	api.ShipNavFlightModeCRUISE:  {25, func(d int) int { return max(1, d) }},
+	api.ShipNavFlightModeSTEALTH: {30, func(d int) int { return max(1, d) }},
+	api.ShipNavFlightModeBURN:    {12.5, func(d int) int { return max(2, 2*d) }},
+	api.ShipNavFlightModeDRIFT:   {250, func(d int) int { return 1 }},
+}
+
+func travelTime(distance, speed int, mode api.ShipNavFlightMode) time.Duration {
+	secs := math.Round(math.Round(math.Max(1, float64(distance)))*flightModes[mode].multiplier/float64(max(1, speed)) + 15)
+	return time.Duration(secs) * time.Second
+}
+
+func (s *Server) NavigateShip(ctx context.Context, req api.OptNavigateShipReq, params api.NavigateShipParams) (*api.NavigateShipOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireArrived(); err != nil {
+		if e, ok := err.(*Error); ok {
+			e.Code = client.CodeNavigateInTransit
+		}
+		return nil, err
+	}
+	if err := sh.requireOrbit(); err != nil {
+		return nil, err
+	}
+
+	dest, err := s.waypoint(req.Value.WaypointSymbol)
+	if err != nil {
+		return nil, errBadRequest(client.CodeNavigateInvalidDest, "Destination %s does not exist.", req.Value.WaypointSymbol)
+	}
+	if dest.SystemSymbol != sh.Nav.SystemSymbol {
+		return nil, errBadRequest(client.CodeNavigateOutsideSystem, "Destination %s is outside of the ships system, use jump or warp instead.", dest.Symbol)
+	}
+	if dest.Symbol == sh.Nav.WaypointSymbol {
+		return nil, errBadRequest(client.CodeNavigateSameDestination, "Ship %s is already at %s.", sh.Symbol, dest.Symbol)
+	}
+
+	origin, err := s.waypoint(string(sh.Nav.WaypointSymbol))
+	if err != nil {
+		return nil, err
+	}
+
+	d := distance(origin, dest)
+	mode := sh.Nav.FlightMode
+	required := flightModes[mode].fuel(d)
+	if sh.Fuel.Capacity > 0 {
+		if sh.Fuel.Current < required {
+			return nil, errBadRequest(client.CodeNavigateInsufficientFuel, "Navigate request failed. Ship %s requires %d more fuel for navigation.", sh.Symbol, required-sh.Fuel.Current).
+				withData(map[string]any{"shipSymbol": sh.Symbol, "fuelRequired": required, "fuelAvailable": sh.Fuel.Current})
+		}
+		sh.Fuel.Current -= required
+		sh.Fuel.Consumed = api.NewOptShipFuelConsumed(api.ShipFuelConsumed{Amount: required, Timestamp: s.now()})
+	}
+
+	now := s.now()
+	sh.Nav.Status = api.ShipNavStatusINTRANSIT
+	sh.Nav.WaypointSymbol = dest.Symbol
+	sh.Nav.Route = api.ShipNavRoute{
+		Origin:        routeWaypoint(origin),
+		Destination:   routeWaypoint(dest),
+		DepartureTime: now,
+		Arrival:       now.Add(travelTime(d, sh.Engine.Speed, mode)),
+	}
+
+	return &api.NavigateShipOK{Data: api.NavigateShipOKData{
+		Fuel:   sh.Fuel,
+		Nav:    sh.Nav,
+		Events: []api.ShipConditionEvent{},
+	}}, nil
+}
+
+func (s *Server) DockShip(ctx context.Context, params api.DockShipParams) (*api.DockShipOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireArrived(); err != nil {
+		return nil, err
+	}
+	sh.Nav.Status = api.ShipNavStatusDOCKED
+	return &api.DockShipOK{Data: api.DockShipOKData{Nav: sh.Nav}}, nil
+}
+
+func (s *Server) OrbitShip(ctx context.Context, params api.OrbitShipParams) (*api.OrbitShipOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireArrived(); err != nil {
+		return nil, err
+	}
+	sh.Nav.Status = api.ShipNavStatusINORBIT
+	return &api.OrbitShipOK{Data: api.OrbitShipOKData{Nav: sh.Nav}}, nil
+}
+
+func (s *Server) GetShipNav(ctx context.Context, params api.GetShipNavParams) (*api.GetShipNavOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetShipNavOK{Data: sh.Nav}, nil
+}
+
+func (s *Server) PatchShipNav(ctx context.Context, req api.OptPatchShipNavReq, params api.PatchShipNavParams) (*api.PatchShipNavOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if mode, ok := req.Value.FlightMode.Get(); ok {
+		sh.Nav.FlightMode = mode
+	}
+	return &api.PatchShipNavOK{Data: api.PatchShipNavOKData{
+		Nav:    sh.Nav,
+		Fuel:   sh.Fuel,
+		Events: []api.ShipConditionEvent{},
+	}}, nil
+}
+
+// RefuelShip tops up the tank from the market at the ships waypoint. Fuel is
+// traded in market units of 100 and any part of a unit is charged in full.
+func (s *Server) RefuelShip(ctx context.Context, req api.OptRefuelShipReq, params api.RefuelShipParams) (*api.RefuelShipOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	sh, err := s.ship(a, params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireDocked(); err != nil {
+		return nil, err
+	}
+
+	m, g, err := s.marketGood(string(sh.Nav.WaypointSymbol), api.TradeSymbolFUEL)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, errBadRequest(client.CodeMarketNoPurchase, "Market at %s does not sell fuel.", m.symbol)
+	}
+
+	units := sh.Fuel.Capacity - sh.Fuel.Current
+	if req.Value.Units.Set {
+		units = min(units, req.Value.Units.Value)
+	}
+	if units <= 0 {
+		return &api.RefuelShipOK{Data: api.RefuelShipOKData{Agent: a.Agent, Fuel: sh.Fuel}}, nil
+	}
+
+	marketUnits := (units + 99) / 100
+	tx, err := s.trade(a, sh, m, g, api.MarketTransactionTypePURCHASE, marketUnits)
+	if err != nil {
+		return nil, err
+	}
+	sh.Fuel.Current += units
+
+	return &api.RefuelShipOK{Data: api.RefuelShipOKData{
+		Agent:       a.Agent,
+		Fuel:        sh.Fuel,
+		Transaction: tx,
+	}}, nil
+}
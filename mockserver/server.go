@@ -0,0 +1,216 @@
+// Package mockserver is an in-memory stand in for the SpaceTraders API. It
+// implements the generated api.Handler over a small procedurally generated
+// universe, so missions can be developed against it without spending credits
+// or rate limit budget.
+package mockserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	mrand "math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/clock"
+	ht "github.com/ogen-go/ogen/http"
+	"github.com/ogen-go/ogen/ogenerrors"
+)
+
+// Config controls the generated universe.
+type Config struct {
+	// Seed makes the universe reproducible between runs.
+	Seed int64
+
+	// Systems is the number of systems to generate.
+	Systems int
+
+	// Credits is the starting balance of every new agent.
+	Credits int64
+
+	// Clock drives transits, cooldowns and market recovery. Share a fake
+	// clock with the kernel to fast-forward both together.
+	Clock clock.Clock
+}
+
+var DefaultConfig = Config{Seed: 1, Systems: 3, Credits: 175_000}
+
+// Server holds the universe and serves it through api.Handler. All state is
+// guarded by a single mutex, requests are handled one at a time.
+type Server struct {
+	api.UnimplementedHandler
+
+	cfg     Config
+	started time.Time
+	clock   clock.Clock
+
+	mu   sync.Mutex
+	rand *mrand.Rand
+
+	systems   []*api.System
+	waypoints map[string][]*api.Waypoint // by system, in generation order
+	markets   map[string]*market
+	shipyards map[string]*shipyard
+	deposits  map[string][]api.TradeSymbol
+
+	agents    []*agent
+	tokens    map[string]*agent
+	ships     map[string]*ship
+	contracts map[string]*contract
+	surveys   map[string]*survey
+}
+
+func New(cfg Config) *Server {
+	if cfg.Systems <= 0 {
+		cfg.Systems = DefaultConfig.Systems
+	}
+	if cfg.Credits <= 0 {
+		cfg.Credits = DefaultConfig.Credits
+	}
+
+	clk := clock.Or(cfg.Clock)
+	s := &Server{
+		cfg:       cfg,
+		started:   clk.Now(),
+		clock:     clk,
+		rand:      mrand.New(mrand.NewSource(cfg.Seed)),
+		waypoints: make(map[string][]*api.Waypoint),
+		markets:   make(map[string]*market),
+		shipyards: make(map[string]*shipyard),
+		deposits:  make(map[string][]api.TradeSymbol),
+		tokens:    make(map[string]*agent),
+		ships:     make(map[string]*ship),
+		contracts: make(map[string]*contract),
+		surveys:   make(map[string]*survey),
+	}
+	s.generate()
+	return s
+}
+
+// Handler returns the http handler serving the API under prefix, typically
+// "/v2" to match the live base url.
+func (s *Server) Handler(prefix string) (http.Handler, error) {
+	return api.NewServer(s, s,
+		api.WithPathPrefix(prefix),
+		api.WithErrorHandler(writeError),
+		api.WithNotFound(func(w http.ResponseWriter, r *http.Request) {
+			writeError(r.Context(), w, r, errNotFound("route", r.URL.Path))
+		}),
+	)
+}
+
+// AddAgent creates an agent that authenticates with token, like Register does
+// for new agents. Used to seed the universe with the agent the bot is
+// configured for.
+func (s *Server) AddAgent(symbol string, faction api.FactionSymbol, token string) (*api.Agent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.register(symbol, faction, token)
+	if err != nil {
+		return nil, err
+	}
+	return &a.Agent, nil
+}
+
+type agentKey struct{}
+
+func (s *Server) HandleAgentToken(ctx context.Context, operationName api.OperationName, t api.AgentToken) (context.Context, error) {
+	s.mu.Lock()
+	a, ok := s.tokens[t.Token]
+	s.mu.Unlock()
+	if !ok {
+		return ctx, &Error{Status: http.StatusUnauthorized, Code: codeTokenInvalid, Message: "Invalid agent token."}
+	}
+	return context.WithValue(ctx, agentKey{}, a), nil
+}
+
+// HandleAccountToken accepts any account token, the mock has no accounts.
+func (s *Server) HandleAccountToken(ctx context.Context, operationName api.OperationName, t api.AccountToken) (context.Context, error) {
+	return ctx, nil
+}
+
+func (s *Server) now() time.Time {
+	return s.clock.Now()
+}
+
+func agentFrom(ctx context.Context) *agent {
+	a, _ := ctx.Value(agentKey{}).(*agent)
+	return a
+}
+
+// mock only error codes, the rest are shared with the client.
+const (
+	codeTokenInvalid     = 4100
+	codeAgentExists      = 4111
+	codeExistingContract = 4511
+	codeNotFound         = 404
+	codeValidation       = 422
+)
+
+// Error is written as a SpaceTraders error body, which the client decodes back
+// into its typed errors.
+type Error struct {
+	Status  int
+	Code    int
+	Message string
+	Data    any
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func errNotFound(kind, symbol string) *Error {
+	return &Error{Status: http.StatusNotFound, Code: codeNotFound, Message: fmt.Sprintf("%s %s not found.", kind, symbol)}
+}
+
+func errBadRequest(code int, format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func errConflict(code int, data any, format string, args ...any) *Error {
+	return &Error{Status: http.StatusConflict, Code: code, Message: fmt.Sprintf(format, args...), Data: data}
+}
+
+func writeError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	var e *Error
+	var params *ogenerrors.DecodeParamsError
+	var body *ogenerrors.DecodeRequestError
+	switch {
+	case errors.As(err, &e):
+	case errors.Is(err, ogenerrors.ErrSecurityRequirementIsNotSatisfied):
+		e = &Error{Status: http.StatusUnauthorized, Code: codeTokenInvalid, Message: "Missing or invalid bearer token."}
+	case errors.Is(err, ht.ErrNotImplemented):
+		e = &Error{Status: http.StatusNotImplemented, Code: http.StatusNotImplemented, Message: "Not implemented by the mock server."}
+	case errors.As(err, &params), errors.As(err, &body):
+		e = &Error{Status: http.StatusUnprocessableEntity, Code: codeValidation, Message: err.Error()}
+	default:
+		e = &Error{Status: http.StatusInternalServerError, Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+
+	if e.Status >= http.StatusInternalServerError {
+		slog.Error("mockserver: request failed", "path", r.URL.Path, "err", err)
+	}
+
+	res := map[string]any{"code": e.Code, "message": e.Message}
+	if e.Data != nil {
+		res["data"] = e.Data
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(map[string]any{"error": res})
+}
+
+func newToken() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,420 @@
+package mockserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+)
+
+type ship struct {
+	api.Ship
+	owner *agent
+
+	// when the ship was last refreshed, see Server.refresh
+	seen time.Time
+}
+
+// blueprint describes the ships the mock can build, loosely following the
+// stats of their live counterparts.
+type blueprint struct {
+	name    string
+	role    api.ShipRole
+	frame   api.ShipFrameSymbol
+	reactor api.ShipReactorSymbol
+	engine  api.ShipEngineSymbol
+	speed   int
+	fuel    int
+	cargo   int
+	crew    int
+	mounts  []api.ShipMountSymbol
+	price   int
+}
+
+var blueprints = map[api.ShipType]blueprint{
+	api.ShipTypeSHIPCOMMANDFRIGATE: {
+		name: "Command Frigate", role: api.ShipRoleCOMMAND, frame: api.ShipFrameSymbolFRAMEFRIGATE,
+		reactor: api.ShipReactorSymbolREACTORFISSIONI, engine: api.ShipEngineSymbolENGINEIONDRIVEII,
+		speed: 30, fuel: 400, cargo: 40, crew: 57,
+		mounts: []api.ShipMountSymbol{api.ShipMountSymbolMOUNTSENSORARRAYII, api.ShipMountSymbolMOUNTMININGLASERII, api.ShipMountSymbolMOUNTSURVEYORII},
+		price:  480_000,
+	},
+	api.ShipTypeSHIPPROBE: {
+		name: "Probe", role: api.ShipRoleSATELLITE, frame: api.ShipFrameSymbolFRAMEPROBE,
+		reactor: api.ShipReactorSymbolREACTORSOLARI, engine: api.ShipEngineSymbolENGINEIMPULSEDRIVEI,
+		speed: 9, price: 24_000,
+	},
+	api.ShipTypeSHIPMININGDRONE: {
+		name: "Mining Drone", role: api.ShipRoleEXCAVATOR, frame: api.ShipFrameSymbolFRAMEDRONE,
+		reactor: api.ShipReactorSymbolREACTORCHEMICALI, engine: api.ShipEngineSymbolENGINEIMPULSEDRIVEI,
+		speed: 9, fuel: 80, cargo: 15,
+		mounts: []api.ShipMountSymbol{api.ShipMountSymbolMOUNTMININGLASERI},
+		price:  42_000,
+	},
+	api.ShipTypeSHIPSURVEYOR: {
+		name: "Surveyor", role: api.ShipRoleSURVEYOR, frame: api.ShipFrameSymbolFRAMEDRONE,
+		reactor: api.ShipReactorSymbolREACTORCHEMICALI, engine: api.ShipEngineSymbolENGINEIMPULSEDRIVEI,
+		speed: 9, fuel: 80,
+		mounts: []api.ShipMountSymbol{api.ShipMountSymbolMOUNTSURVEYORI},
+		price:  38_000,
+	},
+	api.ShipTypeSHIPLIGHTHAULER: {
+		name: "Light Hauler", role: api.ShipRoleHAULER, frame: api.ShipFrameSymbolFRAMELIGHTFREIGHTER,
+		reactor: api.ShipReactorSymbolREACTORCHEMICALI, engine: api.ShipEngineSymbolENGINEIONDRIVEI,
+		speed: 15, fuel: 600, cargo: 80, crew: 20,
+		price: 140_000,
+	},
+}
+
+// mining lasers yield more per extraction with every tier, surveyors find
+// more surveys.
+var mountStrength = map[api.ShipMountSymbol]int{
+	api.ShipMountSymbolMOUNTMININGLASERI:   10,
+	api.ShipMountSymbolMOUNTMININGLASERII:  25,
+	api.ShipMountSymbolMOUNTMININGLASERIII: 60,
+	api.ShipMountSymbolMOUNTSURVEYORI:      1,
+	api.ShipMountSymbolMOUNTSURVEYORII:     2,
+	api.ShipMountSymbolMOUNTSURVEYORIII:    3,
+	api.ShipMountSymbolMOUNTSENSORARRAYII:  4,
+}
+
+func (bp blueprint) components() (api.ShipFrame, api.ShipReactor, api.ShipEngine) {
+	return api.ShipFrame{
+			Symbol: bp.frame, Name: string(bp.frame), Description: bp.name,
+			Condition: 1, Integrity: 1, Quality: 1,
+			ModuleSlots: 3, MountingPoints: max(1, len(bp.mounts)), FuelCapacity: bp.fuel,
+		},
+		api.ShipReactor{
+			Symbol: bp.reactor, Name: string(bp.reactor), Description: bp.name,
+			Condition: 1, Integrity: 1, Quality: 1, PowerOutput: 30,
+		},
+		api.ShipEngine{
+			Symbol: bp.engine, Name: string(bp.engine), Description: bp.name,
+			Condition: 1, Integrity: 1, Quality: 1, Speed: bp.speed,
+		}
+}
+
+func (bp blueprint) equipment() ([]api.ShipModule, []api.ShipMount) {
+	modules := []api.ShipModule{}
+	if bp.cargo > 0 {
+		modules = append(modules, api.ShipModule{
+			Symbol:   api.ShipModuleSymbolMODULECARGOHOLDI,
+			Name:     "Cargo Hold",
+			Capacity: api.NewOptInt(bp.cargo),
+		})
+	}
+
+	mounts := []api.ShipMount{}
+	for _, m := range bp.mounts {
+		mount := api.ShipMount{Symbol: m, Name: string(m), Deposits: []api.ShipMountDepositsItem{}}
+		if strength, ok := mountStrength[m]; ok {
+			mount.Strength = api.NewOptInt(strength)
+		}
+		mounts = append(mounts, mount)
+	}
+	return modules, mounts
+}
+
+// addShip builds a ship of type typ docked at wp and hands it to a. Callers
+// hold s.mu.
+func (s *Server) addShip(a *agent, typ api.ShipType, wp *api.Waypoint) *ship {
+	bp := blueprints[typ]
+	frame, reactor, engine := bp.components()
+	modules, mounts := bp.equipment()
+
+	symbol := fmt.Sprintf("%s-%X", a.Symbol, len(a.ships)+1)
+	here := routeWaypoint(wp)
+	now := s.now()
+
+	sh := &ship{
+		owner: a,
+		Ship: api.Ship{
+			Symbol:       symbol,
+			Registration: api.ShipRegistration{Name: symbol, FactionSymbol: a.StartingFaction, Role: bp.role},
+			Nav: api.ShipNav{
+				SystemSymbol:   wp.SystemSymbol,
+				WaypointSymbol: wp.Symbol,
+				Route:          api.ShipNavRoute{Origin: here, Destination: here, DepartureTime: now, Arrival: now},
+				Status:         api.ShipNavStatusDOCKED,
+				FlightMode:     api.ShipNavFlightModeCRUISE,
+			},
+			Crew:     api.ShipCrew{Current: bp.crew, Required: bp.crew, Capacity: bp.crew, Rotation: api.ShipCrewRotationSTRICT, Morale: 100},
+			Frame:    frame,
+			Reactor:  reactor,
+			Engine:   engine,
+			Cooldown: api.Cooldown{ShipSymbol: symbol},
+			Modules:  modules,
+			Mounts:   mounts,
+			Cargo:    api.ShipCargo{Capacity: bp.cargo, Inventory: []api.ShipCargoItem{}},
+			Fuel:     api.ShipFuel{Current: bp.fuel, Capacity: bp.fuel},
+		},
+	}
+
+	a.ships = append(a.ships, sh)
+	a.ShipCount = len(a.ships)
+	s.ships[symbol] = sh
+	return sh
+}
+
+func routeWaypoint(wp *api.Waypoint) api.ShipNavRouteWaypoint {
+	return api.ShipNavRouteWaypoint{
+		Symbol:       string(wp.Symbol),
+		Type:         wp.Type,
+		SystemSymbol: wp.SystemSymbol,
+		X:            wp.X,
+		Y:            wp.Y,
+	}
+}
+
+// ship returns one of a's ships with its nav and cooldown brought up to date.
+func (s *Server) ship(a *agent, symbol string) (*ship, error) {
+	sh, ok := s.ships[symbol]
+	if !ok || sh.owner != a {
+		return nil, errNotFound("Ship", symbol)
+	}
+	s.refresh(sh)
+	return sh, nil
+}
+
+// refresh lands ships that have arrived and counts down cooldowns. The mock
+// has no background loop, ships catch up whenever they are looked at.
+func (s *Server) refresh(sh *ship) {
+	now := s.now()
+	sh.seen = now
+
+	if sh.Nav.Status == api.ShipNavStatusINTRANSIT && !now.Before(sh.Nav.Route.Arrival) {
+		sh.Nav.Status = api.ShipNavStatusINORBIT
+	}
+
+	if exp, ok := sh.Cooldown.Expiration.Get(); ok {
+		if now.Before(exp) {
+			sh.Cooldown.RemainingSeconds = int(exp.Sub(now).Seconds() + 0.5)
+		} else {
+			sh.Cooldown = api.Cooldown{ShipSymbol: sh.Symbol}
+		}
+	}
+}
+
+func (s *Server) shipView(sh *ship) api.Ship {
+	s.refresh(sh)
+	v := sh.Ship
+	v.Cargo.Inventory = append([]api.ShipCargoItem{}, sh.Cargo.Inventory...)
+	return v
+}
+
+func (s *Server) cooldown(sh *ship, d time.Duration) {
+	sh.Cooldown = api.Cooldown{
+		ShipSymbol:       sh.Symbol,
+		TotalSeconds:     int(d.Seconds()),
+		RemainingSeconds: int(d.Seconds()),
+		Expiration:       api.NewOptDateTime(s.now().Add(d)),
+	}
+}
+
+func (sh *ship) requireCooldown() error {
+	if sh.Cooldown.RemainingSeconds > 0 {
+		return errConflict(client.CodeCooldownConflict, map[string]any{"cooldown": sh.Cooldown},
+			"Ship action is still on cooldown for %d second(s).", sh.Cooldown.RemainingSeconds)
+	}
+	return nil
+}
+
+func (sh *ship) requireArrived() error {
+	if sh.Nav.Status != api.ShipNavStatusINTRANSIT {
+		return nil
+	}
+	return errBadRequest(client.CodeShipInTransit, "Ship is currently in-transit from %s to %s.", sh.Nav.Route.Origin.Symbol, sh.Nav.Route.Destination.Symbol).
+		withData(map[string]any{
+			"departureSymbol":   sh.Nav.Route.Origin.Symbol,
+			"destinationSymbol": sh.Nav.Route.Destination.Symbol,
+			"arrival":           sh.Nav.Route.Arrival,
+			"departureTime":     sh.Nav.Route.DepartureTime,
+			"secondsToArrival":  int(sh.Nav.Route.Arrival.Sub(sh.seen).Seconds()),
+		})
+}
+
+func (sh *ship) requireDocked() error {
+	if err := sh.requireArrived(); err != nil {
+		return err
+	}
+	if sh.Nav.Status != api.ShipNavStatusDOCKED {
+		return errBadRequest(client.CodeShipNotDocked, "Ship action requires ship to be docked. Use the dock endpoint to dock your ship.")
+	}
+	return nil
+}
+
+func (sh *ship) requireOrbit() error {
+	if err := sh.requireArrived(); err != nil {
+		return err
+	}
+	if sh.Nav.Status != api.ShipNavStatusINORBIT {
+		return errBadRequest(client.CodeShipNotInOrbit, "Ship action requires ship to be in orbit. Use the orbit endpoint to orbit your ship.")
+	}
+	return nil
+}
+
+func (sh *ship) mount(strongest ...api.ShipMountSymbol) (api.ShipMount, bool) {
+	for _, want := range strongest {
+		for _, m := range sh.Mounts {
+			if m.Symbol == want {
+				return m, true
+			}
+		}
+	}
+	return api.ShipMount{}, false
+}
+
+func (sh *ship) units(good api.TradeSymbol) int {
+	for _, item := range sh.Cargo.Inventory {
+		if item.Symbol == good {
+			return item.Units
+		}
+	}
+	return 0
+}
+
+// fits checks the hold has room for units more.
+func (sh *ship) fits(units int) error {
+	if units > 0 && sh.Cargo.Units+units > sh.Cargo.Capacity {
+		return errBadRequest(client.CodeShipCargoExceedsLimit, "Ship %s cargo capacity of %d exceeded by %d unit(s).", sh.Symbol, sh.Cargo.Capacity, sh.Cargo.Units+units-sh.Cargo.Capacity)
+	}
+	return nil
+}
+
+// load adds units of good to the hold, or removes them when units is
+// negative.
+func (sh *ship) load(good api.TradeSymbol, units int) error {
+	if err := sh.fits(units); err != nil {
+		return err
+	}
+	if units < 0 && sh.units(good) < -units {
+		if sh.units(good) == 0 {
+			return errBadRequest(client.CodeShipCargoMissing, "Ship %s does not have %s in cargo.", sh.Symbol, good)
+		}
+		return errBadRequest(client.CodeShipCargoUnitCount, "Ship %s has %d unit(s) of %s, %d requested.", sh.Symbol, sh.units(good), good, -units)
+	}
+
+	sh.Cargo.Units += units
+	for i, item := range sh.Cargo.Inventory {
+		if item.Symbol == good {
+			sh.Cargo.Inventory[i].Units += units
+			if sh.Cargo.Inventory[i].Units == 0 {
+				sh.Cargo.Inventory = append(sh.Cargo.Inventory[:i], sh.Cargo.Inventory[i+1:]...)
+			}
+			return nil
+		}
+	}
+	sh.Cargo.Inventory = append(sh.Cargo.Inventory, api.ShipCargoItem{Symbol: good, Name: string(good), Description: string(good), Units: units})
+	return nil
+}
+
+func (e *Error) withData(data any) *Error {
+	e.Data = data
+	return e
+}
+
+func (s *Server) GetMyShips(ctx context.Context, params api.GetMyShipsParams) (*api.GetMyShipsOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	ships := make([]api.Ship, len(a.ships))
+	for i, sh := range a.ships {
+		ships[i] = s.shipView(sh)
+	}
+
+	data, meta, err := paginate(ships, params.Page, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetMyShipsOK{Data: data, Meta: meta}, nil
+}
+
+func (s *Server) GetMyShip(ctx context.Context, params api.GetMyShipParams) (*api.GetMyShipOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetMyShipOK{Data: s.shipView(sh)}, nil
+}
+
+func (s *Server) GetMyShipCargo(ctx context.Context, params api.GetMyShipCargoParams) (*api.GetMyShipCargoOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetMyShipCargoOK{Data: s.shipView(sh).Cargo}, nil
+}
+
+func (s *Server) GetShipCooldown(ctx context.Context, params api.GetShipCooldownParams) (api.GetShipCooldownRes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if sh.Cooldown.RemainingSeconds == 0 {
+		return &api.GetShipCooldownNoContent{}, nil
+	}
+	return &api.GetShipCooldownOK{Data: sh.Cooldown}, nil
+}
+
+func (s *Server) Jettison(ctx context.Context, req api.OptJettisonReq, params api.JettisonParams) (*api.JettisonOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sh, err := s.ship(agentFrom(ctx), params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := sh.requireArrived(); err != nil {
+		return nil, err
+	}
+	if err := sh.load(req.Value.Symbol, -req.Value.Units); err != nil {
+		return nil, err
+	}
+	return &api.JettisonOK{Data: api.JettisonOKData{Cargo: s.shipView(sh).Cargo}}, nil
+}
+
+func (s *Server) TransferCargo(ctx context.Context, req api.OptTransferCargoReq, params api.TransferCargoParams) (*api.TransferCargoOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	from, err := s.ship(a, params.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.ship(a, req.Value.ShipSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := from.requireArrived(); err != nil {
+		return nil, err
+	}
+	if err := to.requireArrived(); err != nil {
+		return nil, err
+	}
+	if from.Nav.WaypointSymbol != to.Nav.WaypointSymbol || from.Nav.Status != to.Nav.Status {
+		return nil, errBadRequest(codeValidation, "Ships %s and %s must be at the same waypoint with the same nav status to transfer cargo.", from.Symbol, to.Symbol)
+	}
+
+	good, units := req.Value.TradeSymbol, req.Value.Units
+	if err := from.load(good, -units); err != nil {
+		return nil, err
+	}
+	if err := to.load(good, units); err != nil {
+		from.load(good, units)
+		return nil, err
+	}
+	return &api.TransferCargoOK{Data: api.TransferCargoOKData{Cargo: s.shipView(from).Cargo}}, nil
+}
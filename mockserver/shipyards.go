@@ -0,0 +1,111 @@
+package mockserver
+
+import (
+	"context"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client"
+)
+
+const modificationsFee = 5_000
+
+type shipyard struct {
+	symbol       string
+	types        []api.ShipType
+	transactions []api.ShipyardTransaction
+}
+
+func (s *Server) addShipyard(symbol string, types ...api.ShipType) {
+	s.shipyards[symbol] = &shipyard{symbol: symbol, types: types}
+}
+
+func (s *Server) GetShipyard(ctx context.Context, params api.GetShipyardParams) (*api.GetShipyardOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sy, ok := s.shipyards[params.WaypointSymbol]
+	if !ok {
+		return nil, errNotFound("Shipyard", params.WaypointSymbol)
+	}
+
+	res := api.Shipyard{Symbol: sy.symbol, ModificationsFee: modificationsFee, ShipTypes: []api.ShipyardShipTypesItem{}}
+	for _, t := range sy.types {
+		res.ShipTypes = append(res.ShipTypes, api.ShipyardShipTypesItem{Type: t})
+	}
+
+	// listings are only visible with a ship on site
+	if agentFrom(ctx).present(s, sy.symbol) {
+		for _, t := range sy.types {
+			bp := blueprints[t]
+			frame, reactor, engine := bp.components()
+			modules, mounts := bp.equipment()
+			res.Ships = append(res.Ships, api.ShipyardShip{
+				Type:          t,
+				Name:          bp.name,
+				Description:   bp.name,
+				Supply:        api.SupplyLevelMODERATE,
+				PurchasePrice: bp.price,
+				Frame:         frame,
+				Reactor:       reactor,
+				Engine:        engine,
+				Modules:       modules,
+				Mounts:        mounts,
+				Crew:          api.ShipyardShipCrew{Required: bp.crew, Capacity: bp.crew},
+			})
+		}
+		res.Transactions = append([]api.ShipyardTransaction{}, sy.transactions...)
+	}
+
+	return &api.GetShipyardOK{Data: res}, nil
+}
+
+func (s *Server) PurchaseShip(ctx context.Context, req api.OptPurchaseShipReq) (*api.PurchaseShipCreated, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := agentFrom(ctx)
+	sy, ok := s.shipyards[req.Value.WaypointSymbol]
+	if !ok {
+		return nil, errNotFound("Shipyard", req.Value.WaypointSymbol)
+	}
+	if !a.present(s, sy.symbol) {
+		return nil, errBadRequest(codeValidation, "Agent %s must have a ship at %s to purchase ships.", a.Symbol, sy.symbol)
+	}
+
+	sold := false
+	for _, t := range sy.types {
+		sold = sold || t == req.Value.ShipType
+	}
+	if !sold {
+		return nil, errBadRequest(codeValidation, "Shipyard %s does not sell %s.", sy.symbol, req.Value.ShipType)
+	}
+
+	bp := blueprints[req.Value.ShipType]
+	if a.Credits < int64(bp.price) {
+		return nil, errBadRequest(client.CodeMarketInsufficientCredits, "Agent has insufficient funds. Total cost is %d, agent has %d credits.", bp.price, a.Credits).
+			withData(map[string]any{"creditsAvailable": a.Credits, "totalPrice": bp.price})
+	}
+	a.Credits -= int64(bp.price)
+
+	wp, err := s.waypoint(sy.symbol)
+	if err != nil {
+		return nil, err
+	}
+	sh := s.addShip(a, req.Value.ShipType, wp)
+
+	tx := api.ShipyardTransaction{
+		WaypointSymbol: api.WaypointSymbol(sy.symbol),
+		ShipSymbol:     sh.Symbol,
+		ShipType:       string(req.Value.ShipType),
+		Price:          bp.price,
+		AgentSymbol:    a.Symbol,
+		Timestamp:      s.now(),
+	}
+	sy.transactions = append(sy.transactions, tx)
+
+	return &api.PurchaseShipCreated{Data: api.PurchaseShipCreatedData{
+		Agent:       a.Agent,
+		Ship:        s.shipView(sh),
+		Transaction: tx,
+	}}, nil
+}
@@ -0,0 +1,84 @@
+package mockserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+// paginate slices items the way the live API does: pages start at 1, the
+// limit defaults to 10 and can't exceed 20.
+func paginate[T any](items []T, page, limit api.OptInt) ([]T, api.Meta, error) {
+	p := page.Or(1)
+	l := limit.Or(10)
+	if p < 1 || l < 1 || l > 20 {
+		return nil, api.Meta{}, &Error{Status: http.StatusBadRequest, Code: codeValidation, Message: "Page must be at least 1 and limit between 1 and 20."}
+	}
+
+	meta := api.Meta{Total: len(items), Page: p, Limit: l}
+	start := min((p-1)*l, len(items))
+	end := min(start+l, len(items))
+	return items[start:end], meta, nil
+}
+
+func (s *Server) GetSystems(ctx context.Context, params api.GetSystemsParams) (*api.GetSystemsOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	systems := make([]api.System, len(s.systems))
+	for i, sys := range s.systems {
+		systems[i] = *sys
+	}
+
+	data, meta, err := paginate(systems, params.Page, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetSystemsOK{Data: data, Meta: meta}, nil
+}
+
+func (s *Server) GetSystem(ctx context.Context, params api.GetSystemParams) (*api.GetSystemOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sys, err := s.system(params.SystemSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetSystemOK{Data: *sys}, nil
+}
+
+func (s *Server) GetSystemWaypoints(ctx context.Context, params api.GetSystemWaypointsParams) (*api.GetSystemWaypointsOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.system(params.SystemSymbol); err != nil {
+		return nil, err
+	}
+
+	waypoints := []api.Waypoint{}
+	for _, wp := range s.waypoints[params.SystemSymbol] {
+		if params.Type.Set && wp.Type != params.Type.Value {
+			continue
+		}
+		waypoints = append(waypoints, *wp)
+	}
+
+	data, meta, err := paginate(waypoints, params.Page, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetSystemWaypointsOK{Data: data, Meta: meta}, nil
+}
+
+func (s *Server) GetWaypoint(ctx context.Context, params api.GetWaypointParams) (*api.GetWaypointOK, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wp, err := s.waypoint(params.WaypointSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return &api.GetWaypointOK{Data: *wp}, nil
+}
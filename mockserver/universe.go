@@ -0,0 +1,190 @@
+package mockserver
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bwiggs/spacetraders-go/api"
+)
+
+// goods the mock markets trade, with their equilibrium price per unit.
+var basePrices = map[api.TradeSymbol]int{
+	api.TradeSymbolFUEL:            72,
+	api.TradeSymbolICEWATER:        14,
+	api.TradeSymbolAMMONIAICE:      22,
+	api.TradeSymbolQUARTZSAND:      20,
+	api.TradeSymbolSILICONCRYSTALS: 34,
+	api.TradeSymbolIRONORE:         40,
+	api.TradeSymbolCOPPERORE:       46,
+	api.TradeSymbolALUMINUMORE:     52,
+	api.TradeSymbolPRECIOUSSTONES:  64,
+	api.TradeSymbolIRON:            110,
+	api.TradeSymbolCOPPER:          120,
+	api.TradeSymbolALUMINUM:        132,
+	api.TradeSymbolFOOD:            90,
+	api.TradeSymbolFABRICS:         150,
+	api.TradeSymbolMACHINERY:       280,
+	api.TradeSymbolELECTRONICS:     420,
+}
+
+// asteroid deposits, mined with a mining laser.
+var asteroidDeposits = []api.TradeSymbol{
+	api.TradeSymbolICEWATER,
+	api.TradeSymbolAMMONIAICE,
+	api.TradeSymbolQUARTZSAND,
+	api.TradeSymbolSILICONCRYSTALS,
+	api.TradeSymbolIRONORE,
+	api.TradeSymbolCOPPERORE,
+	api.TradeSymbolALUMINUMORE,
+	api.TradeSymbolPRECIOUSSTONES,
+}
+
+// industries produce their exports from their imports.
+var industries = [][2][]api.TradeSymbol{
+	{{api.TradeSymbolIRONORE}, {api.TradeSymbolIRON}},
+	{{api.TradeSymbolCOPPERORE}, {api.TradeSymbolCOPPER}},
+	{{api.TradeSymbolALUMINUMORE}, {api.TradeSymbolALUMINUM}},
+	{{api.TradeSymbolICEWATER, api.TradeSymbolAMMONIAICE}, {api.TradeSymbolFOOD}},
+	{{api.TradeSymbolFOOD, api.TradeSymbolQUARTZSAND}, {api.TradeSymbolFABRICS}},
+	{{api.TradeSymbolIRON, api.TradeSymbolALUMINUM}, {api.TradeSymbolMACHINERY}},
+	{{api.TradeSymbolCOPPER, api.TradeSymbolSILICONCRYSTALS}, {api.TradeSymbolELECTRONICS}},
+}
+
+var traits = map[api.WaypointTraitSymbol]string{
+	api.WaypointTraitSymbolMARKETPLACE:         "Marketplace",
+	api.WaypointTraitSymbolSHIPYARD:            "Shipyard",
+	api.WaypointTraitSymbolCOMMONMETALDEPOSITS: "Common Metal Deposits",
+	api.WaypointTraitSymbolMINERALDEPOSITS:     "Mineral Deposits",
+	api.WaypointTraitSymbolICECRYSTALS:         "Ice Crystals",
+}
+
+// generate lays out every system around the origin. Each system gets the same
+// shape: a planet hosting the headquarters, its moon, an orbital station, an
+// asteroid field with a few asteroids, a fuel station and a jump gate. That is
+// enough for contract, mining and trade missions to have somewhere to go.
+func (s *Server) generate() {
+	for i := range s.cfg.Systems {
+		symbol := fmt.Sprintf("X1-MK%02d", i)
+		angle := float64(i) * 2 * math.Pi / float64(s.cfg.Systems)
+		sys := &api.System{
+			Symbol:       symbol,
+			SectorSymbol: "X1",
+			Type:         api.SystemTypeORANGESTAR,
+			X:            int(800 * math.Cos(angle)),
+			Y:            int(800 * math.Sin(angle)),
+			Factions:     []api.SystemFaction{{Symbol: api.FactionSymbolCOSMIC}},
+		}
+		s.systems = append(s.systems, sys)
+
+		planet := s.addWaypoint(sys, "A1", api.WaypointTypePLANET, "", api.WaypointTraitSymbolMARKETPLACE, api.WaypointTraitSymbolSHIPYARD)
+		moon := s.addWaypoint(sys, "A2", api.WaypointTypeMOON, string(planet.Symbol), api.WaypointTraitSymbolMARKETPLACE)
+		station := s.addWaypoint(sys, "A3", api.WaypointTypeORBITALSTATION, string(planet.Symbol), api.WaypointTraitSymbolMARKETPLACE, api.WaypointTraitSymbolSHIPYARD)
+		s.addWaypoint(sys, "B1", api.WaypointTypeASTEROIDFIELD, "", api.WaypointTraitSymbolCOMMONMETALDEPOSITS, api.WaypointTraitSymbolMINERALDEPOSITS)
+		for j := range 3 {
+			s.addWaypoint(sys, fmt.Sprintf("B%d", j+2), api.WaypointTypeASTEROID, "", api.WaypointTraitSymbolCOMMONMETALDEPOSITS, api.WaypointTraitSymbolICECRYSTALS)
+		}
+		fuel := s.addWaypoint(sys, "C1", api.WaypointTypeFUELSTATION, "", api.WaypointTraitSymbolMARKETPLACE)
+		s.addWaypoint(sys, "I1", api.WaypointTypeJUMPGATE, "")
+
+		// spread the industries over the marketplaces so goods have to be
+		// carried between them
+		order := s.rand.Perm(len(industries))
+		s.addMarket(string(planet.Symbol), industries[order[0]], industries[order[1]])
+		s.addMarket(string(moon.Symbol), industries[order[2]], industries[order[3]])
+		s.addMarket(string(station.Symbol), industries[order[4]], industries[order[5]], industries[order[6]])
+		s.addMarket(string(fuel.Symbol))
+
+		s.addShipyard(string(planet.Symbol), api.ShipTypeSHIPPROBE, api.ShipTypeSHIPMININGDRONE, api.ShipTypeSHIPLIGHTHAULER)
+		s.addShipyard(string(station.Symbol), api.ShipTypeSHIPSURVEYOR, api.ShipTypeSHIPLIGHTHAULER, api.ShipTypeSHIPCOMMANDFRIGATE)
+	}
+}
+
+func (s *Server) addWaypoint(sys *api.System, suffix string, typ api.WaypointType, orbits string, traitSymbols ...api.WaypointTraitSymbol) *api.Waypoint {
+	symbol := sys.Symbol + "-" + suffix
+
+	wp := &api.Waypoint{
+		Symbol:       api.WaypointSymbol(symbol),
+		Type:         typ,
+		SystemSymbol: api.SystemSymbol(sys.Symbol),
+		Orbitals:     []api.WaypointOrbital{},
+		Faction:      api.NewOptWaypointFaction(api.WaypointFaction{Symbol: api.FactionSymbolCOSMIC}),
+		Modifiers:    []api.WaypointModifier{},
+	}
+
+	if orbits != "" {
+		// orbitals share their parents coordinates
+		for _, parent := range s.waypoints[sys.Symbol] {
+			if string(parent.Symbol) == orbits {
+				wp.X, wp.Y = parent.X, parent.Y
+				parent.Orbitals = append(parent.Orbitals, api.WaypointOrbital{Symbol: symbol})
+			}
+		}
+		for i := range sys.Waypoints {
+			if string(sys.Waypoints[i].Symbol) == orbits {
+				sys.Waypoints[i].Orbitals = append(sys.Waypoints[i].Orbitals, api.WaypointOrbital{Symbol: symbol})
+			}
+		}
+		wp.Orbits = api.NewOptString(orbits)
+	} else {
+		wp.X = s.rand.Intn(160) - 80
+		wp.Y = s.rand.Intn(160) - 80
+	}
+
+	for _, t := range traitSymbols {
+		wp.Traits = append(wp.Traits, api.WaypointTrait{Symbol: t, Name: traits[t], Description: traits[t]})
+	}
+
+	switch typ {
+	case api.WaypointTypeASTEROIDFIELD, api.WaypointTypeASTEROID:
+		n := 3 + s.rand.Intn(3)
+		for _, i := range s.rand.Perm(len(asteroidDeposits))[:n] {
+			s.deposits[symbol] = append(s.deposits[symbol], asteroidDeposits[i])
+		}
+	}
+
+	s.waypoints[sys.Symbol] = append(s.waypoints[sys.Symbol], wp)
+	sys.Waypoints = append(sys.Waypoints, api.SystemWaypoint{
+		Symbol:   wp.Symbol,
+		Type:     wp.Type,
+		X:        wp.X,
+		Y:        wp.Y,
+		Orbitals: []api.WaypointOrbital{},
+		Orbits:   wp.Orbits,
+	})
+	return wp
+}
+
+func (s *Server) system(symbol string) (*api.System, error) {
+	for _, sys := range s.systems {
+		if sys.Symbol == symbol {
+			return sys, nil
+		}
+	}
+	return nil, errNotFound("System", symbol)
+}
+
+func (s *Server) waypoint(symbol string) (*api.Waypoint, error) {
+	for _, wp := range s.waypoints[systemOf(symbol)] {
+		if string(wp.Symbol) == symbol {
+			return wp, nil
+		}
+	}
+	return nil, errNotFound("Waypoint", symbol)
+}
+
+// systemOf returns the system part of a waypoint symbol.
+func systemOf(waypoint string) string {
+	for i, dashes := 0, 0; i < len(waypoint); i++ {
+		if waypoint[i] == '-' {
+			dashes++
+			if dashes == 2 {
+				return waypoint[:i]
+			}
+		}
+	}
+	return waypoint
+}
+
+func distance(a, b *api.Waypoint) int {
+	return int(math.Round(math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))))
+}
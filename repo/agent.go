@@ -6,6 +6,8 @@ import (
 )
 
 func (r *Repo) UpsertAgents(agents []api.Agent) error {
+	defer r.trace("UpsertAgents")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -42,6 +44,8 @@ func (r *Repo) UpsertAgents(agents []api.Agent) error {
 }
 
 func (r *Repo) GetAgents() ([]*api.Agent, error) {
+	defer r.trace("GetAgents")()
+
 	bufs := [][]byte{}
 	if err := r.db.Select(&bufs, "SELECT json from agents"); err != nil {
 		return nil, err
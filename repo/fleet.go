@@ -1,16 +1,20 @@
 package repo
 
 import (
+	"strings"
+
 	"github.com/bwiggs/spacetraders-go/api"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func (r *Repo) UpsertFleet(ships []api.Ship) error {
+	defer r.trace("UpsertFleet")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	upsertSystem, err := tx.Prepare("INSERT OR REPLACE INTO fleet (symbol, data) values (?, ?);")
+	upsertSystem, err := tx.Prepare("INSERT OR REPLACE INTO fleet (symbol, agent, data) values (?, ?, ?);")
 	if err != nil {
 		return err
 	}
@@ -23,7 +27,7 @@ func (r *Repo) UpsertFleet(ships []api.Ship) error {
 			panic(err)
 		}
 
-		_, err = upsertSystem.Exec(s.GetSymbol(), data)
+		_, err = upsertSystem.Exec(s.GetSymbol(), ShipAgent(s.GetSymbol()), data)
 		if err != nil {
 			panic(err)
 		}
@@ -34,7 +38,19 @@ func (r *Repo) UpsertFleet(ships []api.Ship) error {
 	return nil
 }
 
+// ShipAgent returns the agent owning a ship, ship symbols are the agent's
+// symbol followed by a dash and a hex suffix.
+func ShipAgent(ship string) string {
+	i := strings.LastIndex(ship, "-")
+	if i < 0 {
+		return ship
+	}
+	return ship[:i]
+}
+
 func (r *Repo) GetFleet() ([]*api.Ship, error) {
+	defer r.trace("GetFleet")()
+
 	bufs := [][]byte{}
 	if err := r.db.Select(&bufs, "SELECT data FROM fleet order by symbol asc"); err != nil {
 		return nil, err
@@ -50,3 +66,23 @@ func (r *Repo) GetFleet() ([]*api.Ship, error) {
 	}
 	return ships, nil
 }
+
+// GetAgentFleet returns the ships belonging to agent.
+func (r *Repo) GetAgentFleet(agent string) ([]*api.Ship, error) {
+	defer r.trace("GetAgentFleet")()
+
+	bufs := [][]byte{}
+	if err := r.db.Select(&bufs, "SELECT data FROM fleet WHERE agent = ? order by symbol asc", agent); err != nil {
+		return nil, err
+	}
+
+	ships := make([]*api.Ship, len(bufs))
+	for i := range bufs {
+		s := &api.Ship{}
+		if err := s.UnmarshalJSON(bufs[i]); err != nil {
+			return nil, err
+		}
+		ships[i] = s
+	}
+	return ships, nil
+}
@@ -3,7 +3,9 @@ package repo
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/utils"
@@ -11,6 +13,8 @@ import (
 )
 
 func (r *Repo) UpsertMarket(market api.Market) error {
+	defer r.trace("UpsertMarket")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -36,11 +40,23 @@ func (r *Repo) UpsertMarket(market api.Market) error {
 		}
 		defer upsertMarketStmt.Close()
 
+		// every priced scan is also kept as a snapshot for the backtester
+		snapshotStmt, err := tx.Prepare("INSERT INTO market_snapshots (waypoint, good, type, supply, volume, activity, bid, ask, recorded_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer snapshotStmt.Close()
+
+		recordedAt := time.Now().UTC()
 		for _, tg := range market.TradeGoods {
 			_, err = upsertMarketStmt.Exec(market.Symbol, tg.Symbol, tg.Type, tg.TradeVolume, tg.Activity.Value, tg.PurchasePrice, tg.SellPrice)
 			if err != nil {
 				return err
 			}
+			_, err = snapshotStmt.Exec(market.Symbol, tg.Symbol, tg.Type, tg.Supply, tg.TradeVolume, tg.Activity.Value, tg.PurchasePrice, tg.SellPrice, recordedAt)
+			if err != nil {
+				return errors.Wrap(err, "insert market snapshot")
+			}
 		}
 	} else {
 		upsertMarketStmt, err := tx.Prepare("INSERT OR IGNORE INTO markets (waypoint, good, type) values (?, ?, ?)")
@@ -73,10 +89,14 @@ func (r *Repo) UpsertMarket(market api.Market) error {
 
 	tx.Commit()
 
+	r.notify(func(o Observer) { o.MarketUpserted(market) })
+
 	return nil
 }
 
 func (r *Repo) UpsertTradeGoods(goods []api.TradeGood) error {
+	defer r.trace("UpsertTradeGoods")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -104,6 +124,7 @@ func (r *Repo) UpsertTradeGoods(goods []api.TradeGood) error {
 // be sold at. Markets are returned with the top result accepting the most
 // goods, and the bottom accepting the least goods.
 func (r *Repo) FindMarketsForGoods(goods []string) ([]string, error) {
+	defer r.trace("FindMarketsForGoods")()
 
 	params := strings.Join(strings.Split(strings.Repeat("?", len(goods)), ""), ", ")
 	sql := fmt.Sprintf(`WITH results AS (
@@ -150,6 +171,7 @@ SELECT waypoint FROM results`, params)
 // be sold at. Markets are returned with the top result accepting the most
 // goods, and the bottom accepting the least goods.
 func (r *Repo) FindMarketsWithGoods(goods []string) ([]string, error) {
+	defer r.trace("FindMarketsWithGoods")()
 
 	params := strings.Join(strings.Split(strings.Repeat("?", len(goods)), ""), ", ")
 	sql := fmt.Sprintf(`with results as (SELECT waypoint, count(*) as sellables from markets where type = 'IMPORT' and good in (%s) group by waypoint order by sellables desc) select waypoint from results`, params)
@@ -184,6 +206,8 @@ func (r *Repo) FindMarketsWithGoods(goods []string) ([]string, error) {
 
 // WaypointHasGood returns true if the given waypoint provides the given good
 func (r *Repo) WaypointHasGood(waypoint string, good string) (bool, error) {
+	defer r.trace("WaypointHasGood")()
+
 	rows, err := r.db.Query(`select waypoint from markets where good = ? and waypoint = ? order by ask asc`, good, waypoint)
 	if err != nil {
 		return false, err
@@ -197,6 +221,8 @@ func (r *Repo) WaypointHasGood(waypoint string, good string) (bool, error) {
 
 // FindBuyWaypointsForGood returns a list of markets which are exporting the provided good ordered by ask price asc.
 func (r *Repo) FindBuyWaypointsForGood(good string) ([]string, error) {
+	defer r.trace("FindBuyWaypointsForGood")()
+
 	rows, err := r.db.Query(`select waypoint from markets where good = ? and type in ('EXPORT','EXCHANGE') order by ask asc`, good)
 	if err != nil {
 		return nil, err
@@ -236,55 +262,175 @@ type MarketTrade struct {
 	Distance int
 }
 
-// FindMarketTrades
-func (r *Repo) FindMarketTrades() ([]MarketTrade, error) {
-	rows, err := r.db.Query(`SELECT 
-	im.good,
-	ex.waypoint AS origin,
-	im.ask - ex.bid AS gross,	
-	ex.bid,
-	wex.x AS ox,
-	wex.y AS oy,
-	im.waypoint AS dest,
-	im.ask,
-	wim.x AS dx,
-	wim.y AS dy
-FROM markets im
-JOIN markets ex ON im.good = ex.good AND im.type = 'IMPORT' AND ex.type = 'EXPORT' AND im.bid IS NOT NULL AND ex.ask IS NOT NULL
-JOIN waypoints wim ON wim.symbol = im.waypoint
-JOIN waypoints wex ON wex.symbol = ex.waypoint
-WHERE gross > 100
-ORDER BY gross DESC;`)
-	if err != nil {
-		return nil, err
+// minTradeGross is the smallest per unit margin worth flying for.
+const minTradeGross = 100
+
+// MarketListing is one good's prices at a market, as stored in markets and
+// market_snapshots.
+type MarketListing struct {
+	Waypoint string `db:"waypoint"`
+	Good     string `db:"good"`
+	Type     string `db:"type"`
+	Supply   string `db:"supply"`
+	Volume   int    `db:"volume"`
+	Activity string `db:"activity"`
+	Bid      int    `db:"bid"`
+	Ask      int    `db:"ask"`
+}
+
+// MarketSnapshot is a listing as it was seen at RecordedAt.
+type MarketSnapshot struct {
+	MarketListing
+	RecordedAt time.Time `db:"recorded_at"`
+}
+
+// Coords is a waypoint's position in its system.
+type Coords struct {
+	X int `db:"x"`
+	Y int `db:"y"`
+}
+
+// RankMarketTrades pairs every export with every import of the same good and
+// returns the pairs worth more than minTradeGross per unit, best first. It's
+// the ranking behind FindMarketTrades, split out so the backtester can run it
+// over historical listings. Listings at waypoints missing from coords are
+// skipped.
+func RankMarketTrades(listings []MarketListing, coords map[string]Coords) []MarketTrade {
+	exports := map[string][]MarketListing{}
+	for _, l := range listings {
+		if l.Type == "EXPORT" {
+			exports[l.Good] = append(exports[l.Good], l)
+		}
 	}
-	defer rows.Close()
 
 	trades := []MarketTrade{}
-	for rows.Next() {
-		mt := MarketTrade{}
-		if err := rows.Scan(
-			&mt.Good,
-			&mt.Origin,
-			&mt.Gross,
-			&mt.Bid,
-			&mt.OriginX,
-			&mt.OriginY,
-			&mt.Dest,
-			&mt.Ask,
-			&mt.DestX,
-			&mt.DestY,
-		); err != nil {
-			log.Fatal(err)
+	for _, im := range listings {
+		if im.Type != "IMPORT" {
+			continue
+		}
+		dest, ok := coords[im.Waypoint]
+		if !ok {
+			continue
+		}
+		for _, ex := range exports[im.Good] {
+			origin, ok := coords[ex.Waypoint]
+			if !ok {
+				continue
+			}
+			gross := im.Ask - ex.Bid
+			if gross <= minTradeGross {
+				continue
+			}
+			trades = append(trades, MarketTrade{
+				Good:     im.Good,
+				Gross:    gross,
+				Origin:   ex.Waypoint,
+				OriginX:  origin.X,
+				OriginY:  origin.Y,
+				Bid:      ex.Bid,
+				Dest:     im.Waypoint,
+				DestX:    dest.X,
+				DestY:    dest.Y,
+				Ask:      im.Ask,
+				Distance: utils.Distance2dInt(origin.X, origin.Y, dest.X, dest.Y),
+			})
 		}
+	}
+
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Gross > trades[j].Gross
+	})
+
+	return trades
+}
 
-		mt.Distance = utils.Distance2dInt(mt.OriginX, mt.OriginY, mt.DestX, mt.DestY)
-		trades = append(trades, mt)
+// GetWaypointCoords returns the position of every known waypoint.
+func (r *Repo) GetWaypointCoords() (map[string]Coords, error) {
+	defer r.trace("GetWaypointCoords")()
+
+	rows := []struct {
+		Symbol string `db:"symbol"`
+		Coords
+	}{}
+	if err := r.db.Select(&rows, `SELECT symbol, x, y FROM waypoints`); err != nil {
+		return nil, err
 	}
-	// Check for errors from iterating over rows.
-	if err := rows.Err(); err != nil {
+
+	coords := make(map[string]Coords, len(rows))
+	for _, row := range rows {
+		coords[row.Symbol] = row.Coords
+	}
+	return coords, nil
+}
+
+// GetMarketSnapshots returns every recorded listing, oldest first.
+func (r *Repo) GetMarketSnapshots() ([]MarketSnapshot, error) {
+	defer r.trace("GetMarketSnapshots")()
+
+	snapshots := []MarketSnapshot{}
+	err := r.db.Select(&snapshots, `SELECT 
+	waypoint, 
+	good, 
+	type, 
+	coalesce(supply, '') AS supply, 
+	coalesce(volume, 0) AS volume, 
+	coalesce(activity, '') AS activity, 
+	bid, 
+	ask, 
+	recorded_at 
+FROM market_snapshots 
+WHERE bid IS NOT NULL AND ask IS NOT NULL 
+ORDER BY recorded_at`)
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// GetMarketListings returns every good at every known market. Listings that
+// haven't been priced by a ship at the market have a zero Bid and Ask.
+func (r *Repo) GetMarketListings() ([]MarketListing, error) {
+	defer r.trace("GetMarketListings")()
+
+	listings := []MarketListing{}
+	err := r.db.Select(&listings, `SELECT 
+	waypoint, 
+	good, 
+	type, 
+	coalesce(volume, 0) AS volume, 
+	coalesce(activity, '') AS activity, 
+	coalesce(bid, 0) AS bid, 
+	coalesce(ask, 0) AS ask 
+FROM markets`)
+	if err != nil {
+		return nil, err
+	}
+	return listings, nil
+}
+
+// FindMarketTrades ranks the trades available at the latest market prices.
+func (r *Repo) FindMarketTrades() ([]MarketTrade, error) {
+	defer r.trace("FindMarketTrades")()
+
+	listings := []MarketListing{}
+	err := r.db.Select(&listings, `SELECT 
+	waypoint, 
+	good, 
+	type, 
+	coalesce(volume, 0) AS volume, 
+	coalesce(activity, '') AS activity, 
+	bid, 
+	ask 
+FROM markets 
+WHERE bid IS NOT NULL AND ask IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+
+	coords, err := r.GetWaypointCoords()
+	if err != nil {
 		return nil, err
 	}
 
-	return trades, nil
+	return RankMarketTrades(listings, coords), nil
 }
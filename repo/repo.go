@@ -1,13 +1,43 @@
 package repo
 
 import (
+	"context"
+	"sync"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/models"
+	"github.com/bwiggs/spacetraders-go/telemetry"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/viper"
 )
 
 type Repo struct {
-	db *sqlx.DB
+	db  *sqlx.DB
+	ctx context.Context
+
+	// waypoints memoizes decoded GetWaypoints results, shared by every repo
+	// made WithContext, as are the observers.
+	waypoints *waypointCache
+	observers *observers
+}
+
+// Observer is told about systems, waypoints and markets after their upsert
+// is committed, e.g. to keep an in-memory copy in step with the database.
+type Observer interface {
+	SystemsUpserted(systems []api.System)
+	WaypointsUpserted(waypoints []api.Waypoint)
+	MarketUpserted(market api.Market)
+}
+
+type observers struct {
+	mu   sync.RWMutex
+	list []Observer
+}
+
+type waypointCache struct {
+	mu       sync.Mutex
+	bySystem map[string][]*models.Waypoint
 }
 
 var repo *Repo
@@ -29,10 +59,41 @@ func NewRepo(dbPath string) (*Repo, error) {
 		return nil, err
 	}
 	return &Repo{
-		db: db,
+		db:        db,
+		ctx:       context.Background(),
+		waypoints: &waypointCache{bySystem: map[string][]*models.Waypoint{}},
+		observers: &observers{},
 	}, nil
 }
 
+// WithContext returns a repo sharing r's database whose queries are traced
+// as children of ctx's span, e.g. the behavior tick that made them.
+func (r *Repo) WithContext(ctx context.Context) *Repo {
+	return &Repo{db: r.db, ctx: ctx, waypoints: r.waypoints, observers: r.observers}
+}
+
+// Observe registers o for every later upsert through r or any repo made
+// from it WithContext.
+func (r *Repo) Observe(o Observer) {
+	r.observers.mu.Lock()
+	defer r.observers.mu.Unlock()
+	r.observers.list = append(r.observers.list, o)
+}
+
+func (r *Repo) notify(fn func(Observer)) {
+	r.observers.mu.RLock()
+	defer r.observers.mu.RUnlock()
+	for _, o := range r.observers.list {
+		fn(o)
+	}
+}
+
+// trace starts a span for a repo query, call the returned func when it's done.
+func (r *Repo) trace(query string) func() {
+	_, span := telemetry.Tracer().Start(r.ctx, "repo."+query)
+	return func() { span.End() }
+}
+
 func (repo *Repo) Close() {
 	repo.db.Close()
 }
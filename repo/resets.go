@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/db"
+)
+
+// Migrate brings the database schema up to date.
+func (r *Repo) Migrate() error {
+	return db.Migrate(r.db.DB)
+}
+
+// GetResetDate returns the server reset this database was built for, or an
+// empty string if it has never seen one.
+func (r *Repo) GetResetDate() (string, error) {
+	defer r.trace("GetResetDate")()
+
+	var date string
+	err := r.db.Get(&date, `SELECT reset_date FROM server_resets ORDER BY seen_at DESC LIMIT 1`)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return date, err
+}
+
+func (r *Repo) AddResetDate(date string) error {
+	defer r.trace("AddResetDate")()
+
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO server_resets (reset_date, seen_at) VALUES (?, ?)`, date, time.Now().UTC())
+	return err
+}
+
+// UpsertAgentToken stores the token an agent was registered with.
+func (r *Repo) UpsertAgentToken(symbol, faction, token string) error {
+	defer r.trace("UpsertAgentToken")()
+
+	_, err := r.db.Exec(`INSERT OR REPLACE INTO agent_tokens (symbol, faction, token, created_at) VALUES (?, ?, ?, ?)`, symbol, faction, token, time.Now().UTC())
+	return err
+}
+
+// GetAgentToken returns the stored token for symbol, or an empty string.
+func (r *Repo) GetAgentToken(symbol string) (string, error) {
+	defer r.trace("GetAgentToken")()
+
+	var token string
+	err := r.db.Get(&token, `SELECT token FROM agent_tokens WHERE symbol = ?`, symbol)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return token, err
+}
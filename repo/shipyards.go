@@ -6,6 +6,8 @@ import (
 )
 
 func (r *Repo) UpsertShipyard(shipyard api.Shipyard) (err error) {
+	defer r.trace("UpsertShipyard")()
+
 	// more details
 	if shipyard.Ships != nil {
 		err = r.UpsertShips(shipyard.Ships)
@@ -34,6 +36,8 @@ func (r *Repo) UpsertShipyard(shipyard api.Shipyard) (err error) {
 }
 
 func (r *Repo) UpsertShipTypes(shipTypes []api.ShipyardShipTypesItem) error {
+	defer r.trace("UpsertShipTypes")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -57,6 +61,8 @@ func (r *Repo) UpsertShipTypes(shipTypes []api.ShipyardShipTypesItem) error {
 }
 
 func (r *Repo) UpsertShips(ships []api.ShipyardShip) error {
+	defer r.trace("UpsertShips")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -81,6 +87,8 @@ func (r *Repo) UpsertShips(ships []api.ShipyardShip) error {
 }
 
 func (r *Repo) UpsertShipyardShips(waypoint string, ships []api.ShipyardShip) error {
+	defer r.trace("UpsertShipyardShips")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -104,6 +112,8 @@ func (r *Repo) UpsertShipyardShips(waypoint string, ships []api.ShipyardShip) er
 }
 
 func (r *Repo) UpsertShipyardShipType(waypoint string, ships []api.ShipyardShipTypesItem) error {
+	defer r.trace("UpsertShipyardShipType")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -8,6 +8,8 @@ import (
 )
 
 func (r *Repo) UpsertSystems(systems []api.System) error {
+	defer r.trace("UpsertSystems")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return errors.Wrap(err, "UpsertSystems: failed to start tx")
@@ -28,10 +30,14 @@ func (r *Repo) UpsertSystems(systems []api.System) error {
 
 	tx.Commit()
 
+	r.notify(func(o Observer) { o.SystemsUpserted(systems) })
+
 	return nil
 }
 
 func (r *Repo) GetSystems() ([]models.System, error) {
+	defer r.trace("GetSystems")()
+
 	systems := []models.System{}
 	err := r.db.Select(&systems, "SELECT symbol, constellation, name, type, sector_symbol, x, y FROM systems")
 	if err != nil {
@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TickTrace is one behavior tree tick of a ship: the nodes it visited, in
+// the order they were entered, and the blackboard fields it changed.
+type TickTrace struct {
+	Ship      string
+	Mission   string
+	StartedAt time.Time
+	Duration  time.Duration
+	Status    string
+	Nodes     []TickNode
+	Changes   []FieldChange
+}
+
+// TickNode is a node visited during a tick and the status it returned.
+type TickNode struct {
+	Path     string        `json:"path"`
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Depth is how far below the root of the tree the node is, 0 for the root.
+func (n TickNode) Depth() int {
+	return strings.Count(n.Path, ".")
+}
+
+// FieldChange is a blackboard field a tick changed.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type tickTraceRow struct {
+	Ship      string    `db:"ship"`
+	Mission   string    `db:"mission"`
+	StartedAt time.Time `db:"started_at"`
+	Duration  int64     `db:"duration"`
+	Status    string    `db:"status"`
+	Nodes     string    `db:"nodes"`
+	Changes   string    `db:"changes"`
+}
+
+// InsertTickTrace stores t and drops the ship's older traces beyond the
+// most recent keep.
+func (r *Repo) InsertTickTrace(t TickTrace, keep int) error {
+	defer r.trace("InsertTickTrace")()
+
+	nodes, err := json.Marshal(t.Nodes)
+	if err != nil {
+		return errors.Wrap(err, "marshal tick nodes")
+	}
+	changes, err := json.Marshal(t.Changes)
+	if err != nil {
+		return errors.Wrap(err, "marshal tick changes")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO tick_traces (ship, mission, started_at, duration, status, nodes, changes) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.Ship, t.Mission, t.StartedAt.UTC(), int64(t.Duration), t.Status, string(nodes), string(changes))
+	if err != nil {
+		return errors.Wrap(err, "insert tick trace")
+	}
+
+	_, err = tx.Exec(`DELETE FROM tick_traces 
+WHERE ship = ? 
+AND id <= (SELECT id FROM tick_traces WHERE ship = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`, t.Ship, t.Ship, keep)
+	if err != nil {
+		return errors.Wrap(err, "trim tick traces")
+	}
+
+	return tx.Commit()
+}
+
+// GetTickTraces returns ship's stored traces of the ticks started between
+// from and to, oldest first.
+func (r *Repo) GetTickTraces(ship string, from, to time.Time) ([]TickTrace, error) {
+	defer r.trace("GetTickTraces")()
+
+	rows := []tickTraceRow{}
+	err := r.db.Select(&rows, `SELECT 
+	ship, 
+	mission, 
+	started_at, 
+	duration, 
+	status, 
+	nodes, 
+	changes 
+FROM tick_traces 
+WHERE ship = ? AND started_at >= ? AND started_at <= ? 
+ORDER BY started_at, id`, ship, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	traces := make([]TickTrace, 0, len(rows))
+	for _, row := range rows {
+		t, err := row.trace()
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, t)
+	}
+	return traces, nil
+}
+
+// GetLastTickTrace returns ship's most recent stored trace, or nil if none
+// is stored.
+func (r *Repo) GetLastTickTrace(ship string) (*TickTrace, error) {
+	defer r.trace("GetLastTickTrace")()
+
+	var row tickTraceRow
+	err := r.db.Get(&row, `SELECT 
+	ship, 
+	mission, 
+	started_at, 
+	duration, 
+	status, 
+	nodes, 
+	changes 
+FROM tick_traces 
+WHERE ship = ? 
+ORDER BY id DESC 
+LIMIT 1`, ship)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := row.trace()
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (row tickTraceRow) trace() (TickTrace, error) {
+	t := TickTrace{
+		Ship:      row.Ship,
+		Mission:   row.Mission,
+		StartedAt: row.StartedAt,
+		Duration:  time.Duration(row.Duration),
+		Status:    row.Status,
+	}
+	if err := json.Unmarshal([]byte(row.Nodes), &t.Nodes); err != nil {
+		return t, errors.Wrap(err, "unmarshal tick nodes")
+	}
+	if err := json.Unmarshal([]byte(row.Changes), &t.Changes); err != nil {
+		return t, errors.Wrap(err, "unmarshal tick changes")
+	}
+	return t, nil
+}
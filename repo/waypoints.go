@@ -1,6 +1,9 @@
 package repo
 
 import (
+	"database/sql"
+	"slices"
+
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/models"
 	_ "github.com/mattn/go-sqlite3"
@@ -8,6 +11,8 @@ import (
 )
 
 func (r *Repo) UpsertWaypoints(wps []api.Waypoint) error {
+	defer r.trace("UpsertWaypoints")()
+
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -56,10 +61,18 @@ func (r *Repo) UpsertWaypoints(wps []api.Waypoint) error {
 
 	tx.Commit()
 
+	r.waypoints.mu.Lock()
+	clear(r.waypoints.bySystem)
+	r.waypoints.mu.Unlock()
+
+	r.notify(func(o Observer) { o.WaypointsUpserted(wps) })
+
 	return nil
 }
 
 func (r *Repo) WaypointHasTrait(waypointSymbol, trait string) (bool, error) {
+	defer r.trace("WaypointHasTrait")()
+
 	sql := `SELECT 1 FROM waypoints_traits wt WHERE wt.waypoint = ? AND wt.trait = ?`
 
 	rows, err := r.db.Query(sql, waypointSymbol, trait)
@@ -72,6 +85,8 @@ func (r *Repo) WaypointHasTrait(waypointSymbol, trait string) (bool, error) {
 }
 
 func (r *Repo) GetSystemWaypointsByTrait(system, trait string) ([]*models.Waypoint, error) {
+	defer r.trace("GetSystemWaypointsByTrait")()
+
 	sql := `select json 
 	from waypoints 
 	join waypoints_traits wt on wt.waypoint = waypoints.symbol 
@@ -108,11 +123,30 @@ func (r *Repo) GetSystemWaypointsByTrait(system, trait string) ([]*models.Waypoi
 	return waypoints, nil
 }
 
+// GetWaypoints returns the waypoints of system, every known waypoint when
+// system is empty. Results are decoded once and kept until the next
+// UpsertWaypoints, callers share them and must not modify the waypoints, only
+// the returned slice.
 func (r *Repo) GetWaypoints(system string) ([]*models.Waypoint, error) {
+	defer r.trace("GetWaypoints")()
+
+	r.waypoints.mu.Lock()
+	defer r.waypoints.mu.Unlock()
+
+	if waypoints, ok := r.waypoints.bySystem[system]; ok {
+		return slices.Clone(waypoints), nil
+	}
+
 	waypoints := []*models.Waypoint{}
 
+	// X1-AB1 mustn't match the waypoints of X1-AB12
+	pattern := "%"
+	if system != "" {
+		pattern = system + "-%"
+	}
+
 	waypointjson := [][]byte{}
-	if err := r.db.Select(&waypointjson, `SELECT json FROM waypoints`); err != nil {
+	if err := r.db.Select(&waypointjson, `SELECT json FROM waypoints WHERE json IS NOT NULL AND symbol LIKE ?`, pattern); err != nil {
 		return nil, err
 	}
 	for _, j := range waypointjson {
@@ -123,7 +157,29 @@ func (r *Repo) GetWaypoints(system string) ([]*models.Waypoint, error) {
 		waypoints = append(waypoints, models.NewWaypoint(&wp))
 	}
 
-	return waypoints, nil
+	r.waypoints.bySystem[system] = waypoints
+
+	return slices.Clone(waypoints), nil
+}
+
+// GetWaypoint returns the stored waypoint, or nil if it hasn't been scanned.
+func (r *Repo) GetWaypoint(symbol string) (*api.Waypoint, error) {
+	defer r.trace("GetWaypoint")()
+
+	var buf []byte
+	err := r.db.Get(&buf, `SELECT json FROM waypoints WHERE symbol = ? AND json IS NOT NULL`, symbol)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "GetWaypoint: query failed")
+	}
+
+	wp := &api.Waypoint{}
+	if err := wp.UnmarshalJSON(buf); err != nil {
+		return nil, errors.Wrap(err, "GetWaypoint: failed to unmarshal waypoint")
+	}
+	return wp, nil
 }
 
 func (r *Repo) GetNonOrbitalWaypoints(system string) ([]*models.Waypoint, error) {
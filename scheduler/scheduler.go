@@ -0,0 +1,391 @@
+// Package scheduler runs ship behavior ticks from one timer queue instead of
+// a sleeping goroutine per ship. Tasks say when they next want to run, e.g. a
+// ship's arrival or cooldown expiration, and a bounded pool of workers ticks
+// them as they come due, the most urgent first.
+//
+// The scheduler watches the agent's rate limit pressure: while the request
+// budget is nearly spent, idle polls are held back so the ticks of ships that
+// have just arrived or cooled down get the requests.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/clock"
+)
+
+// Priority orders ticks that are due together, lower runs first.
+type Priority int
+
+const (
+	// PriorityArrival is a ship that just finished a transit.
+	PriorityArrival Priority = iota
+	// PriorityCooldown is a ship whose reactor just cooled down.
+	PriorityCooldown
+	// PriorityPoll is a ship checking in with nothing to wait for.
+	PriorityPoll
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityArrival:
+		return "arrival"
+	case PriorityCooldown:
+		return "cooldown"
+	case PriorityPoll:
+		return "poll"
+	}
+	return "unknown"
+}
+
+const (
+	// DefaultWorkers is how many ticks run at once.
+	DefaultWorkers = 8
+	// DefaultThrottle is the rate limit pressure above which polls wait.
+	DefaultThrottle = 0.8
+	// holdBack is how long a throttled poll waits before it's tried again.
+	holdBack = time.Second
+)
+
+// Task is something the scheduler ticks, usually a ship.
+type Task interface {
+	// Name identifies the task, adding a task with the same name replaces it.
+	Name() string
+	// Next returns when the task next wants to tick, and how urgent that
+	// tick is. It's called when the task is added and after every tick.
+	Next() (time.Time, Priority)
+	// Tick runs one pass of the task's behavior.
+	Tick()
+}
+
+// Scheduler ticks tasks when they come due on a fixed pool of workers.
+type Scheduler struct {
+	clock    clock.Clock
+	workers  int
+	pressure func() float64
+
+	// Throttle is the rate limit pressure, from 0 to 1, above which poll
+	// ticks are held back.
+	Throttle float64
+
+	// Recover, when set, is handed a task's panic and the stack it was
+	// raised from, and returns when to tick the task again. Without it a
+	// panicking tick takes the process down.
+	Recover func(t Task, panicked any, stack []byte) time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	waiting queue
+	ready   queue
+	// busy counts the ticks handed to workers and not yet finished
+	busy    int
+	running sync.WaitGroup
+	jobs    chan *entry
+	wake    chan struct{}
+	// stop is closed by Stop, every Start makes a new one for its workers
+	stop    chan struct{}
+	started bool
+}
+
+type entryState int
+
+const (
+	stateWaiting entryState = iota
+	stateReady
+	stateRunning
+)
+
+type entry struct {
+	task     Task
+	at       time.Time
+	priority Priority
+	state    entryState
+	removed  bool
+	index    int
+}
+
+// New returns a scheduler with workers workers, DefaultWorkers when it's not
+// positive. pressure reports the rate limit pressure, it may be nil.
+func New(clk clock.Clock, workers int, pressure func() float64) *Scheduler {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if pressure == nil {
+		pressure = func() float64 { return 0 }
+	}
+	return &Scheduler{
+		clock:    clock.Or(clk),
+		workers:  workers,
+		pressure: pressure,
+		Throttle: DefaultThrottle,
+		entries:  map[string]*entry{},
+		waiting: queue{less: func(a, b *entry) bool {
+			return a.at.Before(b.at)
+		}},
+		ready: queue{less: func(a, b *entry) bool {
+			if a.priority != b.priority {
+				return a.priority < b.priority
+			}
+			return a.at.Before(b.at)
+		}},
+		jobs: make(chan *entry),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Clock returns the clock tasks are scheduled on.
+func (s *Scheduler) Clock() clock.Clock {
+	return s.clock
+}
+
+// Add schedules t for when it next wants to tick.
+func (s *Scheduler) Add(t Task) {
+	at, prio := t.Next()
+
+	s.mu.Lock()
+	if old, ok := s.entries[t.Name()]; ok {
+		s.remove(old)
+	}
+	e := &entry{task: t, at: at, priority: prio}
+	s.entries[t.Name()] = e
+	heap.Push(&s.waiting, e)
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Remove stops ticking the task with name. A tick already running finishes.
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[name]; ok {
+		s.remove(e)
+	}
+}
+
+func (s *Scheduler) remove(e *entry) {
+	delete(s.entries, e.task.Name())
+	e.removed = true
+	switch e.state {
+	case stateWaiting:
+		heap.Remove(&s.waiting, e.index)
+	case stateReady:
+		heap.Remove(&s.ready, e.index)
+	}
+}
+
+// Len returns the number of scheduled tasks.
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Start runs the workers and the dispatcher until Stop. A stopped scheduler
+// can be started again, with the tasks it still holds.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.stop = make(chan struct{})
+
+	for range s.workers {
+		go s.work(s.stop)
+	}
+	go s.dispatch(s.stop)
+}
+
+// Stop stops dispatching ticks and waits for the ticks already running to
+// finish, or for ctx to be done. No tick is dispatched once Stop is called,
+// so every tick it waits for was already running.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.started = false
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// dispatch moves tasks that have come due into the ready queue and hands the
+// most urgent to idle workers, then sleeps until the next one comes due or a
+// task or worker changes things. It runs until stop is closed.
+func (s *Scheduler) dispatch(stop chan struct{}) {
+	for {
+		s.mu.Lock()
+		// checked under the lock Stop closes stop with, so running is never
+		// added to once Stop has started waiting on it
+		if !s.started || s.stop != stop {
+			s.mu.Unlock()
+			return
+		}
+		now := s.clock.Now()
+		for s.waiting.Len() > 0 && !s.waiting.items[0].at.After(now) {
+			e := heap.Pop(&s.waiting).(*entry)
+			e.state = stateReady
+			heap.Push(&s.ready, e)
+		}
+
+		var dispatched []*entry
+		pressure := -1.0
+		for s.busy < s.workers && s.ready.Len() > 0 {
+			e := heap.Pop(&s.ready).(*entry)
+			if e.priority >= PriorityPoll && pressure < 0 {
+				pressure = s.pressure()
+			}
+			if e.priority >= PriorityPoll && pressure > s.Throttle {
+				// the budget is nearly spent, leave it to ships with work
+				e.state = stateWaiting
+				e.at = now.Add(holdBack)
+				heap.Push(&s.waiting, e)
+				continue
+			}
+			e.state = stateRunning
+			s.busy++
+			s.running.Add(1)
+			dispatched = append(dispatched, e)
+		}
+
+		var timer clock.Timer
+		var due <-chan time.Time
+		if s.waiting.Len() > 0 {
+			timer = s.clock.NewTimer(s.waiting.items[0].at.Sub(now))
+			due = timer.Chan()
+		}
+		s.mu.Unlock()
+
+		for i, e := range dispatched {
+			select {
+			case s.jobs <- e:
+			case <-stop:
+				s.mu.Lock()
+				for _, e := range dispatched[i:] {
+					s.busy--
+					s.requeue(e, e.at, e.priority)
+					s.running.Done()
+				}
+				s.mu.Unlock()
+				return
+			}
+		}
+
+		select {
+		case <-due:
+		case <-s.wake:
+		case <-stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (s *Scheduler) work(stop chan struct{}) {
+	for {
+		select {
+		case e := <-s.jobs:
+			s.tick(e)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// tick runs e's task and queues it again for when it next wants to run.
+func (s *Scheduler) tick(e *entry) {
+	defer s.running.Done()
+
+	at, prio, ok := s.run(e.task)
+	if !ok {
+		at, prio = e.task.Next()
+	}
+
+	s.mu.Lock()
+	s.busy--
+	s.requeue(e, at, prio)
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// requeue puts e back in the waiting queue for at, unless it was removed.
+func (s *Scheduler) requeue(e *entry, at time.Time, prio Priority) {
+	if !e.removed {
+		e.at, e.priority, e.state = at, prio, stateWaiting
+		heap.Push(&s.waiting, e)
+	}
+}
+
+// run ticks t, when it panics and there's a Recover it returns when Recover
+// wants t ticked again, as a poll.
+func (s *Scheduler) run(t Task) (at time.Time, prio Priority, recovered bool) {
+	if s.Recover != nil {
+		defer func() {
+			if p := recover(); p != nil {
+				at, prio, recovered = s.Recover(t, p, debug.Stack()), PriorityPoll, true
+			}
+		}()
+	}
+	t.Tick()
+	return
+}
+
+// queue is a heap of entries ordered by less.
+type queue struct {
+	items []*entry
+	less  func(a, b *entry) bool
+}
+
+func (q queue) Len() int           { return len(q.items) }
+func (q queue) Less(i, j int) bool { return q.less(q.items[i], q.items[j]) }
+
+func (q queue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	e := x.(*entry)
+	e.index = len(q.items)
+	q.items = append(q.items, e)
+}
+
+func (q *queue) Pop() any {
+	n := len(q.items)
+	e := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return e
+}
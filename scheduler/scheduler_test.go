@@ -0,0 +1,251 @@
+package scheduler
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwiggs/spacetraders-go/clock"
+)
+
+var epoch = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// testTask ticks once at its time, and then wants nothing until far in the
+// future. Every tick is reported on ticks, and waits for release when set.
+type testTask struct {
+	name    string
+	at      time.Time
+	prio    Priority
+	ticks   chan<- string
+	release chan struct{}
+
+	mu     sync.Mutex
+	ticked bool
+}
+
+func (t *testTask) Name() string { return t.name }
+
+func (t *testTask) Next() (time.Time, Priority) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ticked {
+		return t.at.Add(24 * time.Hour), PriorityPoll
+	}
+	return t.at, t.prio
+}
+
+func (t *testTask) Tick() {
+	t.mu.Lock()
+	t.ticked = true
+	t.mu.Unlock()
+
+	t.ticks <- t.name
+	if t.release != nil {
+		<-t.release
+	}
+}
+
+func receive(t *testing.T, ticks <-chan string) string {
+	t.Helper()
+	select {
+	case name := <-ticks:
+		return name
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a tick")
+		return ""
+	}
+}
+
+func stop(t *testing.T, s *Scheduler) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
+
+func TestPriorityOrder(t *testing.T) {
+	type task struct {
+		name   string
+		offset time.Duration
+		prio   Priority
+	}
+
+	tests := []struct {
+		name  string
+		tasks []task
+		want  []string
+	}{
+		{
+			name: "arrival before cooldown before poll",
+			tasks: []task{
+				{"poll", 0, PriorityPoll},
+				{"cooldown", 0, PriorityCooldown},
+				{"arrival", 0, PriorityArrival},
+			},
+			want: []string{"arrival", "cooldown", "poll"},
+		},
+		{
+			name: "longest due first within a priority",
+			tasks: []task{
+				{"b", -2 * time.Second, PriorityPoll},
+				{"c", -1 * time.Second, PriorityPoll},
+				{"a", -3 * time.Second, PriorityPoll},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "priority before age",
+			tasks: []task{
+				{"old poll", -time.Hour, PriorityPoll},
+				{"cooldown", -time.Minute, PriorityCooldown},
+				{"arrival", 0, PriorityArrival},
+			},
+			want: []string{"arrival", "cooldown", "old poll"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(epoch)
+			ticks := make(chan string, len(tt.tasks))
+
+			// one worker, so ticks run one at a time in dispatch order
+			s := New(clk, 1, nil)
+			for _, task := range tt.tasks {
+				s.Add(&testTask{name: task.name, at: epoch.Add(task.offset), prio: task.prio, ticks: ticks})
+			}
+			s.Start()
+			defer stop(t, s)
+
+			for i, want := range tt.want {
+				if got := receive(t, ticks); got != want {
+					t.Fatalf("tick %d: got %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestThrottledPolls(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	ticks := make(chan string, 2)
+
+	var pressure atomic.Uint64
+	pressure.Store(math.Float64bits(0.9))
+
+	s := New(clk, 2, func() float64 { return math.Float64frombits(pressure.Load()) })
+	s.Add(&testTask{name: "poll", at: epoch, prio: PriorityPoll, ticks: ticks})
+	s.Add(&testTask{name: "cooldown", at: epoch, prio: PriorityCooldown, ticks: ticks})
+	s.Start()
+	defer stop(t, s)
+
+	if got := receive(t, ticks); got != "cooldown" {
+		t.Fatalf("got %s, want cooldown to run under pressure", got)
+	}
+
+	// still under pressure, the poll is held back again
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := clk.BlockUntilContext(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(holdBack)
+	select {
+	case name := <-ticks:
+		t.Fatalf("%s ticked while the budget was spent", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pressure.Store(math.Float64bits(0.5))
+	if err := clk.BlockUntilContext(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(holdBack)
+	if got := receive(t, ticks); got != "poll" {
+		t.Fatalf("got %s, want the poll once pressure dropped", got)
+	}
+}
+
+func TestChangeDuringTick(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(s *Scheduler, ticks chan<- string) *testTask
+	}{
+		{
+			name: "remove",
+			change: func(s *Scheduler, ticks chan<- string) *testTask {
+				s.Remove("ship")
+				return nil
+			},
+		},
+		{
+			name: "replace",
+			change: func(s *Scheduler, ticks chan<- string) *testTask {
+				replacement := &testTask{name: "ship", at: epoch.Add(time.Minute), prio: PriorityArrival, ticks: ticks}
+				s.Add(replacement)
+				return replacement
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(epoch)
+			ticks := make(chan string, 1)
+			release := make(chan struct{})
+
+			s := New(clk, 1, nil)
+			s.Add(&testTask{name: "ship", at: epoch, prio: PriorityPoll, ticks: ticks, release: release})
+			s.Start()
+
+			receive(t, ticks)
+			want := tt.change(s, ticks)
+			close(release)
+
+			// Stop returns once the running tick has been requeued or dropped
+			stop(t, s)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			var queued []*entry
+			queued = append(queued, s.waiting.items...)
+			queued = append(queued, s.ready.items...)
+
+			if want == nil {
+				if len(queued) != 0 || len(s.entries) != 0 {
+					t.Fatalf("removed task still scheduled: %d queued, %d entries", len(queued), len(s.entries))
+				}
+				return
+			}
+			if len(queued) != 1 || queued[0].task != want {
+				t.Fatalf("want only the replacement queued, got %d entries", len(queued))
+			}
+			if !queued[0].at.Equal(want.at) || queued[0].priority != PriorityArrival {
+				t.Fatalf("replacement queued at %s as %s, want %s as arrival", queued[0].at, queued[0].priority, want.at)
+			}
+		})
+	}
+}
+
+func TestRestart(t *testing.T) {
+	clk := clock.NewFake(epoch)
+	ticks := make(chan string, 1)
+
+	s := New(clk, 1, nil)
+	s.Start()
+	stop(t, s)
+
+	s.Add(&testTask{name: "ship", at: epoch, prio: PriorityArrival, ticks: ticks})
+	s.Start()
+	defer stop(t, s)
+
+	if got := receive(t, ticks); got != "ship" {
+		t.Fatalf("got %s, want ship ticked after a restart", got)
+	}
+}
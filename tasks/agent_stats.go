@@ -6,19 +6,21 @@ import (
 	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/events"
 	"github.com/go-faster/errors"
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 )
 
-func LogAgentMetrics(client api.Invoker) error {
-	client.GetAgent(context.TODO(), api.GetAgentParams{})
-	dat, err := client.GetMyAgent(context.TODO())
+func LogAgentMetrics(ctx context.Context, client api.Invoker) error {
+	client.GetAgent(backgroundContext(ctx), api.GetAgentParams{})
+	dat, err := client.GetMyAgent(backgroundContext(ctx))
 	if err != nil {
 		slog.Error(errors.Wrap(err, "failed to fetch my agent call").Error())
 		return err
 	}
 
 	slog.Info("LogAgentMetrics", "credits", dat.Data.Credits)
+	events.Publish(bus, events.CreditsChanged{Agent: dat.Data.Symbol, Credits: dat.Data.Credits})
 
 	err = postInflux("agent", "credits", dat.Data.Credits)
 	if err != nil {
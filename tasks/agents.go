@@ -6,34 +6,26 @@ import (
 	"log/slog"
 
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client/paginate"
 	"github.com/bwiggs/spacetraders-go/repo"
 	"github.com/go-faster/errors"
 )
 
-func UpdateAgents(client api.Invoker, repo *repo.Repo) error {
+func UpdateAgents(ctx context.Context, client api.Invoker, repo *repo.Repo) error {
+	repo = repo.WithContext(ctx)
 	log := slog.With("job", "UpdateAgents")
 
-	ctx := context.TODO()
-	page := 1
-	limit := 20
-	for {
-		log.Info(fmt.Sprintf("updating agents: page %d", page), "page", page)
-		res, err := client.GetAgents(ctx, api.GetAgentsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+	for page, err := range paginate.Pages(backgroundContext(ctx), paginate.Agents(client)) {
 		if err != nil {
 			return errors.Wrap(err, "UpdateAgents: failed get agents")
 		}
-		if len(res.Data) == 0 || len(res.Data) < limit {
-			log.Info("done")
-			break
-		}
+		log.Info(fmt.Sprintf("updating agents: page %d", page.Meta.Page), "page", page.Meta.Page)
 
-		if err := repo.UpsertAgents(res.Data); err != nil {
+		if err := repo.UpsertAgents(page.Data); err != nil {
 			return errors.Wrap(err, "UpdateAgents: failed upsert agents")
 		}
-
-		page++
-
 	}
 
+	log.Info("done")
 	return nil
 }
@@ -2,33 +2,39 @@ package tasks
 
 import (
 	"context"
-	"math"
 
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client/paginate"
 	"github.com/pkg/errors"
 )
 
-func GetLatestContract(client api.Invoker) (*api.Contract, error) {
-	page := 1
-	limit := 20
+func GetLatestContract(ctx context.Context, client api.Invoker) (*api.Contract, error) {
+	contracts := paginate.Contracts(client)
 
-	// get the latest contract from the api via the list endpoint
-	res, err := client.GetContracts(context.TODO(), api.GetContractsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
-	if err != nil {
-		return nil, errors.Wrap(err, "GetLatestContract: failed to get contracts")
+	// contracts are listed oldest first, the first page tells us where the
+	// last one is
+	var first paginate.Page[api.Contract]
+	for page, err := range paginate.Pages(ctx, contracts) {
+		if err != nil {
+			return nil, errors.Wrap(err, "GetLatestContract: failed to get contracts")
+		}
+		first = page
+		break
 	}
 
-	if len(res.Data) == 0 {
+	if len(first.Data) == 0 {
 		return nil, nil
 	}
 
-	// get the last page of contracts
-	if res.Meta.Total > limit {
-		page = int(math.Ceil(float64(res.Meta.Total) / float64(limit)))
-		res, err = client.GetContracts(context.TODO(), api.GetContractsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
-		if err != nil {
-			return nil, errors.Wrap(err, "GetLatestContract: failed to get contracts")
+	latest := first.Data[len(first.Data)-1]
+	last := paginate.LastPage(first.Meta)
+	if last > first.Meta.Page {
+		for page, err := range paginate.Pages(ctx, contracts, paginate.StartPage(last)) {
+			if err != nil {
+				return nil, errors.Wrap(err, "GetLatestContract: failed to get contracts")
+			}
+			latest = page.Data[len(page.Data)-1]
 		}
 	}
-	return &res.Data[len(res.Data)-1], nil
+	return &latest, nil
 }
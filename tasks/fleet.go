@@ -4,39 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client/paginate"
 	"github.com/bwiggs/spacetraders-go/repo"
 )
 
-func UpdateFleet(client api.Invoker, repo *repo.Repo) error {
-
-	page := 1
-	limit := 20
-	for {
-		slog.Info(fmt.Sprintf("updating ships: page %d", page), "page", page)
-		res, err := client.GetMyShips(context.TODO(), api.GetMyShipsParams{Page: api.NewOptInt(page), Limit: api.NewOptInt(limit)})
+func UpdateFleet(ctx context.Context, client api.Invoker, repo *repo.Repo) error {
+	repo = repo.WithContext(ctx)
+	for page, err := range paginate.Pages(backgroundContext(ctx), paginate.MyShips(client)) {
 		if err != nil {
 			return err
 		}
+		slog.Info(fmt.Sprintf("updating ships: page %d", page.Meta.Page), "page", page.Meta.Page)
 
-		if len(res.Data) == 0 {
-			break
-		}
-
-		err = repo.UpsertFleet(res.Data)
-		if err != nil {
+		if err := repo.UpsertFleet(page.Data); err != nil {
 			return err
 		}
-
-		if len(res.Data) < limit {
-			break
-		}
-
-		time.Sleep(500 * time.Millisecond)
-
-		page++
 	}
 
 	return nil
@@ -6,30 +6,32 @@ import (
 	"log/slog"
 
 	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/client/paginate"
+	"github.com/bwiggs/spacetraders-go/events"
 	"github.com/bwiggs/spacetraders-go/repo"
 	"github.com/go-faster/errors"
 )
 
-func ScanSystem(client api.Invoker, repo *repo.Repo, system string) error {
+func ScanSystem(ctx context.Context, client api.Invoker, repo *repo.Repo, system string) error {
 	log := slog.With("job", "ScanSystem", "system", system)
 
 	var err error
 
 	log.Info("ScanSystem: " + system)
 	log.Info("ScanSystem: waypoints")
-	err = ScanWaypoints(client, repo, system)
+	err = ScanWaypoints(ctx, client, repo, system)
 	if err != nil {
 		return errors.Wrap(err, "ScanSystem: failed to scan waypoints")
 	}
 
 	log.Info("ScanSystem: markets")
-	err = ScanMarkets(client, repo, system)
+	err = ScanMarkets(ctx, client, repo, system)
 	if err != nil {
 		return errors.Wrap(err, "ScanSystem: failed to scan markets")
 	}
 
 	log.Info("ScanSystem: shipyards")
-	err = ScanShipyards(client, repo, system)
+	err = ScanShipyards(ctx, client, repo, system)
 	if err != nil {
 		return errors.Wrap(err, "ScanSystem: failed to scan shipyards")
 	}
@@ -39,44 +41,34 @@ func ScanSystem(client api.Invoker, repo *repo.Repo, system string) error {
 	return nil
 }
 
-func ScanWaypoints(client api.Invoker, repo *repo.Repo, system string) error {
+func ScanWaypoints(ctx context.Context, client api.Invoker, repo *repo.Repo, system string) error {
+	repo = repo.WithContext(ctx)
 	baselog := slog.With("job", "ScanWaypoints", "system", system)
-	ctx := context.TODO()
-	page := 1
-	limit := 20
-	for {
-		log := baselog.With("page", page, "limit", limit)
-		log.Info("fetching waypoints")
-		params := api.GetSystemWaypointsParams{SystemSymbol: system, Limit: api.NewOptInt(limit), Page: api.NewOptInt(page)}
-		res, err := client.GetSystemWaypoints(ctx, params)
+	for page, err := range paginate.Pages(backgroundContext(ctx), paginate.SystemWaypoints(client, system)) {
 		if err != nil {
 			return errors.Wrap(err, "ScanWaypoints: failed get system waypoints")
 		}
 
-		if len(res.Data) == 0 {
-			log.Info("done")
-			break
-		}
-
-		log.Info(fmt.Sprintf("saving %d waypoints", len(res.Data)))
-		err = repo.UpsertWaypoints(res.Data)
+		log := baselog.With("page", page.Meta.Page, "limit", page.Meta.Limit)
+		log.Info(fmt.Sprintf("saving %d waypoints", len(page.Data)))
+		err = repo.UpsertWaypoints(page.Data)
 		if err != nil {
 			return errors.Wrap(err, "ScanWaypoints: failed get upsert waypoints")
 		}
-		log.Info("page complete")
-		page++
 	}
+	baselog.Info("done")
 	return nil
 }
 
-func ScanMarkets(client api.Invoker, repo *repo.Repo, system string) error {
+func ScanMarkets(ctx context.Context, client api.Invoker, repo *repo.Repo, system string) error {
+	repo = repo.WithContext(ctx)
 	waypoints, err := repo.GetSystemWaypointsByTrait(system, "MARKETPLACE")
 	if err != nil {
 		return err
 	}
 
 	for _, wp := range waypoints {
-		err := ScanMarket(client, repo, wp.Symbol)
+		err := ScanMarket(ctx, client, repo, wp.Symbol)
 		if err != nil {
 			slog.Error(errors.Wrap(err, "failed to scan market").Error())
 		}
@@ -85,10 +77,11 @@ func ScanMarkets(client api.Invoker, repo *repo.Repo, system string) error {
 	return nil
 }
 
-func ScanMarket(client api.Invoker, repo *repo.Repo, wp string) error {
+func ScanMarket(ctx context.Context, client api.Invoker, repo *repo.Repo, wp string) error {
+	repo = repo.WithContext(ctx)
 	slog.Debug("scanning market: " + wp)
 
-	dat, err := client.GetMarket(context.TODO(), api.GetMarketParams{SystemSymbol: wp[:7], WaypointSymbol: wp})
+	dat, err := client.GetMarket(backgroundContext(ctx), api.GetMarketParams{SystemSymbol: wp[:7], WaypointSymbol: wp})
 	if err != nil {
 		return errors.Wrap(err, "ScanMarket: failed get market")
 	}
@@ -97,10 +90,12 @@ func ScanMarket(client api.Invoker, repo *repo.Repo, wp string) error {
 	if err != nil {
 		return errors.Wrap(err, "ScanMarket: failed upsert market")
 	}
+	events.Publish(bus, events.MarketScanned{Market: dat.Data})
 	return nil
 }
 
-func ScanShipyards(client api.Invoker, repo *repo.Repo, system string) error {
+func ScanShipyards(ctx context.Context, client api.Invoker, repo *repo.Repo, system string) error {
+	repo = repo.WithContext(ctx)
 	waypoints, err := repo.GetSystemWaypointsByTrait(system, "SHIPYARD")
 	if err != nil {
 		return errors.Wrap(err, "ScanShipyards: failed to get waypoints")
@@ -112,16 +107,17 @@ func ScanShipyards(client api.Invoker, repo *repo.Repo, system string) error {
 	}
 
 	for _, wp := range waypoints {
-		ScanShipyard(client, repo, wp.Symbol)
+		ScanShipyard(ctx, client, repo, wp.Symbol)
 	}
 
 	return nil
 }
 
-func ScanShipyard(client api.Invoker, repo *repo.Repo, wp string) error {
+func ScanShipyard(ctx context.Context, client api.Invoker, repo *repo.Repo, wp string) error {
+	repo = repo.WithContext(ctx)
 	slog.Info("scanning shipyard: " + wp)
 
-	dat, err := client.GetShipyard(context.TODO(), api.GetShipyardParams{SystemSymbol: wp[:7], WaypointSymbol: wp})
+	dat, err := client.GetShipyard(backgroundContext(ctx), api.GetShipyardParams{SystemSymbol: wp[:7], WaypointSymbol: wp})
 	if err != nil {
 		return errors.Wrap(err, "ScanShipyard: failed to get shipyards")
 	}
@@ -133,26 +129,19 @@ func ScanShipyard(client api.Invoker, repo *repo.Repo, wp string) error {
 	return nil
 }
 
-func UpdateSystems(client api.Invoker, repo *repo.Repo) error {
-
-	page := 1
-	for {
-		slog.Info(fmt.Sprintf("updating systems: page %d", page), "page", page)
-		res, err := client.GetSystems(context.TODO(), api.GetSystemsParams{Limit: api.NewOptInt(20), Page: api.NewOptInt(page)})
+func UpdateSystems(ctx context.Context, client api.Invoker, repo *repo.Repo) error {
+	repo = repo.WithContext(ctx)
+	// thousands of systems, fetch a few pages at a time in the background budget
+	for page, err := range paginate.Pages(backgroundContext(ctx), paginate.Systems(client), paginate.Parallel(4)) {
 		if err != nil {
 			return errors.Wrap(err, "UpdateSystems: failed get systems")
 		}
+		slog.Info(fmt.Sprintf("updating systems: page %d", page.Meta.Page), "page", page.Meta.Page)
 
-		if len(res.Data) == 0 {
-			break
-		}
-
-		err = repo.UpsertSystems(res.Data)
+		err = repo.UpsertSystems(page.Data)
 		if err != nil {
 			return errors.Wrap(err, "UpdateSystems: failed upsert systems")
 		}
-
-		page++
 	}
 
 	return nil
@@ -1,22 +1,52 @@
 package tasks
 
 import (
+	"context"
 	"log/slog"
 	"time"
 
+	"github.com/bwiggs/spacetraders-go/client"
+	"github.com/bwiggs/spacetraders-go/clock"
+	"github.com/bwiggs/spacetraders-go/events"
+	"github.com/bwiggs/spacetraders-go/telemetry"
 	"github.com/go-co-op/gocron/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var s gocron.Scheduler
 
-func SetInterval(fn func(), t time.Duration) {
-	s.NewJob(gocron.DurationJob(t), gocron.NewTask(fn))
-	fn()
+// bus receives the events tasks publish, nil until Start.
+var bus *events.Bus
+
+// backgroundContext tags task requests so they only use the rate budget ships
+// aren't using.
+func backgroundContext(ctx context.Context) context.Context {
+	return client.WithPriority(ctx, client.PriorityBackground)
+}
+
+// SetInterval runs fn now and every t after, each run traced as a span
+// named after the task. fn is handed the span's context, so the api calls
+// and repo queries it makes with it are children of the run.
+func SetInterval(name string, fn func(context.Context), t time.Duration) {
+	task := func() {
+		// runs that come due during an api outage wait for it to recover
+		client.APIBreaker().Wait(context.Background())
+
+		ctx, span := telemetry.Tracer().Start(context.Background(), "task", trace.WithAttributes(attribute.String("task", name)))
+		defer span.End()
+		fn(ctx)
+	}
+	s.NewJob(gocron.DurationJob(t), gocron.NewTask(task), gocron.WithName(name))
+	task()
 }
 
-func Start() (err error) {
+// Start runs the scheduler on clk, so intervals follow a fake clock in
+// simulations. Tasks publish their events to b.
+func Start(clk clock.Clock, b *events.Bus) (err error) {
 	slog.Debug("starting", "system", "Scheduler")
-	s, err = gocron.NewScheduler()
+	bus = b
+	s, err = gocron.NewScheduler(gocron.WithClock(clock.Or(clk)))
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,78 @@
+// Package telemetry sets up OpenTelemetry tracing. Ships open a span per
+// behavior tree tick, with the api calls, rate limit waits and repo queries
+// made during the tick as children. Scheduled tasks get a span per run.
+//
+// ST_TRACE_EXPORTER picks where spans go: "otlp" sends them to a collector
+// over http (configured with the usual OTEL_EXPORTER_OTLP_* variables, by
+// default localhost:4318), "stdout" prints them, and "none", the default,
+// turns tracing off. Any other value is an error.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	name        = "github.com/bwiggs/spacetraders-go"
+	serviceName = "spacetraders"
+)
+
+// Tracer returns the tracer for the bot's own spans. The ogen client traces
+// api calls itself through the global provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Setup installs the exporter chosen by ST_TRACE_EXPORTER as the global
+// tracer provider. The returned function flushes and stops it.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch viper.GetString("TRACE_EXPORTER") {
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("telemetry: unknown ST_TRACE_EXPORTER %q, expected otlp, stdout or none", viper.GetString("TRACE_EXPORTER"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// RecordError marks span as failed with err, if there is one.
+func RecordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/bwiggs/spacetraders-go/kernel"
 	"github.com/bwiggs/spacetraders-go/models"
 	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/bwiggs/spacetraders-go/world"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -32,6 +33,11 @@ type Game struct {
 	settings Settings
 
 	kernel *kernel.Kernel
+
+	// changes to the kernel's state and world, applied in Update so Draw
+	// never races them
+	stateEvents <-chan kernel.StateEvent
+	worldEvents <-chan world.Event
 }
 
 type Settings struct {
@@ -70,6 +76,10 @@ func NewGame(k *kernel.Kernel) *Game {
 			DistanceRings:      colornames.Aqua,
 		},
 	}
+	// the kernel's state follows every response from the ships and tasks, so
+	// the ui redraws from it instead of polling the api
+	g.stateEvents, _ = k.State().Subscribe(64)
+	g.worldEvents, _ = k.World().Subscribe(64)
 
 	return g
 }
@@ -86,6 +96,7 @@ func (g *Game) WindowSize() (int, int) {
 }
 
 func (g *Game) Update() error {
+	g.applyChanges()
 
 	if !ebiten.IsFocused() {
 		return nil // Do nothing when the window isn't focused
@@ -264,8 +275,8 @@ func (g *Game) Draw(screen *ebiten.Image) {
 
 	// g.DrawContractStatus(screen, nil)
 	g.DrawCredits(screen, credits)
-	if contract != nil {
-		g.DrawContracts(screen, []api.Contract{*contract})
+	if c := contract.Load(); c != nil {
+		g.DrawContracts(screen, []api.Contract{*c})
 	}
 
 	mx, my := ebiten.CursorPosition()
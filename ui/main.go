@@ -8,12 +8,15 @@ import (
 	"math"
 	"os"
 	"path"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwiggs/spacetraders-go/api"
 	"github.com/bwiggs/spacetraders-go/kernel"
 	"github.com/bwiggs/spacetraders-go/models"
 	"github.com/bwiggs/spacetraders-go/tasks"
+	"github.com/bwiggs/spacetraders-go/world"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/lmittmann/tint"
 	"github.com/spf13/viper"
@@ -80,7 +83,7 @@ var currSystem string
 var waypoints []*models.Waypoint
 var systems []models.System
 var ships []*api.Ship
-var contract *api.Contract
+var contract atomic.Pointer[api.Contract]
 var agents []*api.Agent
 var agentsBySystem map[string]*api.Agent
 var systemCoords map[string][]float64
@@ -96,22 +99,19 @@ func main() {
 
 	kern.Start()
 
+	// subscribed before loading, so no change is missed in between
+	game := NewGame(kern)
+
 	currSystem = viper.GetString("SYSTEM")
 
 	repo := kern.Repo()
 
 	slog.Info("loading waypoints")
 
-	waypoints, err = repo.GetNonOrbitalWaypoints(currSystem)
-	if err != nil {
-		log.Fatal(err)
-	}
+	waypoints = systemWaypoints(kern.World(), currSystem)
 
 	slog.Info("loading systems")
-	systems, err = repo.GetSystems()
-	if err != nil {
-		log.Fatal(err)
-	}
+	systems = kern.World().Systems()
 
 	slog.Info("loading agents")
 	agents, err = repo.GetAgents()
@@ -125,9 +125,9 @@ func main() {
 	}
 
 	slog.Info("loading fleet")
-	ships, err = repo.GetFleet()
-	if err != nil {
-		log.Fatal(err)
+	ships = fleet(kern.State())
+	if agent, ok := kern.State().Agent(); ok {
+		credits = int(agent.Credits)
 	}
 
 	slog.Info("loading contellation colors")
@@ -140,45 +140,17 @@ func main() {
 		}
 	}
 
-	slog.Info("calculating waypoint distances")
-	// compute distance from center for each waypoint
-	for i := 0; i < len(waypoints); i++ {
-		dx := float64(waypoints[i].X) - 0.0
-		dy := float64(waypoints[i].Y) - 0.0
-		waypoints[i].Dist = math.Hypot(dx, dy)
-	}
-
 	slog.Info("starting tasks")
-	tasks.Start()
-
-	slog.Info("starting tasks: updatefleet")
-	go tasks.SetInterval(func() {
-		if err := tasks.UpdateFleet(kern.Client(), kern.Repo()); err != nil {
-			slog.Error("ui: fleet update: failed to update fleet", "err", err)
-		}
-
-		ships, err = repo.GetFleet()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-	}, 10*time.Second)
-
-	slog.Info("starting tasks: my credits")
-	go tasks.SetInterval(func() {
-		res, err := kern.Client().GetMyAgent(context.TODO())
-		if err != nil {
-			slog.Error("ui: fleet update: failed to update fleet", "err", err)
-		}
-		credits = int(res.Data.Credits)
-	}, 1*time.Minute)
+	tasks.Start(kern.Clock(), kern.Bus())
 
 	slog.Info("starting tasks: contracts")
-	go tasks.SetInterval(func() {
-		contract, err = tasks.GetLatestContract(kern.Client())
+	go tasks.SetInterval("LatestContract", func(ctx context.Context) {
+		latest, err := tasks.GetLatestContract(ctx, kern.Client())
 		if err != nil {
 			slog.Error("ui: fleet update: failed to update fleet", "err", err)
+			return
 		}
+		contract.Store(latest)
 	}, 15*time.Second)
 
 	slog.Info("loading fonts")
@@ -191,7 +163,65 @@ func main() {
 	ebiten.SetTPS(60)
 	ebiten.SetWindowTitle("spacetraders.io")
 	slog.Info("spacetraders.io - UI", "system", currSystem, "agent", viper.GetString("AGENT"))
-	if err := ebiten.RunGame(NewGame(kern)); err != nil {
+	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// systemWaypoints copies system's non-orbital waypoints out of the world, the
+// world's own are shared, with their distance from the sun set.
+func systemWaypoints(w *world.World, system string) []*models.Waypoint {
+	wps := []*models.Waypoint{}
+	for _, wp := range w.NonOrbitalWaypoints(system) {
+		wp := *wp
+		wp.Dist = math.Hypot(float64(wp.X), float64(wp.Y))
+		wps = append(wps, &wp)
+	}
+	return wps
+}
+
+// applyChanges reloads what the state and world events received since the
+// last update touched. It runs on the game loop, so Draw reads the globals
+// without racing the subscriptions.
+func (g *Game) applyChanges() {
+	fleetChanged, agentChanged, waypointsChanged := false, false, false
+drain:
+	for {
+		select {
+		case e := <-g.stateEvents:
+			switch e.Kind {
+			case kernel.ShipChanged, kernel.ShipRemoved:
+				fleetChanged = true
+			case kernel.AgentChanged:
+				agentChanged = true
+			}
+		case e := <-g.worldEvents:
+			// rescans of the system's waypoints show up without a restart
+			if e.Kind == world.WaypointChanged && strings.HasPrefix(e.Symbol, currSystem+"-") {
+				waypointsChanged = true
+			}
+		default:
+			break drain
+		}
+	}
+
+	if fleetChanged {
+		ships = fleet(g.kernel.State())
+	}
+	if agentChanged {
+		if agent, ok := g.kernel.State().Agent(); ok {
+			credits = int(agent.Credits)
+		}
+	}
+	if waypointsChanged {
+		waypoints = systemWaypoints(g.kernel.World(), currSystem)
+	}
+}
+
+func fleet(state *kernel.State) []*api.Ship {
+	ships := []*api.Ship{}
+	for _, s := range state.Ships() {
+		ships = append(ships, &s)
+	}
+	return ships
+}
@@ -0,0 +1,84 @@
+package world
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// EventKind is what changed.
+type EventKind int
+
+const (
+	SystemChanged EventKind = iota
+	WaypointChanged
+	MarketChanged
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case SystemChanged:
+		return "system"
+	case WaypointChanged:
+		return "waypoint"
+	case MarketChanged:
+		return "market"
+	}
+	return "unknown"
+}
+
+// Event is published after a change has been applied to the world, Symbol is
+// the changed system, waypoint or market's.
+type Event struct {
+	Kind   EventKind
+	Symbol string
+}
+
+// Subscribe returns a channel receiving every later change, and a func to
+// stop receiving. Publishing never waits for a subscriber, events that don't
+// fit in its buffer are dropped.
+func (w *World) Subscribe(buffer int) (<-chan Event, func()) {
+	return w.events.subscribe(buffer)
+}
+
+type broker struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]chan Event
+}
+
+func newBroker() *broker {
+	return &broker{subs: map[int]chan Event{}}
+}
+
+func (b *broker) subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	ch := make(chan Event, buffer)
+	b.subs[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(ch)
+		})
+	}
+}
+
+func (b *broker) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("world: subscriber is behind, dropping event", "kind", e.Kind, "symbol", e.Symbol)
+		}
+	}
+}
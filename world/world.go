@@ -0,0 +1,342 @@
+// Package world keeps the systems, waypoints and markets stored in the repo
+// indexed in memory, so missions and the ui can look them up every tick
+// without querying and decoding rows from SQLite.
+//
+// The world loads the repo once and then observes it: every committed
+// upsert, whoever makes it, is applied to the indexes and published to
+// subscribers as an Event.
+package world
+
+import (
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/bwiggs/spacetraders-go/api"
+	"github.com/bwiggs/spacetraders-go/models"
+	"github.com/bwiggs/spacetraders-go/repo"
+	"github.com/pkg/errors"
+)
+
+// World is the in-memory copy of the repo's static game data and market
+// listings. Returned waypoints are shared and must not be modified.
+type World struct {
+	mu        sync.RWMutex
+	systems   map[string]models.System
+	waypoints map[string]*models.Waypoint
+	// bySystem holds each system's waypoints ordered by symbol
+	bySystem map[string][]*models.Waypoint
+	traits   map[string]map[string]bool
+	markets  map[string]map[listingKey]repo.MarketListing
+
+	events *broker
+}
+
+type listingKey struct {
+	good string
+	typ  string
+}
+
+func newWorld() *World {
+	return &World{
+		systems:   map[string]models.System{},
+		waypoints: map[string]*models.Waypoint{},
+		bySystem:  map[string][]*models.Waypoint{},
+		traits:    map[string]map[string]bool{},
+		markets:   map[string]map[listingKey]repo.MarketListing{},
+		events:    newBroker(),
+	}
+}
+
+// Load reads systems, waypoints and markets from r and keeps them up to date
+// with every later upsert through it.
+func Load(r *repo.Repo) (*World, error) {
+	w := newWorld()
+
+	systems, err := r.GetSystems()
+	if err != nil {
+		return nil, errors.Wrap(err, "world: failed to load systems")
+	}
+	for _, s := range systems {
+		w.systems[s.Symbol] = s
+	}
+
+	waypoints, err := r.GetWaypoints("")
+	if err != nil {
+		return nil, errors.Wrap(err, "world: failed to load waypoints")
+	}
+	for _, wp := range waypoints {
+		w.putWaypoint(wp)
+	}
+	for system := range w.bySystem {
+		w.sortSystem(system)
+	}
+
+	listings, err := r.GetMarketListings()
+	if err != nil {
+		return nil, errors.Wrap(err, "world: failed to load markets")
+	}
+	for _, l := range listings {
+		w.putListing(l)
+	}
+
+	r.Observe(w)
+
+	return w, nil
+}
+
+// System returns the system with symbol.
+func (w *World) System(symbol string) (models.System, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	s, ok := w.systems[symbol]
+	return s, ok
+}
+
+// Systems returns every known system.
+func (w *World) Systems() []models.System {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	systems := make([]models.System, 0, len(w.systems))
+	for _, s := range w.systems {
+		systems = append(systems, s)
+	}
+	sort.Slice(systems, func(i, j int) bool {
+		return systems[i].Symbol < systems[j].Symbol
+	})
+	return systems
+}
+
+// Waypoint returns the waypoint with symbol.
+func (w *World) Waypoint(symbol string) (*models.Waypoint, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	wp, ok := w.waypoints[symbol]
+	return wp, ok
+}
+
+// Waypoints returns system's waypoints ordered by symbol.
+func (w *World) Waypoints(system string) []*models.Waypoint {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]*models.Waypoint{}, w.bySystem[system]...)
+}
+
+// NonOrbitalWaypoints returns system's waypoints that don't orbit another.
+func (w *World) NonOrbitalWaypoints(system string) []*models.Waypoint {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	waypoints := []*models.Waypoint{}
+	for _, wp := range w.bySystem[system] {
+		if wp.Orbits.Value == "" {
+			waypoints = append(waypoints, wp)
+		}
+	}
+	return waypoints
+}
+
+// WaypointsWithTrait returns system's waypoints with trait.
+func (w *World) WaypointsWithTrait(system, trait string) []*models.Waypoint {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	waypoints := []*models.Waypoint{}
+	for _, wp := range w.bySystem[system] {
+		if w.traits[wp.Symbol][trait] {
+			waypoints = append(waypoints, wp)
+		}
+	}
+	return waypoints
+}
+
+// HasTrait reports whether the waypoint with symbol has trait.
+func (w *World) HasTrait(waypoint, trait string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.traits[waypoint][trait]
+}
+
+// Market returns the listings at waypoint, ordered by good.
+func (w *World) Market(waypoint string) []repo.MarketListing {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	listings := make([]repo.MarketListing, 0, len(w.markets[waypoint]))
+	for _, l := range w.markets[waypoint] {
+		listings = append(listings, l)
+	}
+	sort.Slice(listings, func(i, j int) bool {
+		if listings[i].Good != listings[j].Good {
+			return listings[i].Good < listings[j].Good
+		}
+		return listings[i].Type < listings[j].Type
+	})
+	return listings
+}
+
+// MarketHasGood reports whether the market at waypoint trades good.
+func (w *World) MarketHasGood(waypoint, good string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	for k := range w.markets[waypoint] {
+		if k.good == good {
+			return true
+		}
+	}
+	return false
+}
+
+// MarketTrades ranks the trades available at the latest known prices, as
+// repo.FindMarketTrades does.
+func (w *World) MarketTrades() []repo.MarketTrade {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	listings := []repo.MarketListing{}
+	coords := map[string]repo.Coords{}
+	for waypoint, market := range w.markets {
+		for _, l := range market {
+			listings = append(listings, l)
+		}
+		if wp, ok := w.waypoints[waypoint]; ok {
+			coords[waypoint] = repo.Coords{X: wp.X, Y: wp.Y}
+		}
+	}
+
+	return RankTrades(listings, coords)
+}
+
+// RankTrades ranks the trades between listings as MarketTrades does, for
+// listings that didn't come from the world, e.g. the backtester's historical
+// prices. Unpriced listings are skipped, listings is reused in place.
+func RankTrades(listings []repo.MarketListing, coords map[string]repo.Coords) []repo.MarketTrade {
+	listings = slices.DeleteFunc(listings, func(l repo.MarketListing) bool {
+		return l.Bid == 0 && l.Ask == 0
+	})
+	// stable ranking of equal trades regardless of map order
+	sort.Slice(listings, func(i, j int) bool {
+		a, b := listings[i], listings[j]
+		if a.Waypoint != b.Waypoint {
+			return a.Waypoint < b.Waypoint
+		}
+		if a.Good != b.Good {
+			return a.Good < b.Good
+		}
+		return a.Type < b.Type
+	})
+
+	return repo.RankMarketTrades(listings, coords)
+}
+
+// SystemsUpserted implements repo.Observer.
+func (w *World) SystemsUpserted(systems []api.System) {
+	w.mu.Lock()
+	for _, s := range systems {
+		w.systems[s.Symbol] = models.System{
+			Symbol:        s.Symbol,
+			Constellation: s.Constellation.Value,
+			Name:          s.Name.Value,
+			Type:          string(s.Type),
+			SectorSymbol:  s.SectorSymbol,
+			X:             s.X,
+			Y:             s.Y,
+		}
+	}
+	w.mu.Unlock()
+
+	for _, s := range systems {
+		w.events.publish(Event{Kind: SystemChanged, Symbol: s.Symbol})
+	}
+}
+
+// WaypointsUpserted implements repo.Observer.
+func (w *World) WaypointsUpserted(waypoints []api.Waypoint) {
+	w.mu.Lock()
+	changed := map[string]bool{}
+	for _, wp := range waypoints {
+		w.putWaypoint(models.NewWaypoint(&wp))
+		changed[string(wp.SystemSymbol)] = true
+	}
+	for system := range changed {
+		w.sortSystem(system)
+	}
+	w.mu.Unlock()
+
+	for _, wp := range waypoints {
+		w.events.publish(Event{Kind: WaypointChanged, Symbol: string(wp.Symbol)})
+	}
+}
+
+// MarketUpserted implements repo.Observer. Priced trade goods replace the
+// listings they match, a scan without prices only adds missing listings, as
+// the upsert does in the repo.
+func (w *World) MarketUpserted(market api.Market) {
+	w.mu.Lock()
+	if market.TradeGoods != nil {
+		for _, tg := range market.TradeGoods {
+			w.putListing(repo.MarketListing{
+				Waypoint: market.Symbol,
+				Good:     string(tg.Symbol),
+				Type:     string(tg.Type),
+				Supply:   string(tg.Supply),
+				Volume:   tg.TradeVolume,
+				Activity: string(tg.Activity.Value),
+				Bid:      tg.PurchasePrice,
+				Ask:      tg.SellPrice,
+			})
+		}
+	} else {
+		for typ, goods := range map[string][]api.TradeGood{
+			"EXCHANGE": market.Exchange,
+			"EXPORT":   market.Exports,
+			"IMPORT":   market.Imports,
+		} {
+			for _, g := range goods {
+				key := listingKey{good: string(g.Symbol), typ: typ}
+				if _, ok := w.markets[market.Symbol][key]; !ok {
+					w.putListing(repo.MarketListing{Waypoint: market.Symbol, Good: key.good, Type: typ})
+				}
+			}
+		}
+	}
+	w.mu.Unlock()
+
+	w.events.publish(Event{Kind: MarketChanged, Symbol: market.Symbol})
+}
+
+// putWaypoint indexes wp, the caller holds the lock and re-sorts the system.
+func (w *World) putWaypoint(wp *models.Waypoint) {
+	system := string(wp.SystemSymbol)
+	if _, ok := w.waypoints[wp.Symbol]; ok {
+		w.bySystem[system] = slices.DeleteFunc(w.bySystem[system], func(o *models.Waypoint) bool {
+			return o.Symbol == wp.Symbol
+		})
+	}
+	w.waypoints[wp.Symbol] = wp
+	w.bySystem[system] = append(w.bySystem[system], wp)
+
+	traits := map[string]bool{}
+	for _, t := range wp.Traits {
+		traits[string(t.Symbol)] = true
+	}
+	w.traits[wp.Symbol] = traits
+}
+
+func (w *World) sortSystem(system string) {
+	wps := w.bySystem[system]
+	sort.Slice(wps, func(i, j int) bool {
+		return wps[i].Symbol < wps[j].Symbol
+	})
+}
+
+func (w *World) putListing(l repo.MarketListing) {
+	market, ok := w.markets[l.Waypoint]
+	if !ok {
+		market = map[listingKey]repo.MarketListing{}
+		w.markets[l.Waypoint] = market
+	}
+	market[listingKey{good: l.Good, typ: l.Type}] = l
+}